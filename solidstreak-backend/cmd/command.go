@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func runCommand(ctx context.Context, logger *slog.Logger, pgPool *pgxpool.Pool, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return runMigrateCommand(ctx, logger, pgPool, args[1:])
+	case "export":
+		return runExportCommand(ctx, logger, pgPool, args[1:])
+	case "import":
+		return runImportCommand(ctx, logger, pgPool, args[1:])
+	default:
+		return errors.New("unknown command \"" + args[0] + "\"")
+	}
+}
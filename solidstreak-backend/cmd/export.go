@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	hRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit/repo"
+	usrRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user/repo"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/export"
+)
+
+// runExportCommand writes all user's data to zip archive, by default named after the user and the date
+func runExportCommand(ctx context.Context, logger *slog.Logger, pgPool *pgxpool.Pool, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return errors.New("usage: export <user ID> [output file]")
+	}
+
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return errors.New("invalid user ID \"" + args[0] + "\"")
+	}
+
+	res := resources.Resources{
+		Logger:    logger,
+		UoW:       uow.Init(pgPool),
+		UsrRepo:   usrRepo.Init(pgPool),
+		HabitRepo: hRepo.Init(pgPool),
+	}
+
+	exp, err := export.New(ctx, res, userID)
+	if err != nil {
+		return err
+	}
+
+	fileName := exp.FileName()
+	if len(args) == 2 {
+		fileName = args[1]
+	}
+
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err = exp.WriteZip(ctx, f); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	logger.Info("user data exported", "userId", userID, "file", fileName)
+
+	return nil
+}
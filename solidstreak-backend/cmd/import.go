@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	hRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit/repo"
+	usrRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user/repo"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/importer"
+)
+
+const importUsage = "usage: import <user ID> <file> [--format=auto|loop|generic] [--dry-run] [--skip-invalid]"
+
+// runImportCommand imports habits and checks from Loop Habit Tracker export or generic CSV file
+func runImportCommand(ctx context.Context, logger *slog.Logger, pgPool *pgxpool.Pool, args []string) error {
+	opts := importer.Options{Format: importer.Auto}
+	positional := []string{}
+	for _, arg := range args {
+		switch arg {
+		case "--dry-run":
+			opts.DryRun = true
+		case "--skip-invalid":
+			opts.SkipInvalid = true
+		default:
+			if formatStr, ok := strings.CutPrefix(arg, "--format="); ok {
+				if opts.Format, ok = importer.FormatMapping[formatStr]; !ok {
+					return errors.New("invalid import format \"" + formatStr + "\"")
+				}
+				continue
+			}
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) != 2 {
+		return errors.New(importUsage)
+	}
+
+	userID, err := strconv.ParseInt(positional[0], 10, 64)
+	if err != nil {
+		return errors.New("invalid user ID \"" + positional[0] + "\"")
+	}
+
+	data, err := os.ReadFile(positional[1])
+	if err != nil {
+		return err
+	}
+
+	res := resources.Resources{
+		Logger:    logger,
+		UoW:       uow.Init(pgPool),
+		UsrRepo:   usrRepo.Init(pgPool),
+		HabitRepo: hRepo.Init(pgPool),
+	}
+
+	user, err := res.UsrRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	result, err := importer.Import(ctx, res, user, data, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, rowErr := range result.Errors {
+		logger.Warn("invalid import row", "file", rowErr.File, "row", rowErr.Row, "error", rowErr.Message)
+	}
+	for _, h := range result.Habits {
+		logger.Info("habit", "title", h.Title, "habitId", h.HabitID, "new", h.New, "checks", h.Checks, "from", h.From, "to", h.To, "backdatedTo", h.BackdatedTo)
+	}
+
+	switch {
+	case result.Imported:
+		logger.Info("user data imported", "userId", userID, "format", result.Format, "habits", len(result.Habits), "errors", len(result.Errors))
+	case result.DryRun:
+		logger.Info("dry run, nothing imported", "userId", userID, "format", result.Format, "habits", len(result.Habits), "errors", len(result.Errors))
+	default:
+		return errors.New("file has " + strconv.Itoa(len(result.Errors)) + " invalid rows, nothing imported, use --skip-invalid to import valid ones")
+	}
+
+	return nil
+}
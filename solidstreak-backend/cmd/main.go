@@ -2,11 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata" // Embedded time zones database for users' time zones
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -14,9 +16,14 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/control/http"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/control/jobs"
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/control/tgbot"
+	atRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken/repo"
+	fRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship/repo"
 	hRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit/repo"
+	sessRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session/repo"
 	tcRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat/repo"
 	usrRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user/repo"
 )
@@ -32,9 +39,12 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: slog.Level(viper.GetInt("log_level"))}))
 	// logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.Level(viper.GetInt("log_level"))}))
 
+	// Deferred first to run last, after the resources are released, and to exit with failure status
+	// deploy scripts check after migrations and other subcommands
 	defer func() {
 		if err != nil {
 			logger.Error(err.Error())
+			os.Exit(1)
 		}
 	}()
 
@@ -56,6 +66,17 @@ func main() {
 	}
 	defer pgPool.Close()
 
+	// Running subcommand instead of the service if specified
+	if len(os.Args) > 1 {
+		err = runCommand(mainCtx, logger, pgPool, os.Args[1:])
+		return
+	}
+
+	// Storage schema migration
+	if err = migrateOnStartup(mainCtx, logger, pgPool, viper.GetBool("migrate_on_startup")); err != nil {
+		return
+	}
+
 	var tgBotAPI *tgbotapi.BotAPI
 	if tgBotAPI, err = tgbotapi.NewBotAPI(os.Getenv("TG_BOT_API_TOKEN")); err != nil {
 		return
@@ -64,30 +85,109 @@ func main() {
 
 	resources := resources.Resources{
 		TgBotAPIToken: os.Getenv("TG_BOT_API_TOKEN"),
+		TgBotUsername: tgBotAPI.Self.UserName,
 		Logger:        logger,
 		TgBotAPI:      tgBotAPI,
-		UsrRepo:       usrRepo.Init(mainCtx, pgPool),
-		TCRepo:        tcRepo.Init(mainCtx, pgPool),
-		HabitRepo:     hRepo.Init(mainCtx, pgPool),
+		UoW:           uow.Init(pgPool),
+		UsrRepo:       usrRepo.Init(pgPool),
+		TCRepo:        tcRepo.Init(pgPool),
+		HabitRepo:     hRepo.Init(pgPool),
+		PartRepo:      hRepo.InitPartitionRepo(pgPool),
+		FriendRepo:    fRepo.Init(pgPool),
+		TokenRepo:     atRepo.Init(pgPool),
+		SessionRepo:   sessRepo.Init(pgPool),
+	}
+
+	// Telegram updates receiving mode
+	var tgWebhook *tgbot.Webhook
+	switch mode := viper.GetString("tg_bot_mode"); mode {
+	case tgbot.ModePolling:
+	case tgbot.ModeWebhook:
+		if tgWebhook, err = tgbot.NewWebhook(
+			os.Getenv("TG_WEBHOOK_URL"),
+			os.Getenv("TG_WEBHOOK_SECRET"),
+			viper.GetInt("max_event_handlers"),
+			viper.GetBool("tg_webhook_keep_on_shutdown"),
+		); err != nil {
+			return
+		}
+	default:
+		err = errors.New("unknown telegram bot mode: " + mode)
+		return
+	}
+
+	sessionSecret := os.Getenv("SESSION_SECRET")
+	if sessionSecret == "" {
+		err = errors.New("session secret isn't set")
+		return
 	}
 
-	goroutineDoneCh := make(chan struct{}, 2)
+	goroutineDoneCh := make(chan struct{}, 3)
+
+	// Running background jobs
+	go jobs.Scheduler{
+		Jobs: []jobs.Job{
+			jobs.ChecksPartitions{
+				MonthsAhead: viper.GetInt("checks_partitions_months_ahead"),
+				Every:       viper.GetDuration("checks_partitions_interval"),
+				Res:         resources,
+			},
+			jobs.HabitReminders{
+				Every: viper.GetDuration("habit_reminders_interval"),
+				Res:   resources,
+			},
+			jobs.HabitTrashPurge{
+				Retention: viper.GetDuration("habit_trash_retention"),
+				Every:     viper.GetDuration("habit_trash_purge_interval"),
+				Res:       resources,
+			},
+			jobs.AccountDeletions{
+				Every: viper.GetDuration("account_deletions_interval"),
+				Res:   resources,
+			},
+		},
+		Res: resources,
+	}.Run(mainCtx, goroutineDoneCh)
 
 	// Running event fetcher
 	go tgbot.EventFetcher{
-		TgBotUpdsOffset:  viper.GetInt("tg_bot_upds_offset"),
-		TgBotUpdsTimeout: viper.GetInt("tg_bot_upds_timeout"),
-		MaxEventHandlers: viper.GetInt("max_event_handlers"),
-		Res:              resources,
+		TgBotUpdsOffset:            viper.GetInt("tg_bot_upds_offset"),
+		TgBotUpdsTimeout:           viper.GetInt("tg_bot_upds_timeout"),
+		MaxEventHandlers:           viper.GetInt("max_event_handlers"),
+		EventHandlerTimeout:        viper.GetDuration("tg_event_handler_timeout"),
+		Webhook:                    tgWebhook,
+		AccountDeletionGracePeriod: viper.GetDuration("account_deletion_grace_period"),
+		Res:                        resources,
 	}.Run(mainCtx, goroutineDoneCh)
 
 	// Running web server
 	webServer := http.Server{
-		Env:          os.Getenv("ENV"),
-		CertFilePath: os.Getenv("CERT_FILE_PATH"),
-		KeyFilePath:  os.Getenv("KEY_FILE_PATH"),
-		Addr:         os.Getenv("SERVER_ADDR"),
-		Res:          resources,
+		Env:                        os.Getenv("ENV"),
+		CertFilePath:               os.Getenv("CERT_FILE_PATH"),
+		KeyFilePath:                os.Getenv("KEY_FILE_PATH"),
+		Addr:                       os.Getenv("SERVER_ADDR"),
+		RequestTimeout:             viper.GetDuration("http_request_timeout"),
+		ExportTimeout:              viper.GetDuration("http_export_timeout"),
+		InitDataMaxAge:             viper.GetDuration("init_data_max_age"),
+		SessionSecret:              sessionSecret,
+		SessionTTL:                 viper.GetDuration("session_ttl"),
+		SessionRefreshTTL:          viper.GetDuration("session_refresh_ttl"),
+		SessionMaxLifetime:         viper.GetDuration("session_max_lifetime"),
+		LoginWidgetMaxAge:          viper.GetDuration("login_widget_max_age"),
+		HabitInviteTTL:             viper.GetDuration("habit_invite_ttl"),
+		HabitTrashRetention:        viper.GetDuration("habit_trash_retention"),
+		HabitCheckBackfillDays:     viper.GetInt("habit_check_backfill_days"),
+		AccountDeletionGracePeriod: viper.GetDuration("account_deletion_grace_period"),
+		RequestLog: http.RequestLogConfig{
+			RedactHeaders:      viper.GetStringSlice("log_redact_headers"),
+			RedactFields:       viper.GetStringSlice("log_redact_fields"),
+			MaxBodyBytes:       viper.GetInt("log_max_body_bytes"),
+			SampleSuccessEvery: viper.GetInt("log_sample_success_every"),
+		},
+		Res: resources,
+	}
+	if tgWebhook != nil {
+		webServer.TgWebhook = tgWebhook
 	}
 	go webServer.Run(mainCtx, goroutineDoneCh)
 
@@ -99,6 +199,7 @@ func main() {
 	// Waiting for goroutines to finish
 	<-goroutineDoneCh
 	<-goroutineDoneCh
+	<-goroutineDoneCh
 
 	logger.Info("solid streak stopped")
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/migrations"
+)
+
+func runMigrateCommand(ctx context.Context, logger *slog.Logger, pgPool *pgxpool.Pool, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: migrate up|down|status")
+	}
+
+	m, err := migrations.New(pgPool)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		return migrateUp(ctx, logger, m)
+	case "down":
+		var reverted *migrations.Migration
+		if reverted, err = m.Down(ctx); err != nil {
+			return err
+		}
+		if reverted == nil {
+			logger.Info("no applied migrations to revert")
+			return nil
+		}
+		logger.Info("migration reverted", "version", reverted.Version, "name", reverted.Name)
+	case "status":
+		var statuses []*migrations.Status
+		if statuses, err = m.Status(ctx); err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			logger.Info("migration status", "version", s.Version, "name", s.Name, "applied", s.Applied, "appliedAt", s.AppliedAt)
+		}
+	default:
+		return errors.New("unknown migrate command \"" + args[0] + "\"")
+	}
+
+	return nil
+}
+
+// migrateOnStartup applies pending migrations if enabled, otherwise refuses to start on an outdated schema
+func migrateOnStartup(ctx context.Context, logger *slog.Logger, pgPool *pgxpool.Pool, enabled bool) error {
+	m, err := migrations.New(pgPool)
+	if err != nil {
+		return err
+	}
+
+	if enabled {
+		return migrateUp(ctx, logger, m)
+	}
+
+	pending := 0
+	if pending, err = m.Pending(ctx); err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("database schema is outdated: %d pending migrations, run \"migrate up\" or enable migrate_on_startup", pending)
+	}
+
+	return nil
+}
+
+func migrateUp(ctx context.Context, logger *slog.Logger, m *migrations.Migrator) error {
+	applied, err := m.Up(ctx)
+	for _, mig := range applied {
+		logger.Info("migration applied", "version", mig.Version, "name", mig.Name)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		logger.Info("database schema is up to date")
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package memdb
+
+import (
+	"sync"
+	"time"
+
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	fPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	sessPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session"
+	tcPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+// DB is the in-memory storage shared by in-memory repositories, which lets them run without
+// PostgreSQL, e.g. in tests. Its tables mirror the storage ones, so repositories could
+// reproduce the joins of their SQL queries. Repositories hold the lock while accessing tables
+type DB struct {
+	sync.Mutex
+	Users         map[int64]*usrPkg.User // By ID
+	TgChats       map[int64]*tcPkg.Chat  // By Telegram ID
+	Habits        map[int64]*hPkg.Habit  // By ID, with settings shared by all participants only
+	Members       map[MemberKey]*Member  // Participants of habits, including left ones
+	Checks        map[CheckKey]*hPkg.HabitCheck
+	Invites       map[string]*hPkg.Invite         // By token
+	Friendships   map[PairKey]*fPkg.Friendship    // A pair of users has a single relationship
+	AccessTokens  map[int64]*atPkg.Token          // By ID
+	RefreshTokens map[int64]*sessPkg.RefreshToken // By ID
+	UsedInitData  map[string]*time.Time           // Expiration by hash of initData exchanged for sessions
+	lastIDs       map[string]int64                // Last IDs generated for tables
+}
+
+type MemberKey struct {
+	HabitID int64
+	UserID  int64
+}
+
+// Member is user's participation in the habit
+type Member struct {
+	Active         bool
+	IsPublic       bool
+	JoinedAt       time.Time
+	ReminderSentOn string // Date the last reminder is sent on in the participant's time zone, empty if none
+}
+
+type CheckKey struct {
+	HabitID   int64
+	UserID    int64
+	CheckDate string // Date in "YYYY-MM-DD" format, which also keeps checks dates ordered
+}
+
+func NewCheckKey(hc *hPkg.HabitCheck) CheckKey {
+	return CheckKey{HabitID: hc.HabitID, UserID: hc.UserID, CheckDate: hc.CheckDate.String()}
+}
+
+type PairKey struct {
+	LesserID  int64
+	GreaterID int64
+}
+
+func NewPairKey(userID, otherID int64) PairKey {
+	if userID > otherID {
+		userID, otherID = otherID, userID
+	}
+	return PairKey{LesserID: userID, GreaterID: otherID}
+}
+
+func New() *DB {
+	return &DB{
+		Users:         map[int64]*usrPkg.User{},
+		TgChats:       map[int64]*tcPkg.Chat{},
+		Habits:        map[int64]*hPkg.Habit{},
+		Members:       map[MemberKey]*Member{},
+		Checks:        map[CheckKey]*hPkg.HabitCheck{},
+		Invites:       map[string]*hPkg.Invite{},
+		Friendships:   map[PairKey]*fPkg.Friendship{},
+		AccessTokens:  map[int64]*atPkg.Token{},
+		RefreshTokens: map[int64]*sessPkg.RefreshToken{},
+		UsedInitData:  map[string]*time.Time{},
+		lastIDs:       map[string]int64{},
+	}
+}
+
+// NextID generates ID of a new row in the table, like a serial column does
+func (db *DB) NextID(table string) int64 {
+	db.lastIDs[table]++
+	return db.lastIDs[table]
+}
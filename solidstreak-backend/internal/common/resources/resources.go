@@ -5,16 +5,34 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	at "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken/repo"
+	f "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship/repo"
 	h "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit/repo"
+	sess "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session/repo"
 	tc "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat/repo"
 	usr "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user/repo"
 )
 
+// TgBotAPI is the part of Telegram Bot API client used by the service
+type TgBotAPI interface {
+	Send(tgbotapi.Chattable) (tgbotapi.Message, error)
+	Request(tgbotapi.Chattable) (*tgbotapi.APIResponse, error)
+	MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error)
+	GetUpdatesChan(tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel
+}
+
 type Resources struct {
 	TgBotAPIToken string
+	TgBotUsername string
 	Logger        *slog.Logger
-	TgBotAPI      *tgbotapi.BotAPI
+	TgBotAPI      TgBotAPI
+	UoW           uow.UnitOfWork
 	UsrRepo       usr.Repo
 	TCRepo        tc.Repo
 	HabitRepo     h.Repo
+	PartRepo      h.PartitionRepo
+	FriendRepo    f.Repo
+	TokenRepo     at.Repo
+	SessionRepo   sess.Repo
 }
@@ -0,0 +1,83 @@
+package uow
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DB is the part of pgx API used by repositories, implemented by both pool and transaction
+type DB interface {
+	Begin(context.Context) (pgx.Tx, error)
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
+// UnitOfWork runs several repository operations atomically
+type UnitOfWork interface {
+	// Do runs fn in a transaction, which is committed if fn returns no error and rolled back otherwise.
+	// Repositories called with the context passed to fn take part in the transaction, nested Do calls
+	// join the outer one
+	Do(ctx context.Context, fn func(context.Context) error) error
+	// DoSnapshot runs fn in a read-only transaction, which sees the data as of its first query, so several
+	// reads made in fn are consistent with each other. Nested calls join the outer transaction
+	DoSnapshot(ctx context.Context, fn func(context.Context) error) error
+}
+
+type ctxKeyTx struct{}
+
+type pgUnitOfWork struct {
+	p *pgxpool.Pool
+}
+
+func Init(p *pgxpool.Pool) UnitOfWork {
+	return pgUnitOfWork{p}
+}
+
+func (u pgUnitOfWork) Do(ctx context.Context, fn func(context.Context) error) error {
+	if _, ok := ctx.Value(ctxKeyTx{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	return pgx.BeginFunc(ctx, u.p, func(tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, ctxKeyTx{}, tx))
+	})
+}
+
+func (u pgUnitOfWork) DoSnapshot(ctx context.Context, fn func(context.Context) error) error {
+	if _, ok := ctx.Value(ctxKeyTx{}).(pgx.Tx); ok {
+		return fn(ctx)
+	}
+
+	opts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	return pgx.BeginTxFunc(ctx, u.p, opts, func(tx pgx.Tx) error {
+		return fn(context.WithValue(ctx, ctxKeyTx{}, tx))
+	})
+}
+
+// Conn returns the transaction of the unit of work running in ctx, or p if there is none
+func Conn(ctx context.Context, p *pgxpool.Pool) DB {
+	if tx, ok := ctx.Value(ctxKeyTx{}).(pgx.Tx); ok {
+		return tx
+	}
+	return p
+}
+
+type memUnitOfWork struct{}
+
+// InitMemory returns the unit of work of in-memory repositories, which just runs the work,
+// so changes made before an error aren't rolled back
+func InitMemory() UnitOfWork {
+	return memUnitOfWork{}
+}
+
+func (memUnitOfWork) Do(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+func (memUnitOfWork) DoSnapshot(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	atUsecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/accesstoken"
+)
+
+type AccessToken struct {
+	Name  *string `json:"name"`
+	Scope *string `json:"scope"`
+}
+
+type PostAccessTokenRequest struct {
+	Data *AccessToken `json:"data"`
+}
+
+type CreatedAccessToken struct {
+	*atPkg.Token
+	Secret string `json:"secret"` // Shown once, only its hash is stored
+}
+
+type PostAccessTokenResponse struct {
+	Data *CreatedAccessToken `json:"data"`
+}
+
+type GetAccessTokensResponse struct {
+	Data []*atPkg.Token `json:"data"`
+}
+
+type DeleteAccessTokenResponse struct {
+	Data *atPkg.Token `json:"data"`
+}
+
+func (s Server) getAccessTokens(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	if userID, err = getInt64FromURLParams(r, "userID", true); err != nil {
+		return
+	}
+
+	var tokens []*atPkg.Token
+	if tokens, err = atUsecases.List(r.Context(), s.Res, user, userID); err != nil {
+		return
+	}
+
+	response := GetAccessTokensResponse{Data: tokens}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// postAccessToken creates the access token and returns its secret, which couldn't be got later
+func (s Server) postAccessToken(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	if userID, err = getInt64FromURLParams(r, "userID", true); err != nil {
+		return
+	}
+
+	var req PostAccessTokenRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err = decoder.Decode(&req); err != nil {
+		err = apperrors.ErrBadRequest("invalid request payload")
+		return
+	}
+
+	if req.Data == nil || req.Data.Name == nil {
+		err = apperrors.ErrBadRequest("access token name is required")
+		return
+	}
+	if req.Data.Scope == nil {
+		err = apperrors.ErrBadRequest("access token scope is required")
+		return
+	}
+
+	var (
+		token  *atPkg.Token
+		secret string
+	)
+	if token, secret, err = atUsecases.Create(r.Context(), s.Res, user, userID, *req.Data.Name, *req.Data.Scope); err != nil {
+		return
+	}
+
+	logger.Info("access token created", "userId", user.ID, "accessTokenId", token.ID, "scope", token.Scope)
+
+	response := PostAccessTokenResponse{Data: &CreatedAccessToken{Token: token, Secret: secret}}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s Server) deleteAccessToken(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID, tokenID int64
+	if userID, err = getInt64FromURLParams(r, "userID", true); err != nil {
+		return
+	}
+	if tokenID, err = getInt64FromURLParams(r, "tokenID", true); err != nil {
+		return
+	}
+
+	var token *atPkg.Token
+	if token, err = atUsecases.Revoke(r.Context(), s.Res, user, userID, tokenID); err != nil {
+		return
+	}
+
+	logger.Info("access token revoked", "userId", user.ID, "accessTokenId", token.ID)
+
+	response := DeleteAccessTokenResponse{Data: token}
+
+	json.NewEncoder(w).Encode(response)
+}
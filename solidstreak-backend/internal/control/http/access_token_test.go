@@ -0,0 +1,109 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+func tokensPath(u *usrPkg.User, parts ...int64) string {
+	path := "/users/" + strconv.FormatInt(u.ID, 10) + "/tokens"
+	for _, p := range parts {
+		path += "/" + strconv.FormatInt(p, 10)
+	}
+	return path
+}
+
+func (env *testEnv) addToken(t *testing.T, u *usrPkg.User, name, scope string) *CreatedAccessToken {
+	t.Helper()
+
+	return data[*CreatedAccessToken](t, env.do(t, http.MethodPost, tokensPath(u), u.TgID, PostAccessTokenRequest{Data: &AccessToken{Name: &name, Scope: &scope}}))
+}
+
+func TestAccessTokens(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	other := env.addUser(t, 200)
+
+	wantError(t, env.do(t, http.MethodPost, tokensPath(u), 100, PostAccessTokenRequest{Data: &AccessToken{Name: ptr(" "), Scope: ptr("read")}}), http.StatusBadRequest)
+	wantError(t, env.do(t, http.MethodPost, tokensPath(u), 100, PostAccessTokenRequest{Data: &AccessToken{Name: ptr("Script"), Scope: ptr("admin")}}), http.StatusBadRequest)
+	wantError(t, env.do(t, http.MethodPost, tokensPath(u), 100, PostAccessTokenRequest{Data: &AccessToken{Name: ptr("Script")}}), http.StatusBadRequest)
+	wantError(t, env.do(t, http.MethodPost, tokensPath(other), 100, PostAccessTokenRequest{Data: &AccessToken{Name: ptr("Script"), Scope: ptr("read")}}), http.StatusForbidden)
+
+	created := env.addToken(t, u, "Shortcuts", "checks")
+	if created.Secret == "" || created.Scope != atPkg.Checks || created.UserID != u.ID {
+		t.Fatalf("unexpected created token %+v", created)
+	}
+
+	// Only hashes of secrets are stored and listed tokens don't expose them
+	stored := env.db.AccessTokens[created.ID]
+	if stored.Hash == created.Secret || stored.Hash != atPkg.HashSecret(created.Secret) {
+		t.Errorf("token is stored with hash %q", stored.Hash)
+	}
+	w := env.do(t, http.MethodGet, tokensPath(u), 100, nil)
+	if body := w.Body.String(); strings.Contains(body, created.Secret) || strings.Contains(body, stored.Hash) {
+		t.Errorf("tokens list exposes the secret: %s", body)
+	}
+	if tokens := data[[]*atPkg.Token](t, w); len(tokens) != 1 || tokens[0].Name != "Shortcuts" {
+		t.Errorf("unexpected tokens %+v", tokens)
+	}
+	wantError(t, env.do(t, http.MethodGet, tokensPath(other), 100, nil), http.StatusForbidden)
+
+	// Tokens are revoked by their owners only
+	wantError(t, env.do(t, http.MethodDelete, tokensPath(other, created.ID), 200, nil), http.StatusNotFound)
+	wantError(t, env.do(t, http.MethodDelete, tokensPath(u, created.ID), 200, nil), http.StatusForbidden)
+	data[*atPkg.Token](t, env.do(t, http.MethodDelete, tokensPath(u, created.ID), 100, nil))
+	wantError(t, env.do(t, http.MethodDelete, tokensPath(u, created.ID), 100, nil), http.StatusNotFound)
+
+	wantError(t, env.doWithBearer(t, http.MethodGet, habitsPath(u), created.Secret, nil), http.StatusUnauthorized)
+}
+
+func TestAccessTokenScopes(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	h := env.addHabit(t, u, newHabitRequest("Walk"))
+	today := u.Today()
+
+	read := env.addToken(t, u, "Dashboard", "read").Secret
+	checks := env.addToken(t, u, "Shortcuts", "checks").Secret
+	full := env.addToken(t, u, "Home automation", "full").Secret
+
+	checkReq := PostHabitCheckRequest{Data: &HabitCheck{CheckDate: &today, Completed: ptr(true)}}
+
+	data[[]*hPkg.Habit](t, env.doWithBearer(t, http.MethodGet, habitsPath(u), read, nil))
+	wantError(t, env.doWithBearer(t, http.MethodPost, habitsPath(u, h.ID, "checks"), read, checkReq), http.StatusForbidden)
+
+	data[*hPkg.HabitCheck](t, env.doWithBearer(t, http.MethodPost, habitsPath(u, h.ID, "checks"), checks, checkReq))
+	wantError(t, env.doWithBearer(t, http.MethodPut, habitsPath(u, h.ID), checks, newHabitRequest("Run")), http.StatusForbidden)
+	wantError(t, env.doWithBearer(t, http.MethodDelete, habitsPath(u, h.ID), checks, nil), http.StatusForbidden)
+
+	// Checks tokens are given to home automation and the like, which mustn't read the account
+	exportPath := "/users/" + strconv.FormatInt(u.ID, 10) + "/export"
+	for _, path := range []string{habitsPath(u), habitsPath(u, h.ID), habitsPath(u, h.ID, "checks"), habitsPath(u, h.ID, "stats"), "/users/" + strconv.FormatInt(u.ID, 10) + "/feed", exportPath} {
+		wantError(t, env.doWithBearer(t, http.MethodGet, path, checks, nil), http.StatusForbidden)
+	}
+	wantError(t, env.doWithBearer(t, http.MethodGet, exportPath, read, nil), http.StatusForbidden)
+	if w := env.doWithBearer(t, http.MethodGet, exportPath, full, nil); w.Code != http.StatusOK {
+		t.Errorf("got export status %d for full scope token", w.Code)
+	}
+
+	req := newHabitRequest("Run")
+	req.Data.Archived = ptr(false)
+	data[*hPkg.Habit](t, env.doWithBearer(t, http.MethodPut, habitsPath(u, h.ID), full, req))
+
+	// Tokens couldn't issue new tokens or delete the account, whatever their scope is
+	wantError(t, env.doWithBearer(t, http.MethodGet, tokensPath(u), full, nil), http.StatusForbidden)
+	wantError(t, env.doWithBearer(t, http.MethodPost, tokensPath(u), full, PostAccessTokenRequest{Data: &AccessToken{Name: ptr("Another"), Scope: ptr("full")}}), http.StatusForbidden)
+	wantError(t, env.doWithBearer(t, http.MethodPost, "/users/"+strconv.FormatInt(u.ID, 10)+"/deletion", full, PostAccountDeletionRequest{Data: &AccountDeletion{Confirmation: usrPkg.DeletionConfirmation}}), http.StatusForbidden)
+
+	// Tokens act on behalf of their owners only
+	other := env.addUser(t, 200)
+	wantError(t, env.doWithBearer(t, http.MethodPost, habitsPath(other), full, newHabitRequest("Swim")), http.StatusForbidden)
+
+	wantError(t, env.doWithBearer(t, http.MethodGet, habitsPath(u), atPkg.Prefix+"unknown", nil), http.StatusUnauthorized)
+}
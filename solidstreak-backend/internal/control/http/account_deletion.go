@@ -0,0 +1,121 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+type AccountDeletion struct {
+	Confirmation string `json:"confirmation"`
+}
+
+type PostAccountDeletionRequest struct {
+	Data *AccountDeletion `json:"data"`
+}
+
+// postAccountDeletion schedules deletion of the user's account after the grace period. Deletion
+// must be confirmed by typing the confirmation text
+func (s Server) postAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	if userID, err = getInt64FromURLParams(r, "userID", true); err != nil {
+		return
+	}
+
+	var req PostAccountDeletionRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err = decoder.Decode(&req); err != nil {
+		err = apperrors.ErrBadRequest("invalid request payload")
+		return
+	}
+
+	if req.Data == nil || req.Data.Confirmation != usrPkg.DeletionConfirmation {
+		err = apperrors.ErrBadRequest("account deletion must be confirmed with \"" + usrPkg.DeletionConfirmation + "\"")
+		return
+	}
+
+	if user.ID != userID {
+		err = apperrors.ErrForbidden("couldn't delete account of another user")
+		return
+	}
+
+	user.ScheduleDeletion(s.AccountDeletionGracePeriod)
+	if err = s.Res.UsrRepo.UpdateDeletionScheduledAt(r.Context(), user); err != nil {
+		return
+	}
+
+	logger.Info("account deletion scheduled", "userId", user.ID, "deletionScheduledAt", user.DeletionScheduledAt)
+
+	json.NewEncoder(w).Encode(GetUserResponse{Data: user})
+}
+
+// deleteAccountDeletion cancels scheduled deletion of the user's account
+func (s Server) deleteAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	if userID, err = getInt64FromURLParams(r, "userID", true); err != nil {
+		return
+	}
+
+	if user.ID != userID {
+		err = apperrors.ErrForbidden("couldn't cancel account deletion of another user")
+		return
+	}
+
+	if user.DeletionScheduledAt != nil {
+		user.CancelDeletion()
+		if err = s.Res.UsrRepo.UpdateDeletionScheduledAt(r.Context(), user); err != nil {
+			return
+		}
+		logger.Info("account deletion cancelled", "userId", user.ID)
+	}
+
+	json.NewEncoder(w).Encode(GetUserResponse{Data: user})
+}
@@ -10,14 +10,67 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	atUsecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/accesstoken"
 )
 
+// InitDataExpired is the code of authentication errors telling the client to get fresh initData,
+// i.e. to reopen the Mini App
+const InitDataExpired = "init_data_expired"
+
+// Allowed difference between Telegram's and server's clocks
+const initDataClockSkew = time.Minute
+
+var errInitDataExpired = errors.New("telegram initData has expired")
+
 type ctxKeyUserTgID struct{}
 
+type ctxKeyInitDataAuthDate struct{}
+
+type ctxKeyUser struct{}
+
+type ctxKeyAccessToken struct{}
+
+// ValidateTelegramInitData authenticates requests by Telegram initData only and puts Telegram ID of
+// the user into the context, since the user might not be registered yet, along with auth_date of
+// initData
 func (s Server) ValidateTelegramInitData() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := s.Res.Logger
+
+			// Adding request ID to request context
+			reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+			if reqID != "" {
+				logger = logger.With("requestId", reqID)
+			}
+
+			userTgID, authDate, err := s.validateInitDataHeader(r)
+			if err != nil {
+				processError(w, logger, err)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ctxKeyUserTgID{}, userTgID)
+			r = r.WithContext(context.WithValue(ctx, ctxKeyInitDataAuthDate{}, authDate))
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Authenticate authenticates requests of registered users by session or personal access token in
+// "Authorization: Bearer" header or by Telegram initData, and puts the user into the context along
+// with the access token if any
+func (s Server) Authenticate() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			var err error
@@ -30,25 +83,124 @@ func (s Server) ValidateTelegramInitData() func(http.Handler) http.Handler {
 				logger = logger.With("requestId", reqID)
 			}
 
-			initData := r.Header.Get("X-Telegram-InitData")
-			if initData == "" {
-				processError(w, logger, apperrors.ErrUnauthorized("missing telegram initData"))
-				return
+			defer func() {
+				if err != nil {
+					processError(w, logger, err)
+				}
+			}()
+
+			var (
+				user        *usrPkg.User
+				accessToken *atPkg.Token
+			)
+			if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(token, atPkg.Prefix) {
+				if user, accessToken, err = atUsecases.Authenticate(r.Context(), s.Res, token); err != nil {
+					return
+				}
+			} else if ok {
+				var userID int64
+				if userID, err = parseToken(token, sessionTokenType, s.SessionSecret, time.Now()); err != nil {
+					if errors.Is(err, errSessionExpired) {
+						err = apperrors.ErrUnauthorized(err.Error()).WithCode(SessionExpired)
+					} else {
+						err = apperrors.ErrUnauthorized(err.Error())
+					}
+					return
+				}
+				if user, err = s.Res.UsrRepo.GetByID(r.Context(), userID); err != nil {
+					return
+				}
+			} else {
+				var userTgID int64
+				if userTgID, _, err = s.validateInitDataHeader(r); err != nil {
+					return
+				}
+				if user, err = s.Res.UsrRepo.GetByTgID(r.Context(), userTgID); err != nil {
+					return
+				}
 			}
 
-			var userTgID int64
-			if userTgID, err = validateAndGetUserTgID(initData, s.Res.TgBotAPI.Token); err != nil {
-				processError(w, logger, apperrors.ErrUnauthorized(err.Error()))
+			ctx := context.WithValue(r.Context(), ctxKeyUser{}, user)
+			if accessToken != nil {
+				ctx = context.WithValue(ctx, ctxKeyAccessToken{}, accessToken)
+			}
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope rejects requests authenticated by access tokens which scope doesn't include the
+// specified one. Requests of the Mini App aren't limited
+func (s Server) RequireScope(scope atPkg.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if t, ok := r.Context().Value(ctxKeyAccessToken{}).(*atPkg.Token); ok && !t.Scope.Allows(scope) {
+				logger := s.Res.Logger
+
+				// Adding request ID to request context
+				reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+				if reqID != "" {
+					logger = logger.With("requestId", reqID)
+				}
+
+				processError(w, logger, apperrors.ErrForbidden("access token with \""+string(t.Scope)+"\" scope isn't allowed to do this"))
 				return
 			}
 
-			r = r.WithContext(context.WithValue(r.Context(), ctxKeyUserTgID{}, userTgID))
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DenyAccessTokens rejects requests authenticated by access tokens, so a leaked token couldn't be
+// used to issue new ones or delete the account
+func (s Server) DenyAccessTokens() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Value(ctxKeyAccessToken{}).(*atPkg.Token); ok {
+				logger := s.Res.Logger
+
+				// Adding request ID to request context
+				reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+				if reqID != "" {
+					logger = logger.With("requestId", reqID)
+				}
+
+				processError(w, logger, apperrors.ErrForbidden("this couldn't be done with access token, use the app"))
+				return
+			}
 
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
+// validateInitDataHeader validates initData of the request and returns Telegram ID of the user
+// along with auth_date of initData
+func (s Server) validateInitDataHeader(r *http.Request) (int64, time.Time, error) {
+	initData := r.Header.Get("X-Telegram-InitData")
+	if initData == "" {
+		return 0, time.Time{}, apperrors.ErrUnauthorized("missing telegram initData")
+	}
+
+	now := time.Now()
+
+	userTgID, authDate, err := validateInitData(initData, s.Res.TgBotAPIToken, s.InitDataMaxAge, now)
+	if err == nil {
+		err = s.initDataAuthDates.check(userTgID, authDate, s.InitDataMaxAge, now)
+	}
+	switch {
+	case errors.Is(err, errInitDataExpired):
+		return 0, time.Time{}, apperrors.ErrUnauthorized(err.Error()).WithCode(InitDataExpired)
+	case err != nil:
+		return 0, time.Time{}, apperrors.ErrUnauthorized(err.Error())
+	}
+
+	return userTgID, authDate, nil
+}
+
 func sign(payload, key string) string {
 	skHmac := hmac.New(sha256.New, []byte("WebAppData"))
 	skHmac.Write([]byte(key))
@@ -59,42 +211,60 @@ func sign(payload, key string) string {
 	return hex.EncodeToString(impHmac.Sum(nil))
 }
 
-func validateAndGetUserTgID(initData, token string) (int64, error) {
+// validateInitData checks initData signature and returns Telegram ID of the user and the time
+// the Mini App is opened at. InitData older than maxAge is rejected, unless maxAge is zero
+func validateInitData(initData, token string, maxAge time.Duration, now time.Time) (int64, time.Time, error) {
 	values, err := url.ParseQuery(initData)
 	if err != nil {
-		return 0, err
+		return 0, time.Time{}, err
 	}
 
 	var (
 		hash     string
 		userTgID int64
+		authDate time.Time
 		pairs    = make([]string, 0, len(values))
 	)
 
 	for k, v := range values {
-		if k == "hash" {
+		switch k {
+		case "hash":
 			hash = v[0]
 			continue
-		}
-		if k == "user" {
+		case "user":
 			if userTgID, err = extractUserTgIDFromJSONData(v[0]); err != nil {
-				return 0, err
+				return 0, time.Time{}, err
 			}
+		case "auth_date":
+			var sec int64
+			if sec, err = strconv.ParseInt(v[0], 10, 64); err != nil {
+				return 0, time.Time{}, errors.New("invalid auth_date in telegram initData")
+			}
+			authDate = time.Unix(sec, 0)
 		}
 		pairs = append(pairs, k+"="+v[0])
 	}
 
 	if hash == "" {
-		return 0, errors.New("missing hash in telegram initData")
+		return 0, time.Time{}, errors.New("missing hash in telegram initData")
 	}
 
 	sort.Strings(pairs)
 
-	if sign(strings.Join(pairs, "\n"), token) != hash {
-		return 0, errors.New("request authentication failed")
+	if !hmac.Equal([]byte(sign(strings.Join(pairs, "\n"), token)), []byte(hash)) {
+		return 0, time.Time{}, errors.New("request authentication failed")
+	}
+
+	switch {
+	case authDate.IsZero():
+		return 0, time.Time{}, errors.New("missing auth_date in telegram initData")
+	case authDate.After(now.Add(initDataClockSkew)):
+		return 0, time.Time{}, errors.New("auth_date of telegram initData is in the future")
+	case maxAge > 0 && now.Sub(authDate) > maxAge:
+		return 0, time.Time{}, errInitDataExpired
 	}
 
-	return userTgID, nil
+	return userTgID, authDate, nil
 }
 
 func extractUserTgIDFromJSONData(jsonStr string) (int64, error) {
@@ -109,3 +279,41 @@ func extractUserTgIDFromJSONData(jsonStr string) (int64, error) {
 
 	return u.ID, nil
 }
+
+// authDates keeps the latest auth_date of initData seen for each user. InitData issued before it
+// is rejected, so a leaked one is revoked once the user reopens the Mini App. Dates are kept in
+// memory, so each server replica revokes initData on its own. Replays of initData exchanged for a
+// session are rejected by the storage instead, see postSession
+type authDates struct {
+	sync.Mutex
+	latest    map[int64]time.Time
+	lastPrune time.Time
+}
+
+func newAuthDates() *authDates {
+	return &authDates{latest: map[int64]time.Time{}}
+}
+
+// check records authDate of the user's initData and rejects it if newer one is already seen, i.e.
+// the user has reopened the Mini App since it was issued.
+// Dates older than maxAge are dropped from time to time, since such initData is rejected anyway
+func (d *authDates) check(userTgID int64, authDate time.Time, maxAge time.Duration, now time.Time) error {
+	d.Lock()
+	defer d.Unlock()
+
+	if latest, ok := d.latest[userTgID]; ok && authDate.Before(latest) {
+		return errInitDataExpired
+	}
+	d.latest[userTgID] = authDate
+
+	if maxAge > 0 && now.Sub(d.lastPrune) > maxAge {
+		for id, latest := range d.latest {
+			if now.Sub(latest) > maxAge {
+				delete(d.latest, id)
+			}
+		}
+		d.lastPrune = now
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func authDate(t time.Time) url.Values {
+	return url.Values{"auth_date": {strconv.FormatInt(t.Unix(), 10)}}
+}
+
+func TestValidateInitData(t *testing.T) {
+	now := time.Now()
+
+	withoutAuthDate := signInitData(url.Values{"user": {`{"id":100}`}}, testBotToken)
+
+	tests := []struct {
+		name        string
+		initData    string
+		maxAge      time.Duration
+		wantErr     bool
+		wantExpired bool
+	}{
+		{"fresh", initData(100, authDate(now)), time.Hour, false, false},
+		{"almost expired", initData(100, authDate(now.Add(-time.Hour+time.Second))), time.Hour, false, false},
+		{"expired", initData(100, authDate(now.Add(-time.Hour-time.Second))), time.Hour, true, true},
+		{"old without max age", initData(100, authDate(now.AddDate(-1, 0, 0))), 0, false, false},
+		{"within clock skew", initData(100, authDate(now.Add(30*time.Second))), time.Hour, false, false},
+		{"in the future", initData(100, authDate(now.Add(time.Hour))), time.Hour, true, false},
+		{"invalid auth_date", initData(100, url.Values{"auth_date": {"yesterday"}}), time.Hour, true, false},
+		{"missing auth_date", withoutAuthDate, time.Hour, true, false},
+		{"signed with another token", signInitData(authDate(now), "654321:another-token"), time.Hour, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userTgID, _, err := validateInitData(tt.initData, testBotToken, tt.maxAge, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if errors.Is(err, errInitDataExpired) != tt.wantExpired {
+				t.Errorf("got error %v, want expiration %t", err, tt.wantExpired)
+			}
+			if err == nil && userTgID != 100 {
+				t.Errorf("got user Telegram ID %d, want 100", userTgID)
+			}
+		})
+	}
+}
+
+func TestAuthDatesRevokeOnReopen(t *testing.T) {
+	now := time.Now()
+	launch := now.Add(-10 * time.Minute)
+	reopen := now.Add(-time.Minute)
+
+	type call struct {
+		userTgID    int64
+		authDate    time.Time
+		wantRevoked bool
+	}
+	tests := []struct {
+		name  string
+		calls []call
+	}{
+		{"reused until reopen", []call{{100, launch, false}, {100, launch, false}, {100, launch, false}}},
+		{"revoked by reopen", []call{{100, launch, false}, {100, reopen, false}, {100, launch, true}, {100, reopen, false}}},
+		{"seen out of order", []call{{100, reopen, false}, {100, launch, true}}},
+		{"other users unaffected", []call{{100, reopen, false}, {200, launch, false}, {200, launch, false}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newAuthDates()
+			for i, c := range tt.calls {
+				err := d.check(c.userTgID, c.authDate, time.Hour, now)
+				if c.wantRevoked && !errors.Is(err, errInitDataExpired) {
+					t.Errorf("call %d: got error %v, want expiration", i, err)
+				}
+				if !c.wantRevoked && err != nil {
+					t.Errorf("call %d: got error %v, want none", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthDatesPruning(t *testing.T) {
+	d := newAuthDates()
+	now := time.Now()
+
+	if err := d.check(100, now, time.Hour, now); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dates of expired initData are pruned, it's rejected by its age anyway
+	later := now.Add(2 * time.Hour)
+	if err := d.check(300, later, time.Hour, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := d.latest[100]; ok || len(d.latest) != 1 {
+		t.Errorf("expired dates aren't pruned: %v", d.latest)
+	}
+}
+
+func TestInitDataExpiredResponse(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	path := "/api/v1/users/" + strconv.FormatInt(u.ID, 10)
+
+	get := func(initData string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("X-Telegram-InitData", initData)
+		w := httptest.NewRecorder()
+		env.handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if apperror := wantError(t, get(initData(100, authDate(time.Now().Add(-2*time.Hour)))), http.StatusUnauthorized); apperror.Code != InitDataExpired {
+		t.Errorf("got error code %q for expired initData, want %q", apperror.Code, InitDataExpired)
+	}
+
+	// InitData of the previous Mini App launch is accepted until the app is reopened
+	previous := initData(100, authDate(time.Now().Add(-time.Minute)))
+	data[any](t, get(previous))
+	data[any](t, get(previous))
+	data[any](t, get(initData(100, nil)))
+	if apperror := wantError(t, get(previous), http.StatusUnauthorized); apperror.Code != InitDataExpired {
+		t.Errorf("got error code %q for initData revoked by reopening, want %q", apperror.Code, InitDataExpired)
+	}
+
+	if apperror := wantError(t, get(initData(100, authDate(time.Now().Add(time.Hour)))), http.StatusUnauthorized); apperror.Code != "" {
+		t.Errorf("got error code %q for initData from the future, want none", apperror.Code)
+	}
+}
@@ -1,7 +1,9 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -26,7 +28,16 @@ func writeError(w http.ResponseWriter, err error) {
 func processError(w http.ResponseWriter, logger *slog.Logger, err error) {
 	apperror, ok := err.(apperrors.Error)
 	if !ok {
-		apperror = apperrors.ErrInternal(err.Error())
+		switch {
+		case errors.Is(err, context.DeadlineExceeded):
+			apperror = apperrors.ErrServiceUnavailable("request timed out")
+		case errors.Is(err, context.Canceled):
+			// Client has gone, there is no one to respond to
+			logger.Warn("request cancelled", "error", err)
+			return
+		default:
+			apperror = apperrors.ErrInternal(err.Error())
+		}
 	}
 
 	logger.Error("error occurred", "error", err)
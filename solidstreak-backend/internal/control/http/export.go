@@ -0,0 +1,75 @@
+package http
+
+import (
+	"net/http"
+
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/export"
+)
+
+// getUserExport streams zip archive with all user's data
+func (s Server) getUserExport(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	userID, err = getInt64FromURLParams(r, "userID", true)
+	if err != nil {
+		return
+	}
+
+	if userID != user.ID {
+		err = apperrors.ErrForbidden("couldn't export data of another user")
+		return
+	}
+
+	var exp *export.Export
+	if exp, err = export.New(r.Context(), s.Res, user.ID); err != nil {
+		return
+	}
+
+	aw := &archiveWriter{w: w, fileName: exp.FileName()}
+	if err = exp.WriteZip(r.Context(), aw); err != nil && aw.started {
+		// Writing errors couldn't be reported to the client after the response is started
+		logger.Error("data export writing error", "error", err)
+		err = nil
+	}
+}
+
+// archiveWriter starts the archive response on the first write, so errors occurred before it are
+// still reported as JSON
+type archiveWriter struct {
+	w        http.ResponseWriter
+	fileName string
+	started  bool
+}
+
+func (aw *archiveWriter) Write(p []byte) (int, error) {
+	if !aw.started {
+		aw.started = true
+		aw.w.Header().Set("Content-Type", "application/zip")
+		aw.w.Header().Set("Content-Disposition", "attachment; filename=\""+aw.fileName+"\"")
+	}
+	return aw.w.Write(p)
+}
@@ -0,0 +1,161 @@
+package http
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	hRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit/repo"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/importer"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+func TestGetUserExport(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	other := env.addUser(t, 200)
+	env.addHabit(t, u, newHabitRequest("Read"))
+
+	w := env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(u.ID, 10)+"/export", u.TgID, nil)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("got status %d and content type %q: %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]bool{}
+	for _, f := range zr.File {
+		files[f.Name] = true
+	}
+	if !files["users.json"] || !files["habits.json"] {
+		t.Errorf("unexpected archive files %v", files)
+	}
+
+	wantError(t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(other.ID, 10)+"/export", u.TgID, nil), http.StatusForbidden)
+}
+
+func TestGetUserExportChecks(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	read := env.addHabit(t, u, newHabitRequest("Read"))
+	run := env.addHabit(t, u, newHabitRequest("Run"))
+	env.check(t, u, run.ID, u.Today(), ptr(true), nil)
+	env.check(t, u, read.ID, u.Today(), ptr(true), nil)
+
+	w := env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(u.ID, 10)+"/export", u.TgID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d: %s", w.Code, w.Body)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zr.Open("user_habit_checks.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	habitID := func(h *hPkg.Habit) string { return strconv.FormatInt(h.ID, 10) }
+	if len(records) != 3 || records[1][1] != habitID(read) || records[2][1] != habitID(run) || records[1][2] != u.Today().String() {
+		t.Errorf("unexpected checks %v", records)
+	}
+}
+
+// failingChecksRepo passes the number of checks and then fails, as a lost connection does
+type failingChecksRepo struct {
+	hRepo.Repo
+	checks int
+}
+
+func (r failingChecksRepo) ForEachUserCheck(ctx context.Context, userID int64, fn func(*hPkg.HabitCheck) error) error {
+	for i := range r.checks {
+		hc := &hPkg.HabitCheck{HabitID: int64(i), UserID: userID, CheckDate: date.Today().AddDate(0, 0, -i), Completed: true, CheckedAt: time.Now()}
+		if err := fn(hc); err != nil {
+			return err
+		}
+	}
+	return errors.New("connection lost")
+}
+
+func TestGetUserExportFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     int
+		wantStatus int
+	}{
+		{"before the first byte", 0, http.StatusInternalServerError},
+		{"after the first byte", 20000, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+			u := env.addUser(t, 100)
+			env.res.HabitRepo = failingChecksRepo{Repo: env.res.HabitRepo, checks: tt.checks}
+			env.initHandler()
+
+			w := env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(u.ID, 10)+"/export", u.TgID, nil)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				wantError(t, w, tt.wantStatus)
+				if w.Header().Get("Content-Disposition") != "" {
+					t.Error("error is reported as attachment")
+				}
+				return
+			}
+
+			if w.Header().Get("Content-Type") != "application/zip" {
+				t.Errorf("got content type %q", w.Header().Get("Content-Type"))
+			}
+			if _, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len())); err == nil {
+				t.Error("broken archive is completed")
+			}
+		})
+	}
+}
+
+func TestPostUserImport(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	path := "/users/" + strconv.FormatInt(u.ID, 10) + "/import"
+
+	today := u.Today()
+	csv := []byte("habit,date\nRead," + today.String() + "\nRead," + today.AddDate(0, 0, -1).String() + "\n")
+
+	dryRun := data[*importer.Result](t, env.do(t, http.MethodPost, path+"?dry_run=true", u.TgID, csv))
+	if dryRun.Format != importer.Generic || dryRun.Imported || len(dryRun.Habits) != 1 || dryRun.Habits[0].Checks != 2 {
+		t.Errorf("unexpected dry run result %+v", dryRun)
+	}
+	if len(env.db.Habits) != 0 {
+		t.Fatalf("habits are imported in dry run mode: %+v", env.db.Habits)
+	}
+
+	result := data[*importer.Result](t, env.do(t, http.MethodPost, path, u.TgID, csv))
+	if !result.Imported || len(result.Habits) != 1 || !result.Habits[0].New {
+		t.Errorf("unexpected import result %+v", result)
+	}
+	checks := data[[]*HabitCheck](t, env.do(t, http.MethodGet, habitsPath(u, result.Habits[0].HabitID, "checks"), u.TgID, nil))
+	if len(checks) != 2 {
+		t.Errorf("got %d imported checks, want 2", len(checks))
+	}
+
+	wantError(t, env.do(t, http.MethodPost, path, u.TgID, []byte{}), http.StatusBadRequest)
+	wantError(t, env.do(t, http.MethodPost, path+"?format=unknown", u.TgID, csv), http.StatusBadRequest)
+	wantError(t, env.do(t, http.MethodPost, "/users/"+strconv.FormatInt(u.ID+1, 10)+"/import", u.TgID, csv), http.StatusForbidden)
+}
@@ -0,0 +1,447 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	fPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+type Friend struct {
+	User      *usrPkg.User `json:"user"`
+	Status    fPkg.Status  `json:"status"`
+	Incoming  bool         `json:"incoming"` // Request is sent to the user, not by them
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
+
+type GetFriendsResponse struct {
+	Data []*Friend `json:"data"`
+}
+
+type PostFriendResponse struct {
+	Data *fPkg.Friendship `json:"data"`
+}
+
+type FeedItem struct {
+	User   *usrPkg.User  `json:"user"`
+	Habits []*hPkg.Habit `json:"habits"`
+}
+
+type GetFeedResponse struct {
+	Data []*FeedItem `json:"data"`
+}
+
+func (s Server) getFriends(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	userID, err = getInt64FromURLParams(r, "userID", true)
+	if err != nil {
+		return
+	}
+
+	status := fPkg.Accepted
+	if statusStr := r.URL.Query().Get("status"); statusStr != "" {
+		if status, ok = fPkg.StatusMapping[statusStr]; !ok {
+			err = apperrors.ErrBadRequest("invalid friendship status \"" + statusStr + "\" in URL query")
+			return
+		}
+	}
+
+	if userID != user.ID {
+		err = apperrors.ErrForbidden("couldn't get friends of another user")
+		return
+	}
+
+	var friendships []*fPkg.Friendship
+	if friendships, err = s.Res.FriendRepo.GetByUserIDAndStatus(r.Context(), user.ID, status); err != nil {
+		return
+	}
+
+	friends := make([]*Friend, 0, len(friendships))
+	for _, f := range friendships {
+		var other *usrPkg.User
+		if other, err = s.Res.UsrRepo.GetByID(r.Context(), f.OtherID(user.ID)); err != nil {
+			return
+		}
+		friends = append(friends, &Friend{
+			User:      other,
+			Status:    f.Status,
+			Incoming:  f.IsIncomingFor(user.ID),
+			UpdatedAt: f.UpdatedAt,
+		})
+	}
+
+	response := GetFriendsResponse{Data: friends}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// postFriendRequest sends friend request to the user. Request to the user who has already sent
+// one to the requester accepts it
+func (s Server) postFriendRequest(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	var (
+		user     *usrPkg.User
+		friendID int64
+	)
+	if user, friendID, err = s.getUserAndFriendID(r, "couldn't send friend request for another user"); err != nil {
+		return
+	}
+
+	if _, err = s.Res.UsrRepo.GetByID(r.Context(), friendID); err != nil {
+		return
+	}
+
+	var f *fPkg.Friendship
+	f, err = s.Res.FriendRepo.Get(r.Context(), user.ID, friendID)
+	switch {
+	case isNotFound(err):
+		err = nil
+		f = fPkg.NewRequest(user.ID, friendID)
+	case err != nil:
+		return
+	case f.Status == fPkg.Blocked:
+		err = apperrors.ErrForbidden("couldn't send friend request to specified user")
+		return
+	case f.Status == fPkg.Pending && f.IsIncomingFor(user.ID):
+		f.Accept()
+	default: // Already requested or accepted
+		json.NewEncoder(w).Encode(PostFriendResponse{Data: f})
+		return
+	}
+
+	if err = s.Res.FriendRepo.Save(r.Context(), f); err != nil {
+		return
+	}
+
+	response := PostFriendResponse{Data: f}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s Server) postFriendAccept(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	var (
+		user     *usrPkg.User
+		friendID int64
+	)
+	if user, friendID, err = s.getUserAndFriendID(r, "couldn't accept friend request for another user"); err != nil {
+		return
+	}
+
+	var f *fPkg.Friendship
+	if f, err = s.Res.FriendRepo.Get(r.Context(), user.ID, friendID); err != nil && !isNotFound(err) {
+		return
+	}
+	if f == nil || f.Status != fPkg.Pending || !f.IsIncomingFor(user.ID) {
+		err = apperrors.ErrNotFound("couldn't find friend request from specified user")
+		return
+	}
+
+	f.Accept()
+
+	if err = s.Res.FriendRepo.Save(r.Context(), f); err != nil {
+		return
+	}
+
+	response := PostFriendResponse{Data: f}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// deleteFriend removes a friend, cancels or declines a friend request, or unblocks the user
+func (s Server) deleteFriend(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	var (
+		user     *usrPkg.User
+		friendID int64
+	)
+	if user, friendID, err = s.getUserAndFriendID(r, "couldn't remove friend of another user"); err != nil {
+		return
+	}
+
+	var f *fPkg.Friendship
+	if f, err = s.Res.FriendRepo.Get(r.Context(), user.ID, friendID); err != nil {
+		return
+	}
+
+	// Blocking is hidden from the blocked user
+	if f.Status == fPkg.Blocked && f.RequesterID != user.ID {
+		err = apperrors.ErrNotFound("couldn't find relationship with specified user")
+		return
+	}
+
+	if err = s.Res.FriendRepo.Delete(r.Context(), user.ID, friendID); err != nil {
+		return
+	}
+
+	response := PostFriendResponse{Data: f}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// postFriendBlock blocks the user, replacing friendship or friend request with them
+func (s Server) postFriendBlock(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	var (
+		user     *usrPkg.User
+		friendID int64
+	)
+	if user, friendID, err = s.getUserAndFriendID(r, "couldn't block user for another user"); err != nil {
+		return
+	}
+
+	if _, err = s.Res.UsrRepo.GetByID(r.Context(), friendID); err != nil {
+		return
+	}
+
+	var f *fPkg.Friendship
+	f, err = s.Res.FriendRepo.Get(r.Context(), user.ID, friendID)
+	switch {
+	case isNotFound(err):
+		err = nil
+	case err != nil:
+		return
+	case f.Status == fPkg.Blocked:
+		// Block by the other user is kept and isn't revealed
+		if f.RequesterID != user.ID {
+			f = fPkg.NewBlock(user.ID, friendID)
+		}
+		json.NewEncoder(w).Encode(PostFriendResponse{Data: f})
+		return
+	}
+
+	f = fPkg.NewBlock(user.ID, friendID)
+
+	if err = s.Res.FriendRepo.Save(r.Context(), f); err != nil {
+		return
+	}
+
+	response := PostFriendResponse{Data: f}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// getFeed returns friends' public active habits with their checks, by default over the last week
+func (s Server) getFeed(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	userID, err = getInt64FromURLParams(r, "userID", true)
+	if err != nil {
+		return
+	}
+
+	if userID != user.ID {
+		err = apperrors.ErrForbidden("couldn't get feed of another user")
+		return
+	}
+
+	var fromDate, toDate *date.Date
+	if fromDate, toDate, err = getFromToDatesFromURLQuery(r, user.Today()); err != nil {
+		return
+	}
+	if r.URL.Query().Get("from") == "" {
+		d := toDate.AddDate(0, 0, -6)
+		fromDate = &d
+	}
+
+	var friendships []*fPkg.Friendship
+	if friendships, err = s.Res.FriendRepo.GetByUserIDAndStatus(r.Context(), user.ID, fPkg.Accepted); err != nil {
+		return
+	}
+
+	feed := make([]*FeedItem, 0, len(friendships))
+	for _, f := range friendships {
+		item := &FeedItem{}
+		if item.User, err = s.Res.UsrRepo.GetByID(r.Context(), f.OtherID(user.ID)); err != nil {
+			return
+		}
+		if item.Habits, err = s.Res.HabitRepo.GetByOwnerIDAndStatus(r.Context(), item.User.ID, hPkg.Active, false); err != nil {
+			return
+		}
+		if len(item.Habits) == 0 {
+			continue
+		}
+
+		habitIDs := make([]int64, 0, len(item.Habits))
+		for _, h := range item.Habits {
+			habitIDs = append(habitIDs, h.ID)
+		}
+		var habitChecks []*hPkg.HabitCheck
+		if habitChecks, err = s.Res.HabitRepo.GetUserHabitsCompletedChecks(r.Context(), item.User.ID, habitIDs, fromDate, toDate); err != nil {
+			return
+		}
+		habitChecksByHabitID := make(map[int64][]*hPkg.HabitCheck)
+		for _, hc := range habitChecks {
+			habitChecksByHabitID[hc.HabitID] = append(habitChecksByHabitID[hc.HabitID], hc)
+		}
+		for _, h := range item.Habits {
+			h.Checks = habitChecksByHabitID[h.ID]
+		}
+
+		feed = append(feed, item)
+	}
+
+	response := GetFeedResponse{Data: feed}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// checkCanView returns an error if the user isn't allowed to view another user's profile. Public
+// habits are visible to everyone regardless
+func (s Server) checkCanView(ctx context.Context, userID, otherID int64) error {
+	if userID == otherID {
+		return nil
+	}
+
+	f, err := s.Res.FriendRepo.Get(ctx, userID, otherID)
+	if err != nil && !isNotFound(err) {
+		return err
+	}
+	if f == nil || f.Status != fPkg.Accepted {
+		return apperrors.ErrForbidden("only friends could view another user's profile")
+	}
+
+	return nil
+}
+
+func (s Server) getUserAndFriendID(r *http.Request, forbiddenDetail string) (*usrPkg.User, int64, error) {
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		return nil, 0, apperrors.ErrUnauthorized("couldn't identify user")
+	}
+
+	userID, err := getInt64FromURLParams(r, "userID", true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	friendID, err := getInt64FromURLParams(r, "friendID", true)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if userID != user.ID {
+		return nil, 0, apperrors.ErrForbidden(forbiddenDetail)
+	}
+	if friendID == user.ID {
+		return nil, 0, apperrors.ErrBadRequest("couldn't make relationship with oneself")
+	}
+
+	return user, friendID, nil
+}
+
+func isNotFound(err error) bool {
+	var apperror apperrors.Error
+	return errors.As(err, &apperror) && apperror.HTTPCode == http.StatusNotFound
+}
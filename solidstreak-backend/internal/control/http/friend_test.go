@@ -0,0 +1,156 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	fPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+func friendsPath(u *usrPkg.User, parts ...string) string {
+	path := "/users/" + strconv.FormatInt(u.ID, 10) + "/friends"
+	for _, p := range parts {
+		path += "/" + p
+	}
+	return path
+}
+
+func friendPath(u, friend *usrPkg.User, action string) string {
+	path := friendsPath(u, strconv.FormatInt(friend.ID, 10))
+	if action != "" {
+		path += "/" + action
+	}
+	return path
+}
+
+func TestFriendRequest(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	other := env.addUser(t, 200)
+
+	f := data[*fPkg.Friendship](t, env.do(t, http.MethodPost, friendPath(u, other, "request"), u.TgID, nil))
+	if f.Status != fPkg.Pending || f.RequesterID != u.ID {
+		t.Fatalf("unexpected friend request %+v", f)
+	}
+
+	incoming := data[[]*Friend](t, env.do(t, http.MethodGet, friendsPath(other)+"?status=pending", other.TgID, nil))
+	if len(incoming) != 1 || incoming[0].User.ID != u.ID || !incoming[0].Incoming {
+		t.Errorf("unexpected incoming requests %+v", incoming)
+	}
+	outgoing := data[[]*Friend](t, env.do(t, http.MethodGet, friendsPath(u)+"?status=pending", u.TgID, nil))
+	if len(outgoing) != 1 || outgoing[0].User.ID != other.ID || outgoing[0].Incoming {
+		t.Errorf("unexpected outgoing requests %+v", outgoing)
+	}
+
+	// Request couldn't be accepted by the requester
+	wantError(t, env.do(t, http.MethodPost, friendPath(u, other, "accept"), u.TgID, nil), http.StatusNotFound)
+
+	accepted := data[*fPkg.Friendship](t, env.do(t, http.MethodPost, friendPath(other, u, "accept"), other.TgID, nil))
+	if accepted.Status != fPkg.Accepted {
+		t.Errorf("friend request isn't accepted: %+v", accepted)
+	}
+
+	friends := data[[]*Friend](t, env.do(t, http.MethodGet, friendsPath(u), u.TgID, nil))
+	if len(friends) != 1 || friends[0].User.ID != other.ID || friends[0].Status != fPkg.Accepted {
+		t.Errorf("unexpected friends %+v", friends)
+	}
+}
+
+func TestFriendRequestCrossed(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	other := env.addUser(t, 200)
+
+	data[*fPkg.Friendship](t, env.do(t, http.MethodPost, friendPath(u, other, "request"), u.TgID, nil))
+
+	// Request to the user who already requested friendship accepts it
+	if f := data[*fPkg.Friendship](t, env.do(t, http.MethodPost, friendPath(other, u, "request"), other.TgID, nil)); f.Status != fPkg.Accepted {
+		t.Errorf("crossed friend request isn't accepted: %+v", f)
+	}
+}
+
+func TestFriendRequestValidation(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	other := env.addUser(t, 200)
+
+	wantError(t, env.do(t, http.MethodPost, friendPath(u, u, "request"), u.TgID, nil), http.StatusBadRequest)
+	wantError(t, env.do(t, http.MethodPost, friendPath(other, u, "request"), u.TgID, nil), http.StatusForbidden)
+	wantError(t, env.do(t, http.MethodPost, friendsPath(u, "999", "request"), u.TgID, nil), http.StatusNotFound)
+	wantError(t, env.do(t, http.MethodPost, friendsPath(u, "abc", "request"), u.TgID, nil), http.StatusBadRequest)
+}
+
+func TestDeleteFriend(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	other := env.addUser(t, 200)
+	env.befriend(t, u, other)
+
+	data[*fPkg.Friendship](t, env.do(t, http.MethodDelete, friendPath(other, u, ""), other.TgID, nil))
+	if friends := data[[]*Friend](t, env.do(t, http.MethodGet, friendsPath(u), u.TgID, nil)); len(friends) != 0 {
+		t.Errorf("removed friend is listed: %+v", friends)
+	}
+	wantError(t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(other.ID, 10), u.TgID, nil), http.StatusForbidden)
+
+	wantError(t, env.do(t, http.MethodDelete, friendPath(other, u, ""), other.TgID, nil), http.StatusNotFound)
+}
+
+func TestFriendBlock(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	other := env.addUser(t, 200)
+	env.befriend(t, u, other)
+
+	blocked := data[*fPkg.Friendship](t, env.do(t, http.MethodPost, friendPath(u, other, "block"), u.TgID, nil))
+	if blocked.Status != fPkg.Blocked || blocked.RequesterID != u.ID {
+		t.Fatalf("unexpected block %+v", blocked)
+	}
+
+	wantError(t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(u.ID, 10), other.TgID, nil), http.StatusForbidden)
+	wantError(t, env.do(t, http.MethodPost, friendPath(other, u, "request"), other.TgID, nil), http.StatusForbidden)
+
+	// Blocking is hidden from the blocked user, so they couldn't remove it
+	wantError(t, env.do(t, http.MethodDelete, friendPath(other, u, ""), other.TgID, nil), http.StatusNotFound)
+	if list := data[[]*Friend](t, env.do(t, http.MethodGet, friendsPath(u)+"?status=blocked", u.TgID, nil)); len(list) != 1 {
+		t.Errorf("got %d blocked users, want 1", len(list))
+	}
+
+	// Unblocking removes the relationship
+	data[*fPkg.Friendship](t, env.do(t, http.MethodDelete, friendPath(u, other, ""), u.TgID, nil))
+	data[*fPkg.Friendship](t, env.do(t, http.MethodPost, friendPath(other, u, "request"), other.TgID, nil))
+}
+
+func TestFeed(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	friend := env.addUser(t, 200)
+	idle := env.addUser(t, 300)
+	stranger := env.addUser(t, 400)
+	env.befriend(t, u, friend)
+	env.befriend(t, u, idle)
+
+	public := env.addHabit(t, friend, newHabitRequest("Run"))
+	private := newHabitRequest("Diary")
+	private.Data.IsPublic = ptr(false)
+	env.addHabit(t, friend, private)
+	env.addHabit(t, stranger, newHabitRequest("Swim"))
+
+	today := friend.Today()
+	env.db.Habits[public.ID].CreatedAt = time.Now().AddDate(0, 0, -7)
+	data[*hPkg.HabitCheck](t, env.check(t, friend, public.ID, today, ptr(true), nil))
+	data[*hPkg.HabitCheck](t, env.check(t, friend, public.ID, today.AddDate(0, 0, -7), ptr(true), nil)) // Out of default range
+
+	feed := data[[]*FeedItem](t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(u.ID, 10)+"/feed", u.TgID, nil))
+	if len(feed) != 1 || feed[0].User.ID != friend.ID {
+		t.Fatalf("unexpected feed %+v", feed)
+	}
+	if habits := feed[0].Habits; len(habits) != 1 || habits[0].ID != public.ID || len(habits[0].Checks) != 1 {
+		t.Errorf("unexpected feed habits %+v", habits)
+	}
+
+	wantError(t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(friend.ID, 10)+"/feed", u.TgID, nil), http.StatusForbidden)
+}
@@ -3,13 +3,13 @@ package http
 import (
 	"encoding/json"
 	"net/http"
-	"time"
 
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
 	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
 
 	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
 	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	hUsecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/habit"
 )
 
 type GetHabitResponse struct {
@@ -17,11 +17,15 @@ type GetHabitResponse struct {
 }
 
 type Habit struct {
-	Archived    *bool   `json:"archived"`
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
-	Color       *string `json:"color"`
-	IsPublic    *bool   `json:"isPublic"`
+	Archived     *bool          `json:"archived"`
+	Title        *string        `json:"title"`
+	Description  *string        `json:"description"`
+	Color        *string        `json:"color"`
+	Schedule     *hPkg.Schedule `json:"schedule"`
+	Target       *float64       `json:"target"`
+	Unit         *string        `json:"unit"`
+	ReminderTime *string        `json:"reminderTime"`
+	IsPublic     *bool          `json:"isPublic"`
 }
 
 type PostPutHabitRequest struct {
@@ -39,6 +43,7 @@ type GetHabitsResponse struct {
 type HabitCheck struct {
 	CheckDate *date.Date `json:"checkDate"`
 	Completed *bool      `json:"completed"`
+	Amount    *float64   `json:"amount"`
 }
 
 type PostHabitCheckRequest struct {
@@ -53,7 +58,9 @@ type GetUserHabitsCompletedChecksResponse struct {
 	Data []*hPkg.HabitCheck `json:"data"`
 }
 
-// TODO: попробовать избавиться от дублирования кода
+type GetHabitStatsResponse struct {
+	Data *hPkg.Stats `json:"data"`
+}
 
 func (s Server) postHabit(w http.ResponseWriter, r *http.Request) {
 	var err error
@@ -72,7 +79,7 @@ func (s Server) postHabit(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	userTgID, ok := r.Context().Value(ctxKeyUserTgID{}).(int64)
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
 	if !ok {
 		err = apperrors.ErrUnauthorized("couldn't identify user")
 		return
@@ -96,39 +103,9 @@ func (s Server) postHabit(w http.ResponseWriter, r *http.Request) {
 		err = apperrors.ErrBadRequest("habit data is required")
 		return
 	}
-	if req.Data.Title == nil {
-		err = apperrors.ErrBadRequest("habit title is required")
-		return
-	}
-	var color hPkg.Color
-	if req.Data.Color != nil {
-		var ok bool
-		if color, ok = hPkg.ColorMapping[*req.Data.Color]; !ok {
-			err = apperrors.ErrBadRequest("invalid habit color")
-			return
-		}
-	}
-	if color == "" {
-		color = hPkg.Green
-	}
-	if req.Data.IsPublic == nil {
-		err = apperrors.ErrBadRequest("habit public status is required")
-		return
-	}
 
-	var user *usrPkg.User
-	if user, err = s.Res.UsrRepo.GetByTgID(userTgID); err != nil {
-		return
-	}
-
-	if userID != user.ID {
-		err = apperrors.ErrForbidden("couldn't create habit for another user")
-		return
-	}
-
-	habit := hPkg.NewHabit(*req.Data.Title, *req.Data.Description, color, user.ID, *req.Data.IsPublic)
-
-	if err = s.Res.HabitRepo.Create(habit); err != nil {
+	var habit *hPkg.Habit
+	if habit, err = hUsecases.CreateHabit(r.Context(), s.Res, user, userID, hUsecases.Data(*req.Data)); err != nil {
 		return
 	}
 
@@ -154,7 +131,7 @@ func (s Server) putHabit(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	userTgID, ok := r.Context().Value(ctxKeyUserTgID{}).(int64)
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
 	if !ok {
 		err = apperrors.ErrUnauthorized("couldn't identify user")
 		return
@@ -178,58 +155,9 @@ func (s Server) putHabit(w http.ResponseWriter, r *http.Request) {
 		err = apperrors.ErrBadRequest("habit data is required")
 		return
 	}
-	if req.Data.Archived == nil {
-		err = apperrors.ErrBadRequest("habit archived status is required")
-		return
-	}
-	if req.Data.Title == nil {
-		err = apperrors.ErrBadRequest("habit title is required")
-		return
-	}
-	var color hPkg.Color
-	if req.Data.Color != nil {
-		var ok bool
-		if color, ok = hPkg.ColorMapping[*req.Data.Color]; !ok {
-			err = apperrors.ErrBadRequest("invalid habit color")
-			return
-		}
-	}
-	if color == "" {
-		color = hPkg.Green
-	}
-	if req.Data.IsPublic == nil {
-		err = apperrors.ErrBadRequest("habit public status is required")
-		return
-	}
-
-	var user *usrPkg.User
-	if user, err = s.Res.UsrRepo.GetByTgID(userTgID); err != nil {
-		return
-	}
-
-	requestedByOwner := userID == user.ID
-	if !requestedByOwner {
-		err = apperrors.ErrForbidden("couldn't update habit for another user")
-		return
-	}
-
-	habit, err := s.Res.HabitRepo.GetByIDAndOwnerID(habitID, userID, requestedByOwner)
-	if err != nil {
-		return
-	}
-
-	habit.Archived = *req.Data.Archived
-	habit.Title = *req.Data.Title
-	if req.Data.Description != nil {
-		habit.Description = *req.Data.Description
-	} else {
-		habit.Description = ""
-	}
-	habit.Color = color
-	habit.IsPublic = *req.Data.IsPublic
-	habit.UpdatedAt = time.Now()
 
-	if err = s.Res.HabitRepo.Update(habit); err != nil {
+	var habit *hPkg.Habit
+	if habit, err = hUsecases.UpdateHabit(r.Context(), s.Res, user, userID, habitID, hUsecases.Data(*req.Data)); err != nil {
 		return
 	}
 
@@ -255,7 +183,7 @@ func (s Server) deleteHabit(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	userTgID, ok := r.Context().Value(ctxKeyUserTgID{}).(int64)
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
 	if !ok {
 		err = apperrors.ErrUnauthorized("couldn't identify user")
 		return
@@ -267,26 +195,8 @@ func (s Server) deleteHabit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user *usrPkg.User
-	if user, err = s.Res.UsrRepo.GetByTgID(userTgID); err != nil {
-		return
-	}
-
-	requestedByOwner := userID == user.ID
-	if !requestedByOwner {
-		err = apperrors.ErrForbidden("couldn't delete habit for another user")
-		return
-	}
-
-	habit, err := s.Res.HabitRepo.GetByIDAndOwnerID(habitID, userID, requestedByOwner)
-	if err != nil {
-		return
-	}
-
-	habit.Active = false
-	habit.UpdatedAt = time.Now()
-
-	if err = s.Res.HabitRepo.Update(habit); err != nil {
+	var habit *hPkg.Habit
+	if habit, err = hUsecases.DeleteHabit(r.Context(), s.Res, user, userID, habitID); err != nil {
 		return
 	}
 
@@ -312,7 +222,7 @@ func (s Server) getHabit(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	userTgID, ok := r.Context().Value(ctxKeyUserTgID{}).(int64)
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
 	if !ok {
 		err = apperrors.ErrUnauthorized("couldn't identify user")
 		return
@@ -324,41 +234,19 @@ func (s Server) getHabit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var withChecks bool
-	withChecks, err = getBoolFromURLQuery(r, "with_checks", false)
-	if err != nil {
+	opts := hUsecases.LoadOptions{}
+	if opts.WithChecks, err = getBoolFromURLQuery(r, "with_checks", false); err != nil {
 		return
 	}
-
-	var fromDate, toDate *date.Date
-	if withChecks {
-		fromDate, toDate, err = getFromToDatesFromURLQuery(r)
-		if err != nil {
-			return
-		}
-	}
-
-	var user *usrPkg.User
-	if user, err = s.Res.UsrRepo.GetByTgID(userTgID); err != nil {
+	if opts.From, opts.To, err = getDatesRangeFromURLQuery(r); err != nil {
 		return
 	}
 
-	var (
-		requestedByOwner bool = userID == user.ID
-		habit            *hPkg.Habit
-	)
-	if habit, err = s.Res.HabitRepo.GetByIDAndOwnerID(habitID, userID, requestedByOwner); err != nil {
+	var habit *hPkg.Habit
+	if habit, err = hUsecases.GetHabit(r.Context(), s.Res, user, userID, habitID, opts); err != nil {
 		return
 	}
 
-	if withChecks {
-		var habitChecks []*hPkg.HabitCheck
-		if habitChecks, err = s.Res.HabitRepo.GetUserHabitsCompletedChecks(userID, []int64{habit.ID}, fromDate, toDate); err != nil {
-			return
-		}
-		habit.Checks = habitChecks
-	}
-
 	response := GetHabitResponse{Data: habit}
 
 	json.NewEncoder(w).Encode(response)
@@ -381,7 +269,7 @@ func (s Server) getHabits(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	userTgID, ok := r.Context().Value(ctxKeyUserTgID{}).(int64)
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
 	if !ok {
 		err = apperrors.ErrUnauthorized("couldn't identify user")
 		return
@@ -393,55 +281,23 @@ func (s Server) getHabits(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var status hPkg.HabitStatus
-	status, err = getHabitStatusFromURLQuery(r)
-	if err != nil {
+	opts := hUsecases.LoadOptions{}
+	if opts.Status, err = getHabitStatusFromURLQuery(r); err != nil {
 		return
 	}
-
-	var withChecks bool
-	withChecks, err = getBoolFromURLQuery(r, "with_checks", false)
-	if err != nil {
+	if opts.WithChecks, err = getBoolFromURLQuery(r, "with_checks", false); err != nil {
 		return
 	}
-
-	var fromDate, toDate *date.Date
-	if withChecks {
-		fromDate, toDate, err = getFromToDatesFromURLQuery(r)
-		if err != nil {
-			return
-		}
-	}
-
-	var user *usrPkg.User
-	if user, err = s.Res.UsrRepo.GetByTgID(userTgID); err != nil {
+	if opts.WithStats, err = getBoolFromURLQuery(r, "with_stats", false); err != nil {
 		return
 	}
-
-	var (
-		requestedByOwner bool = userID == user.ID
-		habits           []*hPkg.Habit
-	)
-	if habits, err = s.Res.HabitRepo.GetByOwnerIDAndStatus(userID, status, requestedByOwner); err != nil {
+	if opts.From, opts.To, err = getDatesRangeFromURLQuery(r); err != nil {
 		return
 	}
 
-	if withChecks && len(habits) > 0 {
-		habitIDs := make([]int64, 0, len(habits))
-		for _, h := range habits {
-			habitIDs = append(habitIDs, h.ID)
-		}
-		var habitChecks []*hPkg.HabitCheck
-		if habitChecks, err = s.Res.HabitRepo.GetUserHabitsCompletedChecks(userID, habitIDs, fromDate, toDate); err != nil {
-			return
-		}
-		habitChecksByHabitID := make(map[int64][]*hPkg.HabitCheck)
-		for _, hc := range habitChecks {
-			habitChecksByHabitID[hc.HabitID] = append(habitChecksByHabitID[hc.HabitID], hc)
-		}
-		for _, h := range habits {
-			h.Checks = habitChecksByHabitID[h.ID]
-		}
+	var habits []*hPkg.Habit
+	if habits, err = hUsecases.ListHabits(r.Context(), s.Res, user, userID, opts); err != nil {
+		return
 	}
 
 	response := GetHabitsResponse{Data: habits}
@@ -466,20 +322,14 @@ func (s Server) postUserHabitCheck(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	userTgID, ok := r.Context().Value(ctxKeyUserTgID{}).(int64)
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
 	if !ok {
 		err = apperrors.ErrUnauthorized("couldn't identify user")
 		return
 	}
 
-	var userID int64
-	userID, err = getInt64FromURLParams(r, "userID", true)
-	if err != nil {
-		return
-	}
-
-	var habitID int64
-	habitID, err = getInt64FromURLParams(r, "habitID", true)
+	var userID, habitID int64
+	userID, habitID, err = getUserIDAndHabitIDFromURLParams(r)
 	if err != nil {
 		return
 	}
@@ -500,46 +350,66 @@ func (s Server) postUserHabitCheck(w http.ResponseWriter, r *http.Request) {
 		err = apperrors.ErrBadRequest("habit check date is required")
 		return
 	}
-	if req.Data.Completed == nil {
-		err = apperrors.ErrBadRequest("habit completion status is required")
+
+	var habitCheck *hPkg.HabitCheck
+	if _, habitCheck, err = hUsecases.CheckHabit(r.Context(), s.Res, user, userID, habitID, *req.Data.CheckDate, req.Data.Completed, req.Data.Amount, s.HabitCheckBackfillDays); err != nil {
 		return
 	}
 
-	var user *usrPkg.User
-	if user, err = s.Res.UsrRepo.GetByTgID(userTgID); err != nil {
-		return
+	response := PostHabitCheckResponse{Data: habitCheck}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s Server) getUserHabitCompletedChecks(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
 	}
 
-	requestedByOwner := userID == user.ID
-	if !requestedByOwner {
-		err = apperrors.ErrForbidden("couldn't check habit for another user")
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
 		return
 	}
 
-	var habit *hPkg.Habit
-	habit, err = s.Res.HabitRepo.GetByIDAndOwnerID(habitID, userID, requestedByOwner)
+	var userID, habitID int64
+	userID, habitID, err = getUserIDAndHabitIDFromURLParams(r)
 	if err != nil {
 		return
 	}
 
-	habitCheck := &hPkg.HabitCheck{
-		HabitID:   habit.ID,
-		UserID:    user.ID,
-		CheckDate: *req.Data.CheckDate,
-		Completed: *req.Data.Completed,
-		CheckedAt: time.Now(),
+	// Incomplete checks hold partial progress of quantitative habits
+	opts := hUsecases.LoadOptions{WithChecks: true}
+	if opts.IncludeIncomplete, err = getBoolFromURLQuery(r, "include_incomplete", false); err != nil {
+		return
+	}
+	if opts.From, opts.To, err = getDatesRangeFromURLQuery(r); err != nil {
+		return
 	}
 
-	if err = s.Res.HabitRepo.SetUserHabitCheck(habitCheck); err != nil {
+	var habit *hPkg.Habit
+	if habit, err = hUsecases.GetHabit(r.Context(), s.Res, user, userID, habitID, opts); err != nil {
 		return
 	}
 
-	response := PostHabitCheckResponse{Data: habitCheck}
+	response := GetUserHabitsCompletedChecksResponse{Data: habit.Checks}
 
 	json.NewEncoder(w).Encode(response)
 }
 
-func (s Server) getUserHabitCompletedChecks(w http.ResponseWriter, r *http.Request) {
+func (s Server) getHabitStats(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	logger := s.Res.Logger
@@ -556,7 +426,7 @@ func (s Server) getUserHabitCompletedChecks(w http.ResponseWriter, r *http.Reque
 		}
 	}()
 
-	userTgID, ok := r.Context().Value(ctxKeyUserTgID{}).(int64)
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
 	if !ok {
 		err = apperrors.ErrUnauthorized("couldn't identify user")
 		return
@@ -568,31 +438,17 @@ func (s Server) getUserHabitCompletedChecks(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	var fromDate, toDate *date.Date
-	fromDate, toDate, err = getFromToDatesFromURLQuery(r)
-	if err != nil {
-		return
-	}
-
-	var user *usrPkg.User
-	if user, err = s.Res.UsrRepo.GetByTgID(userTgID); err != nil {
-		return
-	}
-
-	var (
-		requestedByOwner bool = userID == user.ID
-		habit            *hPkg.Habit
-	)
-	if habit, err = s.Res.HabitRepo.GetByIDAndOwnerID(habitID, userID, requestedByOwner); err != nil {
+	opts := hUsecases.LoadOptions{WithStats: true}
+	if opts.From, opts.To, err = getDatesRangeFromURLQuery(r); err != nil {
 		return
 	}
 
-	var habitChecks []*hPkg.HabitCheck
-	if habitChecks, err = s.Res.HabitRepo.GetUserHabitsCompletedChecks(userID, []int64{habit.ID}, fromDate, toDate); err != nil {
+	var habit *hPkg.Habit
+	if habit, err = hUsecases.GetHabit(r.Context(), s.Res, user, userID, habitID, opts); err != nil {
 		return
 	}
 
-	response := GetUserHabitsCompletedChecksResponse{Data: habitChecks}
+	response := GetHabitStatsResponse{Data: habit.Stats}
 
 	json.NewEncoder(w).Encode(response)
 }
@@ -616,27 +472,34 @@ func getUserIDAndHabitIDFromURLParams(r *http.Request) (int64, int64, error) {
 	return userID, habitID, nil
 }
 
-func getFromToDatesFromURLQuery(r *http.Request) (*date.Date, *date.Date, error) {
-	var (
-		err              error
-		fromDate, toDate *date.Date
-	)
-
-	fromDate, err = getDateFromURLQuery(r, "from", false)
+// getDatesRangeFromURLQuery returns requested dates range, nil bounds aren't specified
+func getDatesRangeFromURLQuery(r *http.Request) (*date.Date, *date.Date, error) {
+	fromDate, err := getDateFromURLQuery(r, "from", false)
 	if err != nil {
 		return nil, nil, err
 	}
-	if fromDate == nil {
-		d := date.Today().AddDate(-1, 0, 0)
-		fromDate = &d
+
+	toDate, err := getDateFromURLQuery(r, "to", false)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	toDate, err = getDateFromURLQuery(r, "to", false)
+	return fromDate, toDate, nil
+}
+
+// getFromToDatesFromURLQuery returns requested dates range, by default the last year up to today
+func getFromToDatesFromURLQuery(r *http.Request, today date.Date) (*date.Date, *date.Date, error) {
+	fromDate, toDate, err := getDatesRangeFromURLQuery(r)
 	if err != nil {
 		return nil, nil, err
 	}
+
+	if fromDate == nil {
+		d := today.AddDate(-1, 0, 0)
+		fromDate = &d
+	}
 	if toDate == nil {
-		d := date.Today()
+		d := today
 		toDate = &d
 	}
 
@@ -0,0 +1,272 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	hUsecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/habit"
+)
+
+type HabitInvite struct {
+	*hPkg.Invite
+	BotLink string `json:"botLink"` // Opens the bot chat, which joins the user to the habit on start
+	AppLink string `json:"appLink"` // Opens the Mini App with the invite as start parameter
+}
+
+type PostHabitInviteResponse struct {
+	Data *HabitInvite `json:"data"`
+}
+
+type HabitJoin struct {
+	Token    *string `json:"token"`
+	IsPublic *bool   `json:"isPublic"`
+}
+
+type PostHabitJoinRequest struct {
+	Data *HabitJoin `json:"data"`
+}
+
+type GetHabitParticipantsResponse struct {
+	Data []*hPkg.Participant `json:"data"`
+}
+
+func (s Server) postHabitInvite(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID, habitID int64
+	userID, habitID, err = getUserIDAndHabitIDFromURLParams(r)
+	if err != nil {
+		return
+	}
+
+	requestedByOwner := userID == user.ID
+	if !requestedByOwner {
+		err = apperrors.ErrForbidden("couldn't invite to habit of another user")
+		return
+	}
+
+	var habit *hPkg.Habit
+	if habit, err = s.Res.HabitRepo.GetByIDAndOwnerID(r.Context(), habitID, userID, requestedByOwner); err != nil {
+		return
+	}
+
+	if !habit.IsCreator(user.ID) {
+		err = apperrors.ErrForbidden("only habit creator could invite to it")
+		return
+	}
+	if habit.Archived {
+		err = apperrors.ErrBadRequest("couldn't invite to archived habit")
+		return
+	}
+
+	var invite *hPkg.Invite
+	if invite, err = hPkg.NewInvite(habit.ID, user.ID, s.HabitInviteTTL); err != nil {
+		return
+	}
+
+	if err = s.Res.HabitRepo.CreateInvite(r.Context(), invite); err != nil {
+		return
+	}
+
+	response := PostHabitInviteResponse{Data: &HabitInvite{
+		Invite:  invite,
+		BotLink: "https://t.me/" + s.Res.TgBotUsername + "?start=" + invite.StartParam(),
+		AppLink: "https://t.me/" + s.Res.TgBotUsername + "?startapp=" + invite.StartParam(),
+	}}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s Server) postHabitJoin(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	userID, err = getInt64FromURLParams(r, "userID", true)
+	if err != nil {
+		return
+	}
+
+	var req PostHabitJoinRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err = decoder.Decode(&req); err != nil {
+		err = apperrors.ErrBadRequest("invalid request payload")
+		return
+	}
+
+	if req.Data == nil {
+		err = apperrors.ErrBadRequest("request data is required")
+		return
+	}
+	if req.Data.Token == nil || *req.Data.Token == "" {
+		err = apperrors.ErrBadRequest("habit invite token is required")
+		return
+	}
+	isPublic := false
+	if req.Data.IsPublic != nil {
+		isPublic = *req.Data.IsPublic
+	}
+
+	if userID != user.ID {
+		err = apperrors.ErrForbidden("couldn't join habit for another user")
+		return
+	}
+
+	var habit *hPkg.Habit
+	if habit, err = hUsecases.Join(r.Context(), s.Res, user, *req.Data.Token, isPublic); err != nil {
+		return
+	}
+
+	response := PostPutHabitResponse{Data: habit}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s Server) postHabitLeave(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID, habitID int64
+	userID, habitID, err = getUserIDAndHabitIDFromURLParams(r)
+	if err != nil {
+		return
+	}
+
+	requestedByOwner := userID == user.ID
+	if !requestedByOwner {
+		err = apperrors.ErrForbidden("couldn't leave habit for another user")
+		return
+	}
+
+	var habit *hPkg.Habit
+	if habit, err = s.Res.HabitRepo.GetByIDAndOwnerID(r.Context(), habitID, userID, requestedByOwner); err != nil {
+		return
+	}
+
+	if habit.IsCreator(user.ID) {
+		err = apperrors.ErrForbidden("habit creator couldn't leave it, delete it instead")
+		return
+	}
+
+	if err = s.Res.HabitRepo.RemoveMember(r.Context(), habit.ID, user.ID); err != nil {
+		return
+	}
+
+	response := PostPutHabitResponse{Data: habit}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+func (s Server) getHabitParticipants(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID, habitID int64
+	userID, habitID, err = getUserIDAndHabitIDFromURLParams(r)
+	if err != nil {
+		return
+	}
+
+	// Participants are visible to the habit participants only
+	requestedByOwner := userID == user.ID
+	if !requestedByOwner {
+		err = apperrors.ErrForbidden("couldn't get participants of habit of another user")
+		return
+	}
+
+	var habit *hPkg.Habit
+	if habit, err = s.Res.HabitRepo.GetByIDAndOwnerID(r.Context(), habitID, userID, requestedByOwner); err != nil {
+		return
+	}
+
+	var participants []*hPkg.Participant
+	if participants, err = s.Res.HabitRepo.GetParticipants(r.Context(), habit.ID); err != nil {
+		return
+	}
+
+	response := GetHabitParticipantsResponse{Data: participants}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,385 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func habitsPath(u *usrPkg.User, parts ...any) string {
+	path := "/users/" + strconv.FormatInt(u.ID, 10) + "/habits"
+	for _, p := range parts {
+		switch v := p.(type) {
+		case int64:
+			path += "/" + strconv.FormatInt(v, 10)
+		case string:
+			path += "/" + v
+		}
+	}
+	return path
+}
+
+func newHabitRequest(title string) PostPutHabitRequest {
+	return PostPutHabitRequest{Data: &Habit{Title: ptr(title), Description: ptr(""), IsPublic: ptr(true)}}
+}
+
+// addHabit creates the habit of the user through the API
+func (env *testEnv) addHabit(t *testing.T, u *usrPkg.User, req PostPutHabitRequest) *hPkg.Habit {
+	t.Helper()
+
+	return data[*hPkg.Habit](t, env.do(t, http.MethodPost, habitsPath(u), u.TgID, req))
+}
+
+func (env *testEnv) check(t *testing.T, u *usrPkg.User, habitID int64, d date.Date, completed *bool, amount *float64) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return env.do(t, http.MethodPost, habitsPath(u, habitID, "checks"), u.TgID, PostHabitCheckRequest{Data: &HabitCheck{CheckDate: &d, Completed: completed, Amount: amount}})
+}
+
+func TestPostHabit(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+
+	req := newHabitRequest("Read")
+	req.Data.Color = ptr("blue")
+	req.Data.Schedule = &hPkg.Schedule{Type: hPkg.Weekdays, Weekdays: []int{1, 3, 5}}
+	req.Data.ReminderTime = ptr("08:30")
+
+	h := env.addHabit(t, u, req)
+	if h.ID == 0 || h.Title != "Read" || h.Color != hPkg.Blue || h.Schedule.Type != hPkg.Weekdays || h.ReminderTime != "08:30" || h.CreatorID != u.ID || !h.IsPublic {
+		t.Errorf("unexpected created habit %+v", h)
+	}
+
+	got := data[*hPkg.Habit](t, env.do(t, http.MethodGet, habitsPath(u, h.ID), 100, nil))
+	if got.ID != h.ID || !got.Schedule.Equal(h.Schedule) {
+		t.Errorf("got habit %+v, want %+v", got, h)
+	}
+
+	// Description and color are optional
+	minimal := env.addHabit(t, u, PostPutHabitRequest{Data: &Habit{Title: ptr("Walk"), IsPublic: ptr(false)}})
+	if minimal.Description != "" || minimal.Color != hPkg.Green || minimal.Schedule.Type != hPkg.DailySchedule().Type {
+		t.Errorf("unexpected habit with defaults %+v", minimal)
+	}
+}
+
+func TestPostHabitValidation(t *testing.T) {
+	withData := func(modify func(*Habit)) PostPutHabitRequest {
+		req := newHabitRequest("Read")
+		modify(req.Data)
+		return req
+	}
+
+	tests := []struct {
+		name string
+		req  any
+	}{
+		{"invalid payload", []byte("[]")},
+		{"missing data", PostPutHabitRequest{}},
+		{"missing title", withData(func(h *Habit) { h.Title = nil })},
+		{"missing public status", withData(func(h *Habit) { h.IsPublic = nil })},
+		{"invalid color", withData(func(h *Habit) { h.Color = ptr("black") })},
+		{"invalid schedule", withData(func(h *Habit) { h.Schedule = &hPkg.Schedule{Type: hPkg.TimesPerWeek, Times: 8} })},
+		{"unit without target", withData(func(h *Habit) { h.Unit = ptr("pages") })},
+		{"non-positive target", withData(func(h *Habit) { h.Target = ptr(0.0) })},
+		{"invalid reminder time", withData(func(h *Habit) { h.ReminderTime = ptr("25:00") })},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+			u := env.addUser(t, 100)
+
+			wantError(t, env.do(t, http.MethodPost, habitsPath(u), 100, tt.req), http.StatusBadRequest)
+			if len(env.db.Habits) != 0 {
+				t.Errorf("unexpected habits %+v", env.db.Habits)
+			}
+		})
+	}
+
+	t.Run("another user", func(t *testing.T) {
+		env := newTestEnv()
+		env.addUser(t, 100)
+		other := env.addUser(t, 200)
+
+		wantError(t, env.do(t, http.MethodPost, habitsPath(other), 100, newHabitRequest("Read")), http.StatusForbidden)
+	})
+}
+
+func TestPutHabit(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	h := env.addHabit(t, u, newHabitRequest("Read"))
+
+	req := newHabitRequest("Read books")
+	req.Data.Archived = ptr(true)
+	req.Data.IsPublic = ptr(false)
+
+	updated := data[*hPkg.Habit](t, env.do(t, http.MethodPut, habitsPath(u, h.ID), 100, req))
+	if updated.Title != "Read books" || !updated.Archived || updated.IsPublic {
+		t.Errorf("unexpected updated habit %+v", updated)
+	}
+
+	active := data[[]*hPkg.Habit](t, env.do(t, http.MethodGet, habitsPath(u)+"?status=active", 100, nil))
+	archived := data[[]*hPkg.Habit](t, env.do(t, http.MethodGet, habitsPath(u)+"?status=archived", 100, nil))
+	if len(active) != 0 || len(archived) != 1 || archived[0].Title != "Read books" {
+		t.Errorf("got active %+v and archived %+v habits", active, archived)
+	}
+
+	wantError(t, env.do(t, http.MethodPut, habitsPath(u, h.ID), 100, newHabitRequest("Read")), http.StatusBadRequest) // Missing archived status
+	wantError(t, env.do(t, http.MethodPut, habitsPath(u, int64(999)), 100, req), http.StatusNotFound)
+}
+
+func TestPutArchivedHabitKeepsArchivingTime(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	h := env.addHabit(t, u, newHabitRequest("Read"))
+
+	req := newHabitRequest("Read")
+	req.Data.Archived = ptr(true)
+	archived := data[*hPkg.Habit](t, env.do(t, http.MethodPut, habitsPath(u, h.ID), 100, req))
+	if archived.ArchivedAt == nil {
+		t.Fatalf("archiving time isn't set: %+v", archived)
+	}
+
+	req = newHabitRequest("Read books")
+	req.Data.Archived = ptr(true)
+	edited := data[*hPkg.Habit](t, env.do(t, http.MethodPut, habitsPath(u, h.ID), 100, req))
+	if edited.ArchivedAt == nil || !edited.ArchivedAt.Equal(*archived.ArchivedAt) {
+		t.Errorf("archiving time is changed by editing from %v to %v", archived.ArchivedAt, edited.ArchivedAt)
+	}
+
+	req.Data.Archived = ptr(false)
+	if restored := data[*hPkg.Habit](t, env.do(t, http.MethodPut, habitsPath(u, h.ID), 100, req)); restored.ArchivedAt != nil {
+		t.Errorf("archiving time is kept for unarchived habit: %v", restored.ArchivedAt)
+	}
+}
+
+func TestPutHabitTargetRecalcsChecks(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+
+	req := newHabitRequest("Water")
+	req.Data.Target = ptr(8.0)
+	req.Data.Unit = ptr("glasses")
+	h := env.addHabit(t, u, req)
+
+	today := u.Today()
+	if hc := data[*hPkg.HabitCheck](t, env.check(t, u, h.ID, today, nil, ptr(6.0))); hc.Completed {
+		t.Fatal("partial check is completed")
+	}
+
+	update := newHabitRequest("Water")
+	update.Data.Archived = ptr(false)
+	update.Data.Target = ptr(5.0)
+	data[*hPkg.Habit](t, env.do(t, http.MethodPut, habitsPath(u, h.ID), 100, update))
+
+	checks := data[[]*hPkg.HabitCheck](t, env.do(t, http.MethodGet, habitsPath(u, h.ID, "checks"), 100, nil))
+	if len(checks) != 1 || !checks[0].Completed {
+		t.Errorf("check isn't completed after target is lowered: %+v", checks)
+	}
+
+	// Habit kind couldn't be changed
+	plain := env.addHabit(t, u, newHabitRequest("Read"))
+	wantError(t, env.do(t, http.MethodPut, habitsPath(u, plain.ID), 100, update), http.StatusBadRequest)
+}
+
+func TestHabitChecks(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	h := env.addHabit(t, u, newHabitRequest("Read"))
+	today := u.Today()
+	// Statistics start when the habit is created and joined
+	env.db.Habits[h.ID].CreatedAt = time.Now().AddDate(0, 0, -7)
+	env.db.Members[memdb.MemberKey{HabitID: h.ID, UserID: u.ID}].JoinedAt = time.Now().AddDate(0, 0, -7)
+
+	for i := range 3 {
+		data[*hPkg.HabitCheck](t, env.check(t, u, h.ID, today.AddDate(0, 0, -i), ptr(true), nil))
+	}
+	data[*hPkg.HabitCheck](t, env.check(t, u, h.ID, today.AddDate(0, 0, -3), ptr(false), nil))
+
+	checks := data[[]*hPkg.HabitCheck](t, env.do(t, http.MethodGet, habitsPath(u, h.ID, "checks"), 100, nil))
+	if len(checks) != 3 {
+		t.Errorf("got %d completed checks, want 3", len(checks))
+	}
+	all := data[[]*hPkg.HabitCheck](t, env.do(t, http.MethodGet, habitsPath(u, h.ID, "checks")+"?include_incomplete=true", 100, nil))
+	if len(all) != 4 || all[0].CheckDate.Compare(today.AddDate(0, 0, -3)) != 0 {
+		t.Errorf("unexpected checks including incomplete %+v", all)
+	}
+	ranged := data[[]*hPkg.HabitCheck](t, env.do(t, http.MethodGet, habitsPath(u, h.ID, "checks")+"?from="+today.String()+"&to="+today.String(), 100, nil))
+	if len(ranged) != 1 {
+		t.Errorf("got %d checks for today, want 1", len(ranged))
+	}
+
+	stats := data[*hPkg.Stats](t, env.do(t, http.MethodGet, habitsPath(u, h.ID, "stats"), 100, nil))
+	if stats.CurrentStreak != 3 || stats.TotalCompleted != 3 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	habits := data[[]*hPkg.Habit](t, env.do(t, http.MethodGet, habitsPath(u)+"?with_checks=true&with_stats=true", 100, nil))
+	if len(habits) != 1 || len(habits[0].Checks) != 3 || habits[0].Stats == nil || habits[0].Stats.CurrentStreak != 3 {
+		t.Errorf("unexpected habits with checks and stats %+v", habits)
+	}
+
+	withChecks := data[*hPkg.Habit](t, env.do(t, http.MethodGet, habitsPath(u, h.ID)+"?with_checks=true", 100, nil))
+	if len(withChecks.Checks) != 3 {
+		t.Errorf("got habit with %d checks, want 3", len(withChecks.Checks))
+	}
+
+	wantError(t, env.check(t, u, h.ID, today, nil, nil), http.StatusBadRequest)
+	wantError(t, env.check(t, u, h.ID, today, ptr(true), ptr(1.0)), http.StatusBadRequest)
+	wantError(t, env.check(t, u, int64(999), today, ptr(true), nil), http.StatusNotFound)
+}
+
+func TestHabitCheckDate(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	h := env.addHabit(t, u, newHabitRequest("Read"))
+	today := u.Today()
+
+	tests := []struct {
+		name      string
+		createdAt time.Time
+		checkDate date.Date
+		wantCode  string
+	}{
+		{"today", time.Now(), today, ""},
+		{"in the future", time.Now(), today.AddDate(0, 0, 1), hPkg.CheckDateInFuture},
+		{"next year", time.Now(), today.AddDate(1, 0, 0), hPkg.CheckDateInFuture},
+		{"before creation", time.Now(), today.AddDate(0, 0, -1), hPkg.CheckDateBeforeCreation},
+		{"in backfill window", time.Now().AddDate(0, 0, -30), today.AddDate(0, 0, -7), ""},
+		{"out of backfill window", time.Now().AddDate(0, 0, -30), today.AddDate(0, 0, -8), hPkg.CheckDateOutOfBackfill},
+		{"epoch", time.Now().AddDate(0, 0, -30), date.New(time.Unix(0, 0)), hPkg.CheckDateBeforeCreation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env.db.Habits[h.ID].CreatedAt = tt.createdAt
+
+			w := env.check(t, u, h.ID, tt.checkDate, ptr(true), nil)
+			if tt.wantCode == "" {
+				data[*hPkg.HabitCheck](t, w)
+				return
+			}
+			if apperror := wantError(t, w, http.StatusBadRequest); apperror.Code != tt.wantCode {
+				t.Errorf("got error code %q, want %q", apperror.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestHabitsVisibility(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	friend := env.addUser(t, 200)
+	stranger := env.addUser(t, 300)
+	env.befriend(t, u, friend)
+
+	public := env.addHabit(t, u, newHabitRequest("Read"))
+	private := newHabitRequest("Diary")
+	private.Data.IsPublic = ptr(false)
+	hidden := env.addHabit(t, u, private)
+
+	// Public habits are visible to every user, not only friends, as before friendships
+	for _, viewer := range []*usrPkg.User{friend, stranger} {
+		habits := data[[]*hPkg.Habit](t, env.do(t, http.MethodGet, habitsPath(u), viewer.TgID, nil))
+		if len(habits) != 1 || habits[0].ID != public.ID {
+			t.Errorf("user %d got habits %+v, want public one only", viewer.ID, habits)
+		}
+		data[*hPkg.Habit](t, env.do(t, http.MethodGet, habitsPath(u, public.ID), viewer.TgID, nil))
+		wantError(t, env.do(t, http.MethodGet, habitsPath(u, hidden.ID), viewer.TgID, nil), http.StatusNotFound)
+		data[*hPkg.Stats](t, env.do(t, http.MethodGet, habitsPath(u, public.ID, "stats"), viewer.TgID, nil))
+		data[[]*hPkg.HabitCheck](t, env.do(t, http.MethodGet, habitsPath(u, public.ID, "checks"), viewer.TgID, nil))
+	}
+
+	// Profile is visible to friends only
+	data[*usrPkg.User](t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(u.ID, 10), friend.TgID, nil))
+	wantError(t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(u.ID, 10), stranger.TgID, nil), http.StatusForbidden)
+
+	// Friends couldn't change anything
+	update := newHabitRequest("Mine")
+	update.Data.Archived = ptr(false)
+	wantError(t, env.do(t, http.MethodPut, habitsPath(u, public.ID), friend.TgID, update), http.StatusForbidden)
+	wantError(t, env.do(t, http.MethodDelete, habitsPath(u, public.ID), friend.TgID, nil), http.StatusForbidden)
+	today := u.Today()
+	wantError(t, env.do(t, http.MethodPost, habitsPath(u, public.ID, "checks"), friend.TgID, PostHabitCheckRequest{Data: &HabitCheck{CheckDate: &today, Completed: ptr(true)}}), http.StatusForbidden)
+}
+
+func TestHabitTrash(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	h := env.addHabit(t, u, newHabitRequest("Read"))
+
+	deleted := data[*hPkg.Habit](t, env.do(t, http.MethodDelete, habitsPath(u, h.ID), 100, nil))
+	if deleted.Active || deleted.DeletedAt == nil {
+		t.Fatalf("habit isn't deleted: %+v", deleted)
+	}
+	if habits := data[[]*hPkg.Habit](t, env.do(t, http.MethodGet, habitsPath(u), 100, nil)); len(habits) != 0 {
+		t.Errorf("deleted habit is listed: %+v", habits)
+	}
+
+	trash := data[[]*TrashedHabit](t, env.do(t, http.MethodGet, habitsPath(u, "trash"), 100, nil))
+	if len(trash) != 1 || trash[0].ID != h.ID || trash[0].PurgeAt.Sub(*trash[0].DeletedAt) != 30*24*time.Hour {
+		t.Fatalf("unexpected trash %+v", trash)
+	}
+
+	restored := data[*hPkg.Habit](t, env.do(t, http.MethodPost, habitsPath(u, h.ID, "restore"), 100, nil))
+	if !restored.Active || restored.DeletedAt != nil {
+		t.Errorf("habit isn't restored: %+v", restored)
+	}
+	if trash := data[[]*TrashedHabit](t, env.do(t, http.MethodGet, habitsPath(u, "trash"), 100, nil)); len(trash) != 0 {
+		t.Errorf("restored habit is in the trash: %+v", trash)
+	}
+
+	wantError(t, env.do(t, http.MethodPost, habitsPath(u, h.ID, "restore"), 100, nil), http.StatusBadRequest)
+}
+
+func TestHabitSharing(t *testing.T) {
+	env := newTestEnv()
+	creator := env.addUser(t, 100)
+	member := env.addUser(t, 200)
+	h := env.addHabit(t, creator, newHabitRequest("Run"))
+
+	invite := data[*HabitInvite](t, env.do(t, http.MethodPost, habitsPath(creator, h.ID, "invites"), creator.TgID, nil))
+	if invite.Token == "" || invite.BotLink != "https://t.me/solidstreak_bot?start="+invite.StartParam() {
+		t.Fatalf("unexpected invite %+v", invite)
+	}
+
+	wantError(t, env.do(t, http.MethodPost, habitsPath(member, "join"), member.TgID, PostHabitJoinRequest{Data: &HabitJoin{Token: ptr("unknown")}}), http.StatusNotFound)
+	wantError(t, env.do(t, http.MethodPost, habitsPath(member, "join"), member.TgID, PostHabitJoinRequest{Data: &HabitJoin{}}), http.StatusBadRequest)
+
+	joined := data[*hPkg.Habit](t, env.do(t, http.MethodPost, habitsPath(member, "join"), member.TgID, PostHabitJoinRequest{Data: &HabitJoin{Token: &invite.Token}}))
+	if joined.ID != h.ID || joined.IsPublic {
+		t.Errorf("unexpected joined habit %+v", joined)
+	}
+
+	participants := data[[]*hPkg.Participant](t, env.do(t, http.MethodGet, habitsPath(member, h.ID, "participants"), member.TgID, nil))
+	if len(participants) != 2 || participants[0].UserID != creator.ID || !participants[0].IsCreator || participants[1].UserID != member.ID {
+		t.Errorf("unexpected participants %+v", participants)
+	}
+
+	// Participants could change their own visibility only
+	req := newHabitRequest("Run")
+	req.Data.Archived = ptr(false)
+	if updated := data[*hPkg.Habit](t, env.do(t, http.MethodPut, habitsPath(member, h.ID), member.TgID, req)); !updated.IsPublic {
+		t.Error("participant's visibility isn't changed")
+	}
+	req.Data.Title = ptr("Walk")
+	wantError(t, env.do(t, http.MethodPut, habitsPath(member, h.ID), member.TgID, req), http.StatusForbidden)
+	wantError(t, env.do(t, http.MethodPost, habitsPath(member, h.ID, "invites"), member.TgID, nil), http.StatusForbidden)
+	wantError(t, env.do(t, http.MethodDelete, habitsPath(member, h.ID), member.TgID, nil), http.StatusForbidden)
+
+	wantError(t, env.do(t, http.MethodPost, habitsPath(creator, h.ID, "leave"), creator.TgID, nil), http.StatusForbidden)
+	data[*hPkg.Habit](t, env.do(t, http.MethodPost, habitsPath(member, h.ID, "leave"), member.TgID, nil))
+	wantError(t, env.do(t, http.MethodGet, habitsPath(member, h.ID, "participants"), member.TgID, nil), http.StatusNotFound)
+}
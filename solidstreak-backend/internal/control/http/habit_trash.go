@@ -0,0 +1,128 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+type TrashedHabit struct {
+	*hPkg.Habit
+	PurgeAt time.Time `json:"purgeAt"` // When the habit is deleted permanently
+}
+
+type GetHabitsTrashResponse struct {
+	Data []*TrashedHabit `json:"data"`
+}
+
+// getHabitsTrash returns the user's deleted habits, which could be restored until they're purged
+func (s Server) getHabitsTrash(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	if userID, err = getInt64FromURLParams(r, "userID", true); err != nil {
+		return
+	}
+
+	if userID != user.ID {
+		err = apperrors.ErrForbidden("couldn't get deleted habits of another user")
+		return
+	}
+
+	var habits []*hPkg.Habit
+	if habits, err = s.Res.HabitRepo.GetTrashedByCreatorID(r.Context(), user.ID); err != nil {
+		return
+	}
+
+	trashed := make([]*TrashedHabit, 0, len(habits))
+	for _, h := range habits {
+		trashed = append(trashed, &TrashedHabit{Habit: h, PurgeAt: h.DeletedAt.Add(s.HabitTrashRetention)})
+	}
+
+	response := GetHabitsTrashResponse{Data: trashed}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// postHabitRestore returns the deleted habit from the trash
+func (s Server) postHabitRestore(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var habitID, userID int64
+	if userID, habitID, err = getUserIDAndHabitIDFromURLParams(r); err != nil {
+		return
+	}
+
+	if userID != user.ID {
+		err = apperrors.ErrForbidden("couldn't restore habit of another user")
+		return
+	}
+
+	var habit *hPkg.Habit
+	if habit, err = s.Res.HabitRepo.GetByIDAndOwnerID(r.Context(), habitID, user.ID, true); err != nil {
+		return
+	}
+
+	if !habit.IsCreator(user.ID) {
+		err = apperrors.ErrForbidden("only habit creator could restore it")
+		return
+	}
+	if habit.Active {
+		err = apperrors.ErrBadRequest("habit isn't deleted")
+		return
+	}
+
+	habit.Restore()
+	if err = s.Res.HabitRepo.Update(r.Context(), habit); err != nil {
+		return
+	}
+
+	response := PostPutHabitResponse{Data: habit}
+
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/importer"
+)
+
+// Max size of uploaded import file
+const maxImportSize = 32 << 20
+
+type PostImportResponse struct {
+	Data *importer.Result `json:"data"`
+}
+
+// postUserImport imports habits and checks from the file sent as request body. Nothing is imported
+// if the file has invalid rows, unless "skip_invalid" is set, and in "dry_run" mode
+func (s Server) postUserImport(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var userID int64
+	userID, err = getInt64FromURLParams(r, "userID", true)
+	if err != nil {
+		return
+	}
+
+	opts := importer.Options{Format: importer.Auto}
+	if formatStr := r.URL.Query().Get("format"); formatStr != "" {
+		if opts.Format, ok = importer.FormatMapping[formatStr]; !ok {
+			err = apperrors.ErrBadRequest("invalid \"format\" in URL query")
+			return
+		}
+	}
+	if opts.DryRun, err = getBoolFromURLQuery(r, "dry_run", false); err != nil {
+		return
+	}
+	if opts.SkipInvalid, err = getBoolFromURLQuery(r, "skip_invalid", false); err != nil {
+		return
+	}
+
+	if userID != user.ID {
+		err = apperrors.ErrForbidden("couldn't import data of another user")
+		return
+	}
+
+	var data []byte
+	if data, err = io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize)); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			err = apperrors.ErrBadRequest("import file is too large")
+		} else {
+			err = apperrors.ErrBadRequest("couldn't read import file")
+		}
+		return
+	}
+	if len(data) == 0 {
+		err = apperrors.ErrBadRequest("import file is empty")
+		return
+	}
+
+	var result *importer.Result
+	if result, err = importer.Import(r.Context(), s.Res, user, data, opts); err != nil {
+		return
+	}
+
+	response := PostImportResponse{Data: result}
+
+	json.NewEncoder(w).Encode(response)
+}
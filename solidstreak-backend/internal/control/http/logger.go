@@ -2,16 +2,37 @@ package http
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+const redacted = "[REDACTED]"
+
+// alwaysRedactedHeaders carry credentials, so they're redacted whatever is configured
+var alwaysRedactedHeaders = []string{"Authorization", "X-Telegram-InitData"}
+
+// RequestLogConfig controls what's logged about API requests
+type RequestLogConfig struct {
+	RedactHeaders      []string // Request and response headers logged redacted, case-insensitive
+	RedactFields       []string // Fields of JSON bodies logged redacted at any depth, case-insensitive
+	MaxBodyBytes       int      // Logged bodies are truncated to this size, unlimited if zero
+	SampleSuccessEvery int      // Only every Nth successful request is logged, all if zero or one. Failed ones are always logged
+}
+
 // responseLogger оборачивает http.ResponseWriter для логирования ответа
 type responseLogger struct {
 	http.ResponseWriter
-	status int
-	body   bytes.Buffer
+	status    int
+	size      int
+	body      bytes.Buffer // JSON body only, binary ones aren't kept
+	maxBody   int          // Size the body is kept up to, unlimited if zero
+	truncated bool         // Body is bigger than the kept part
 }
 
 func (rl *responseLogger) WriteHeader(statusCode int) {
@@ -20,11 +41,35 @@ func (rl *responseLogger) WriteHeader(statusCode int) {
 }
 
 func (rl *responseLogger) Write(b []byte) (int, error) {
-	rl.body.Write(b)
+	rl.size += len(b)
+	if isJSON(rl.Header().Get("Content-Type")) && !rl.truncated {
+		kept := b
+		if rl.maxBody > 0 && rl.body.Len()+len(b) > rl.maxBody {
+			kept = b[:rl.maxBody-rl.body.Len()]
+			rl.truncated = true
+		}
+		rl.body.Write(kept)
+	}
 	return rl.ResponseWriter.Write(b)
 }
 
+// Flush sends the response written so far to the client, e.g. parts of streamed archives
+func (rl *responseLogger) Flush() {
+	http.NewResponseController(rl.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (rl *responseLogger) Unwrap() http.ResponseWriter {
+	return rl.ResponseWriter
+}
+
+// Logger logs API requests along with their responses once they're handled
 func (s Server) Logger() func(http.Handler) http.Handler {
+	redactHeaders := lowerSet(append(alwaysRedactedHeaders, s.RequestLog.RedactHeaders...))
+	redactFields := lowerSet(s.RequestLog.RedactFields)
+
+	var successes atomic.Uint64
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			logger := s.Res.Logger
@@ -35,47 +80,136 @@ func (s Server) Logger() func(http.Handler) http.Handler {
 				logger = logger.With("requestId", reqID)
 			}
 
-			// Request body reading
+			start := time.Now()
+
+			// Request body reading, limited for requests aren't authenticated yet. Uploaded files,
+			// e.g. data import archives, aren't read
 			var body string
 			if r.Body != nil && (r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch) {
-				data, err := io.ReadAll(r.Body)
-				if err == nil {
-					body = string(data)
-					r.Body = io.NopCloser(bytes.NewReader(data))
+				if ct := r.Header.Get("Content-Type"); ct == "" || isJSON(ct) {
+					var reader io.Reader = r.Body
+					if max := s.RequestLog.MaxBodyBytes; max > 0 {
+						reader = io.LimitReader(r.Body, int64(max)+1)
+					}
+					data, err := io.ReadAll(reader)
+					if err == nil {
+						if max := s.RequestLog.MaxBodyBytes; max > 0 && len(data) > max {
+							body = "<more than " + strconv.Itoa(max) + " bytes>"
+						} else {
+							body = s.logBody(data, redactFields)
+						}
+					}
+					// The rest of the body is passed to the handler unread
+					r.Body = readCloser{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+				} else {
+					body = "<" + strconv.FormatInt(r.ContentLength, 10) + " bytes of " + ct + ">"
 				}
 			}
 
-			// Request headers serialization
-			reqHeaders := make(map[string]string)
-			for k, v := range r.Header {
-				if len(v) > 0 {
-					reqHeaders[k] = v[0]
-				}
+			rl := &responseLogger{ResponseWriter: w, status: 200, maxBody: s.RequestLog.MaxBodyBytes}
+			next.ServeHTTP(rl, r)
+
+			if rl.status < 400 && s.RequestLog.SampleSuccessEvery > 1 &&
+				(successes.Add(1)-1)%uint64(s.RequestLog.SampleSuccessEvery) != 0 {
+				return
 			}
 
-			logger.Info("request received",
+			// Binary responses, e.g. data export archives, aren't logged. Truncated bodies couldn't be
+			// parsed to redact their fields, so they're logged only if there are no fields to redact
+			var respBody string
+			switch ct := rl.Header().Get("Content-Type"); {
+			case ct != "" && !isJSON(ct):
+				respBody = "<" + strconv.Itoa(rl.size) + " bytes of " + ct + ">"
+			case rl.truncated && len(redactFields) > 0:
+				respBody = "<more than " + strconv.Itoa(rl.maxBody) + " bytes>"
+			case rl.truncated:
+				respBody = strings.ToValidUTF8(rl.body.String(), "") + "...<" + strconv.Itoa(rl.size) + " bytes>"
+			default:
+				respBody = s.logBody(rl.body.Bytes(), redactFields)
+			}
+
+			logger.Info("request handled",
 				slog.String("method", r.Method),
 				slog.String("url", r.URL.String()),
-				slog.Any("headers", reqHeaders),
+				slog.Int("status", rl.status),
+				slog.Int64("latencyMs", time.Since(start).Milliseconds()),
+				slog.Any("headers", logHeaders(r.Header, redactHeaders)),
 				slog.String("body", body),
+				slog.Any("responseHeaders", logHeaders(rl.Header(), redactHeaders)),
+				slog.String("responseBody", respBody),
 			)
+		})
+	}
+}
 
-			rl := &responseLogger{ResponseWriter: w, status: 200}
-			next.ServeHTTP(rl, r)
+// logBody returns the JSON body with redacted fields, truncated to the configured size. Bodies
+// which couldn't be redacted aren't logged
+func (s Server) logBody(data []byte, redactFields map[string]bool) string {
+	if len(redactFields) > 0 && len(data) > 0 {
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return "<unparseable body, " + strconv.Itoa(len(data)) + " bytes>"
+		}
+		redactedData, err := json.Marshal(redactJSON(v, redactFields))
+		if err != nil {
+			return "<unparseable body, " + strconv.Itoa(len(data)) + " bytes>"
+		}
+		data = redactedData
+	}
 
-			// Response headers serialization
-			respHeaders := make(map[string]string)
-			for k, v := range rl.Header() {
-				if len(v) > 0 {
-					respHeaders[k] = v[0]
-				}
+	if max := s.RequestLog.MaxBodyBytes; max > 0 && len(data) > max {
+		return strings.ToValidUTF8(string(data[:max]), "") + "...<" + strconv.Itoa(len(data)) + " bytes>"
+	}
+	return string(data)
+}
+
+// redactJSON replaces values of the fields at any depth of the decoded JSON
+func redactJSON(v any, fields map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, fv := range v {
+			if fields[strings.ToLower(k)] {
+				v[k] = redacted
+			} else {
+				v[k] = redactJSON(fv, fields)
 			}
+		}
+	case []any:
+		for i, iv := range v {
+			v[i] = redactJSON(iv, fields)
+		}
+	}
+	return v
+}
 
-			logger.Info("response sent",
-				slog.Int("status", rl.status),
-				slog.Any("headers", respHeaders),
-				slog.String("body", rl.body.String()),
-			)
-		})
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func logHeaders(h http.Header, redactHeaders map[string]bool) map[string]string {
+	headers := make(map[string]string, len(h))
+	for k, v := range h {
+		if len(v) == 0 {
+			continue
+		}
+		if redactHeaders[strings.ToLower(k)] {
+			headers[k] = redacted
+		} else {
+			headers[k] = v[0]
+		}
 	}
+	return headers
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
+
+func isJSON(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/json")
 }
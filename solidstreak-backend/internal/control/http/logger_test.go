@@ -0,0 +1,312 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+)
+
+type logEntry struct {
+	Msg             string            `json:"msg"`
+	RequestID       string            `json:"requestId"`
+	Status          int               `json:"status"`
+	LatencyMs       *int64            `json:"latencyMs"`
+	Headers         map[string]string `json:"headers"`
+	Body            string            `json:"body"`
+	ResponseHeaders map[string]string `json:"responseHeaders"`
+	ResponseBody    string            `json:"responseBody"`
+}
+
+// logRequests passes requests through the logger to the handler, returning the logged entries
+func logRequests(t *testing.T, cfg RequestLogConfig, handler http.HandlerFunc, reqs ...*http.Request) []logEntry {
+	t.Helper()
+
+	var buf bytes.Buffer
+	s := Server{RequestLog: cfg, Res: resources.Resources{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}}
+	h := s.Logger()(handler)
+
+	for _, r := range reqs {
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	var entries []logEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var e logEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("couldn't decode log entry %q: %v", line, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	cfg := RequestLogConfig{
+		RedactHeaders: []string{"x-telegram-initdata", "Authorization"},
+		RedactFields:  []string{"refreshToken", "token", "tgUsername"},
+	}
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "refresh-secret") {
+			t.Errorf("handler got altered body %s", body)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Authorization", "Bearer response-secret")
+		w.Write([]byte(`{"data":{"token":"session-secret","user":{"id":1,"tgUsername":"alex"},"habits":[{"token":"nested-secret"}]}}`))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(`{"data":{"refreshToken":"refresh-secret"}}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Telegram-InitData", "query_id=1&hash=initdata-secret")
+	r.Header.Set("Authorization", "Bearer request-secret")
+	r.Header.Set("X-Request-ID", "abc")
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeyRequestID{}, "abc"))
+
+	entries := logRequests(t, cfg, handler, r)
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	e := entries[0]
+
+	raw, _ := json.Marshal(e)
+	for _, secret := range []string{"initdata-secret", "request-secret", "response-secret", "refresh-secret", "session-secret", "nested-secret", "alex"} {
+		if strings.Contains(string(raw), secret) {
+			t.Errorf("log entry contains %q: %s", secret, raw)
+		}
+	}
+
+	if e.RequestID != "abc" || e.Headers["X-Request-Id"] != "abc" {
+		t.Errorf("request ID isn't logged: %+v", e)
+	}
+	if e.Headers["X-Telegram-Initdata"] != redacted || e.ResponseHeaders["Authorization"] != redacted {
+		t.Errorf("headers aren't redacted: %v, %v", e.Headers, e.ResponseHeaders)
+	}
+	if !strings.Contains(e.ResponseBody, `"id":1`) {
+		t.Errorf("response body fields other than redacted ones are lost: %s", e.ResponseBody)
+	}
+	if e.Status != http.StatusOK || e.LatencyMs == nil {
+		t.Errorf("status or latency isn't logged: %+v", e)
+	}
+}
+
+func TestLoggerTruncatesBodies(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data":"` + strings.Repeat("ж", 100) + `"}`))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/habits", nil)
+
+	entries := logRequests(t, RequestLogConfig{MaxBodyBytes: 16}, handler, r)
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+
+	body := entries[0].ResponseBody
+	if !strings.HasSuffix(body, "...<211 bytes>") || len(body) > 16+len("...<211 bytes>") {
+		t.Errorf("response body isn't truncated: %s", body)
+	}
+	if !utf8.ValidString(body) || !strings.HasPrefix(body, `{"data":"жжж...`) {
+		t.Errorf("truncated body has broken characters: %s", body)
+	}
+}
+
+func TestLoggerLimitsResponseBodyBuffering(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RequestLogConfig
+		want string
+	}{
+		{"without redaction", RequestLogConfig{MaxBodyBytes: 16}, `{"data":"aaaaaaa...<1011 bytes>`},
+		{"with redaction", RequestLogConfig{MaxBodyBytes: 16, RedactFields: []string{"token"}}, "<more than 16 bytes>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"data":"`))
+				for range 100 {
+					w.Write([]byte(strings.Repeat("a", 10)))
+				}
+				w.Write([]byte(`"}`))
+
+				if rl := w.(*responseLogger); rl.body.Len() > 16 {
+					t.Errorf("logger kept %d bytes of the response, want at most 16", rl.body.Len())
+				}
+			}
+
+			entries := logRequests(t, tt.cfg, handler, httptest.NewRequest(http.MethodGet, "/habits", nil))
+			if len(entries) != 1 {
+				t.Fatalf("got %d log entries, want 1", len(entries))
+			}
+			if entries[0].ResponseBody != tt.want {
+				t.Errorf("got response body %q, want %q", entries[0].ResponseBody, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerFlushesStreamedResponses(t *testing.T) {
+	var s Server
+	s.Res.Logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+
+	w := httptest.NewRecorder()
+	s.Logger()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write([]byte("PK\x03\x04"))
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("couldn't flush the response: %v", err)
+		}
+	})).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/export", nil))
+
+	if !w.Flushed {
+		t.Error("response isn't flushed through the logger")
+	}
+}
+
+func TestLoggerSkipsBinaryBodies(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write([]byte("PK\x03\x04archive"))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader("PK\x03\x04archive"))
+	r.Header.Set("Content-Type", "application/zip")
+
+	entries := logRequests(t, RequestLogConfig{}, handler, r)
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	if e := entries[0]; e.Body != "<11 bytes of application/zip>" || e.ResponseBody != "<11 bytes of application/zip>" {
+		t.Errorf("binary bodies are logged: %+v", e)
+	}
+}
+
+func TestLoggerSamplesSuccessfulRequests(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}
+
+	var reqs []*http.Request
+	for range 6 {
+		reqs = append(reqs, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	}
+	reqs = append(reqs, httptest.NewRequest(http.MethodGet, "/fail", nil), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	var ok, failed int
+	for _, e := range logRequests(t, RequestLogConfig{SampleSuccessEvery: 3}, handler, reqs...) {
+		if e.Status == http.StatusOK {
+			ok++
+		} else {
+			failed++
+		}
+	}
+	if ok != 2 || failed != 2 {
+		t.Errorf("got %d successful and %d failed requests logged, want 2 and 2", ok, failed)
+	}
+}
+
+func TestLoggerRedactsCredentialsWithoutConfig(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/habits", nil)
+	r.Header.Set("X-Telegram-InitData", "query_id=1&hash=initdata-secret")
+	r.Header.Set("Authorization", "Bearer request-secret")
+
+	entries := logRequests(t, RequestLogConfig{}, func(w http.ResponseWriter, r *http.Request) {}, r)
+	if len(entries) != 1 {
+		t.Fatalf("got %d log entries, want 1", len(entries))
+	}
+	if h := entries[0].Headers; h["X-Telegram-Initdata"] != redacted || h["Authorization"] != redacted {
+		t.Errorf("credentials aren't redacted: %v", h)
+	}
+}
+
+func TestLoggerSkipsUnparseableBodies(t *testing.T) {
+	cfg := RequestLogConfig{RedactFields: []string{"refreshToken", "hash"}}
+
+	tests := []struct {
+		name string
+		ct   string
+		body string
+	}{
+		{"malformed JSON", "application/json", `{"data":{"refreshToken":"refresh-secret"`},
+		{"untyped", "", `refreshToken=refresh-secret`},
+		{"login form", "", `{"data":{"login":{"hash":"hash-secret"}}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(tt.body))
+			if tt.ct != "" {
+				r.Header.Set("Content-Type", tt.ct)
+			}
+
+			entries := logRequests(t, cfg, func(w http.ResponseWriter, r *http.Request) {}, r)
+			if len(entries) != 1 {
+				t.Fatalf("got %d log entries, want 1", len(entries))
+			}
+			if want := "<unparseable body, " + strconv.Itoa(len(tt.body)) + " bytes>"; entries[0].Body != want {
+				t.Errorf("got body %q, want %q", entries[0].Body, want)
+			}
+		})
+	}
+}
+
+func TestLoggerLimitsRequestBodyReading(t *testing.T) {
+	payload := `{"data":"` + strings.Repeat("a", 100) + `"}`
+
+	var got string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got = string(body)
+	}
+
+	var read int
+	body := &countingReader{Reader: strings.NewReader(payload), read: &read}
+	r := httptest.NewRequest(http.MethodPost, "/habits", nil)
+	r.Body = io.NopCloser(body)
+	r.Header.Set("Content-Type", "application/json")
+
+	var buf bytes.Buffer
+	s := Server{RequestLog: RequestLogConfig{MaxBodyBytes: 16}, Res: resources.Resources{Logger: slog.New(slog.NewJSONHandler(&buf, nil))}}
+	s.Logger()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if read > 17 {
+			t.Errorf("logger read %d bytes of the body, want at most 17", read)
+		}
+		handler(w, r)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+
+	if got != payload {
+		t.Errorf("handler got body %q, want %q", got, payload)
+	}
+	if !strings.Contains(buf.String(), `"body":"<more than 16 bytes>"`) {
+		t.Errorf("oversized body is logged: %s", buf.String())
+	}
+}
+
+type countingReader struct {
+	io.Reader
+	read *int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	*c.read += n
+	return n, err
+}
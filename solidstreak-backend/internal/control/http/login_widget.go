@@ -0,0 +1,177 @@
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	tcPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+// LoginExpired is the code of authentication errors telling the web app to log in with Telegram again
+const LoginExpired = "login_expired"
+
+var errLoginExpired = errors.New("telegram login has expired")
+
+// TelegramLogin is the user's data passed by Telegram Login Widget to the web app
+type TelegramLogin struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+	PhotoURL  string `json:"photo_url"`
+	AuthDate  int64  `json:"auth_date"`
+	Hash      string `json:"hash"`
+}
+
+type TelegramLoginData struct {
+	Login    *TelegramLogin `json:"login"`
+	Timezone *string        `json:"timezone"`
+}
+
+type PostTelegramLoginRequest struct {
+	Data *TelegramLoginData `json:"data"`
+}
+
+// postTelegramLogin registers the user logged in with Telegram Login Widget outside of Telegram
+// and starts their session, as initData exchange does for the Mini App
+func (s Server) postTelegramLogin(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	var req PostTelegramLoginRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err = decoder.Decode(&req); err != nil {
+		err = apperrors.ErrBadRequest("invalid request payload")
+		return
+	}
+
+	if req.Data == nil || req.Data.Login == nil {
+		err = apperrors.ErrBadRequest("telegram login data is required")
+		return
+	}
+	login := req.Data.Login
+
+	now := time.Now()
+
+	if err = validateTelegramLogin(login, s.Res.TgBotAPIToken, s.LoginWidgetMaxAge, now); err != nil {
+		if errors.Is(err, errLoginExpired) {
+			err = apperrors.ErrUnauthorized(err.Error()).WithCode(LoginExpired)
+		} else {
+			err = apperrors.ErrUnauthorized(err.Error())
+		}
+		return
+	}
+
+	// Login Widget doesn't tell the user's language, so the one known from the Mini App is kept
+	user := usrPkg.NewUser(login.ID, login.Username, login.FirstName, login.LastName, "", false)
+
+	var registered *usrPkg.User
+	if registered, err = s.Res.UsrRepo.GetByTgID(r.Context(), login.ID); err == nil {
+		user.TgLangCode = registered.TgLangCode
+	} else if isNotFound(err) {
+		err = nil
+	} else {
+		return
+	}
+
+	// Time zone reported by the browser is used only until the user's time zone is known
+	if req.Data.Timezone != nil && usrPkg.ValidateTimezone(*req.Data.Timezone) == nil {
+		user.Timezone = *req.Data.Timezone
+	}
+
+	// Login Widget asks the user to allow the bot to message them, so the private chat is used
+	err = s.Res.UoW.Do(r.Context(), func(ctx context.Context) error {
+		if err := s.Res.UsrRepo.Upsert(ctx, user); err != nil {
+			return err
+		}
+		return s.Res.TCRepo.Upsert(ctx, tcPkg.NewChat(user.TgID, user.ID))
+	})
+	if err != nil {
+		return
+	}
+
+	var sess *Session
+	if sess, err = s.startSession(r.Context(), user, now); err != nil {
+		return
+	}
+
+	response := PostSessionResponse{Data: sess}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// validateTelegramLogin checks the hash of Login Widget data. Data older than maxAge is rejected,
+// unless maxAge is zero
+func validateTelegramLogin(l *TelegramLogin, token string, maxAge time.Duration, now time.Time) error {
+	if l.Hash == "" {
+		return errors.New("missing hash in telegram login data")
+	}
+	if !hmac.Equal([]byte(telegramLoginHash(l, token)), []byte(l.Hash)) {
+		return errors.New("request authentication failed")
+	}
+
+	authDate := time.Unix(l.AuthDate, 0)
+	switch {
+	case l.AuthDate == 0:
+		return errors.New("missing auth_date in telegram login data")
+	case authDate.After(now.Add(initDataClockSkew)):
+		return errors.New("auth_date of telegram login data is in the future")
+	case maxAge > 0 && now.Sub(authDate) > maxAge:
+		return errLoginExpired
+	}
+
+	return nil
+}
+
+// telegramLoginHash signs the data-check-string of Login Widget data, i.e. its sorted non-empty
+// fields, with SHA-256 of the bot token. Unlike initData, it's not derived with "WebAppData" key
+func telegramLoginHash(l *TelegramLogin, token string) string {
+	fields := map[string]string{
+		"id":         strconv.FormatInt(l.ID, 10),
+		"first_name": l.FirstName,
+		"last_name":  l.LastName,
+		"username":   l.Username,
+		"photo_url":  l.PhotoURL,
+		"auth_date":  strconv.FormatInt(l.AuthDate, 10),
+	}
+
+	pairs := make([]string, 0, len(fields))
+	for k, v := range fields {
+		if v != "" {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	sort.Strings(pairs)
+
+	secretKey := sha256.Sum256([]byte(token))
+	mac := hmac.New(sha256.New, secretKey[:])
+	mac.Write([]byte(strings.Join(pairs, "\n")))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
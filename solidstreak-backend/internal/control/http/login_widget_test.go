@@ -0,0 +1,98 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+// telegramLogin returns Login Widget data of the user signed with the test bot token
+func telegramLogin(tgID int64, authDate time.Time) *TelegramLogin {
+	l := &TelegramLogin{ID: tgID, FirstName: "Alex", Username: "alex", AuthDate: authDate.Unix()}
+	l.Hash = telegramLoginHash(l, testBotToken)
+	return l
+}
+
+func TestTelegramLoginHash(t *testing.T) {
+	// Computed independently as HMAC-SHA256 of the data-check-string keyed with SHA-256 of the token
+	l := &TelegramLogin{ID: 100, FirstName: "Alex", Username: "alex", AuthDate: 1700000000}
+	if got, want := telegramLoginHash(l, testBotToken), "04075ad51bb65d93ff74db96832fc9d8540f455b8b462912f3a35add1ba7690d"; got != want {
+		t.Errorf("got hash %s, want %s", got, want)
+	}
+}
+
+func TestValidateTelegramLogin(t *testing.T) {
+	now := time.Now()
+
+	tampered := telegramLogin(100, now)
+	tampered.ID = 200
+
+	initDataSigned := telegramLogin(100, now)
+	initDataSigned.Hash = sign("auth_date="+strconv.FormatInt(now.Unix(), 10)+"\nfirst_name=Alex\nid=100\nusername=alex", testBotToken)
+
+	tests := []struct {
+		name        string
+		login       *TelegramLogin
+		wantErr     bool
+		wantExpired bool
+	}{
+		{"fresh", telegramLogin(100, now), false, false},
+		{"expired", telegramLogin(100, now.Add(-time.Hour-time.Second)), true, true},
+		{"in the future", telegramLogin(100, now.Add(time.Hour)), true, false},
+		{"tampered", tampered, true, false},
+		{"signed as initData", initDataSigned, true, false},
+		{"without hash", &TelegramLogin{ID: 100, AuthDate: now.Unix()}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTelegramLogin(tt.login, testBotToken, time.Hour, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if errors.Is(err, errLoginExpired) != tt.wantExpired {
+				t.Errorf("got error %v, want expiration %t", err, tt.wantExpired)
+			}
+		})
+	}
+}
+
+func TestTelegramLogin(t *testing.T) {
+	env := newTestEnv()
+
+	post := func(l *TelegramLogin) *Session {
+		t.Helper()
+		return data[*Session](t, env.do(t, http.MethodPost, "/auth/telegram-login", 0, PostTelegramLoginRequest{Data: &TelegramLoginData{Login: l, Timezone: ptr("Europe/Berlin")}}))
+	}
+
+	// New users are registered with their private chat with the bot
+	sess := post(telegramLogin(300, time.Now()))
+	if sess.User == nil || sess.User.TgID != 300 || sess.User.Timezone != "Europe/Berlin" {
+		t.Fatalf("unexpected user %+v", sess.User)
+	}
+	if chat := env.db.TgChats[300]; chat == nil || chat.UserID != sess.User.ID {
+		t.Errorf("private chat isn't saved: %+v", chat)
+	}
+	data[*usrPkg.User](t, env.doWithBearer(t, http.MethodGet, "/users/"+strconv.FormatInt(sess.User.ID, 10), sess.Token, nil))
+
+	// Registered users get the same identity as in the Mini App
+	u := env.addUser(t, 100)
+	if sess = post(telegramLogin(100, time.Now())); sess.User.ID != u.ID || sess.User.TgLangCode != u.TgLangCode {
+		t.Errorf("got user %+v, want %+v", sess.User, u)
+	}
+
+	expired := telegramLogin(100, time.Now().Add(-2*time.Hour))
+	w := env.do(t, http.MethodPost, "/auth/telegram-login", 0, PostTelegramLoginRequest{Data: &TelegramLoginData{Login: expired}})
+	if apperror := wantError(t, w, http.StatusUnauthorized); apperror.Code != LoginExpired {
+		t.Errorf("got error code %q for expired login, want %q", apperror.Code, LoginExpired)
+	}
+
+	forged := telegramLogin(100, time.Now())
+	forged.Hash = telegramLoginHash(forged, "654321:another-token")
+	wantError(t, env.do(t, http.MethodPost, "/auth/telegram-login", 0, PostTelegramLoginRequest{Data: &TelegramLoginData{Login: forged}}), http.StatusUnauthorized)
+	wantError(t, env.do(t, http.MethodPost, "/auth/telegram-login", 0, PostTelegramLoginRequest{}), http.StatusBadRequest)
+}
@@ -9,18 +9,40 @@ import (
 	"time"
 
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
 	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
 	"github.com/go-chi/chi/v5"
 )
 
 type Server struct {
-	Env          string
-	CertFilePath string
-	KeyFilePath  string
-	Addr         string
-	Res          resources.Resources
-	s            *http.Server
+	Env                        string
+	CertFilePath               string
+	KeyFilePath                string
+	Addr                       string
+	RequestTimeout             time.Duration
+	ExportTimeout              time.Duration // Deadline of streaming data export archives, which take longer than other requests
+	InitDataMaxAge             time.Duration // How long Telegram initData is accepted after the Mini App is opened, unlimited if zero
+	SessionSecret              string        // Key signing session tokens
+	SessionTTL                 time.Duration
+	SessionRefreshTTL          time.Duration
+	SessionMaxLifetime         time.Duration // How long sessions are refreshed after the login, unlimited if zero
+	LoginWidgetMaxAge          time.Duration // How long Telegram Login Widget data is accepted after the user logs in, unlimited if zero
+	HabitInviteTTL             time.Duration
+	HabitTrashRetention        time.Duration
+	HabitCheckBackfillDays     int // How many days before today habits could be checked for
+	AccountDeletionGracePeriod time.Duration
+	TgWebhook                  TgWebhook // Set in the bot's webhook mode
+	RequestLog                 RequestLogConfig
+	Res                        resources.Resources
+	s                          *http.Server
+	initDataAuthDates          *authDates // Shared by the router's middlewares
+}
+
+// TgWebhook is the handler of updates pushed by Telegram
+type TgWebhook interface {
+	http.Handler
+	Path() string
 }
 
 func (s Server) Run(mainCtx context.Context, doneCh chan struct{}) {
@@ -28,43 +50,9 @@ func (s Server) Run(mainCtx context.Context, doneCh chan struct{}) {
 
 	s.Res.Logger.Info("web server initialization...")
 
-	router := chi.NewRouter()
-
-	api := chi.NewRouter()
-
-	api.Use(s.Recovery())
-	api.Use(s.Logger())
-	api.Use(s.ValidateTelegramInitData())
-
-	api.Post("/user-info/upsert", s.postUserInfo)
-	api.Get("/users/{userId}", s.getUser)
-
-	api.Post("/users/{userID}/habits", s.postHabit)
-	api.Put("/users/{userID}/habits/{habitID}", s.putHabit)
-	api.Get("/users/{userID}/habits/{habitID}", s.getHabit)
-	api.Delete("/users/{userID}/habits/{habitID}", s.deleteHabit)
-	api.Get("/users/{userID}/habits", s.getHabits)
-	api.Post("/users/{userID}/habits/{habitID}/checks", s.postUserHabitCheck)
-	api.Get("/users/{userID}/habits/{habitID}/checks", s.getUserHabitCompletedChecks)
-
-	router.Mount("/api/v1", api)
-
-	router.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) {
-		staticPath := filepath.Join("static", r.URL.Path)
-		if info, err := os.Stat(staticPath); err == nil && !info.IsDir() {
-			http.ServeFile(w, r, staticPath)
-			return
-		}
-		http.ServeFile(w, r, "./static/index.html")
-	})
-
-	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "./static/index.html")
-	})
-
 	s.s = &http.Server{
 		Addr:    s.Addr,
-		Handler: router,
+		Handler: s.router(),
 	}
 
 	s.Res.Logger.Info("web server started on " + s.Addr)
@@ -100,6 +88,121 @@ func (s Server) Run(mainCtx context.Context, doneCh chan struct{}) {
 	s.Res.Logger.Info("web server stopped")
 }
 
+// router routes the API, the bot's webhook and the Mini App static files
+func (s Server) router() http.Handler {
+	router := chi.NewRouter()
+
+	api := chi.NewRouter()
+
+	s.initDataAuthDates = newAuthDates()
+
+	api.Use(s.Recovery())
+	api.Use(s.Logger())
+
+	api.Group(func(api chi.Router) {
+		api.Use(s.Timeout(s.RequestTimeout))
+
+		// Refresh token and Telegram Login Widget data are checked by the handlers
+		api.Post("/auth/refresh", s.postSessionRefresh)
+		api.Post("/auth/telegram-login", s.postTelegramLogin)
+
+		// Mini App registers the user and starts a session with initData
+		api.Group(func(api chi.Router) {
+			api.Use(s.ValidateTelegramInitData())
+
+			api.Post("/user-info/upsert", s.postUserInfo)
+			api.Post("/auth/session", s.postSession)
+		})
+
+		api.Group(func(api chi.Router) {
+			api.Use(s.Authenticate())
+
+			s.routeUsersAPI(api)
+		})
+	})
+
+	// Archive is streamed for as long as it's downloaded, so the export has its own deadline. It
+	// dumps the whole account, so viewing scope isn't enough
+	api.Group(func(api chi.Router) {
+		api.Use(s.Timeout(s.ExportTimeout))
+		api.Use(s.Authenticate())
+
+		api.With(s.RequireScope(atPkg.Full)).Get("/users/{userID}/export", s.getUserExport)
+	})
+
+	// Unknown API routes are reported as JSON errors, not as the Mini App page
+	api.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, apperrors.ErrNotFound("couldn't find API route"))
+	})
+
+	router.Mount("/api/v1", api)
+
+	// Authenticated by the webhook secret token, not logged for the token is passed in headers
+	if s.TgWebhook != nil {
+		router.With(s.Recovery()).Post(s.TgWebhook.Path(), s.TgWebhook.ServeHTTP)
+	}
+
+	router.HandleFunc("/*", func(w http.ResponseWriter, r *http.Request) {
+		staticPath := filepath.Join("static", r.URL.Path)
+		if info, err := os.Stat(staticPath); err == nil && !info.IsDir() {
+			http.ServeFile(w, r, staticPath)
+			return
+		}
+		http.ServeFile(w, r, "./static/index.html")
+	})
+
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./static/index.html")
+	})
+
+	return router
+}
+
+// routeUsersAPI routes the API of registered users. Requests authenticated by access tokens are
+// limited by their scopes
+func (s Server) routeUsersAPI(api chi.Router) {
+	read := api.With(s.RequireScope(atPkg.Read))
+	checks := api.With(s.RequireScope(atPkg.Checks))
+	full := api.With(s.RequireScope(atPkg.Full))
+
+	read.Get("/users/{userId}", s.getUser)
+	full.Put("/users/{userId}/timezone", s.putUserTimezone)
+
+	full.Post("/users/{userID}/habits", s.postHabit)
+	full.Put("/users/{userID}/habits/{habitID}", s.putHabit)
+	read.Get("/users/{userID}/habits/{habitID}", s.getHabit)
+	full.Delete("/users/{userID}/habits/{habitID}", s.deleteHabit)
+	read.Get("/users/{userID}/habits", s.getHabits)
+	read.Get("/users/{userID}/habits/trash", s.getHabitsTrash)
+	full.Post("/users/{userID}/habits/{habitID}/restore", s.postHabitRestore)
+	checks.Post("/users/{userID}/habits/{habitID}/checks", s.postUserHabitCheck)
+	read.Get("/users/{userID}/habits/{habitID}/checks", s.getUserHabitCompletedChecks)
+	read.Get("/users/{userID}/habits/{habitID}/stats", s.getHabitStats)
+	full.Post("/users/{userID}/habits/{habitID}/invites", s.postHabitInvite)
+	full.Post("/users/{userID}/habits/join", s.postHabitJoin)
+	full.Post("/users/{userID}/habits/{habitID}/leave", s.postHabitLeave)
+	read.Get("/users/{userID}/habits/{habitID}/participants", s.getHabitParticipants)
+
+	read.Get("/users/{userID}/friends", s.getFriends)
+	full.Post("/users/{userID}/friends/{friendID}/request", s.postFriendRequest)
+	full.Post("/users/{userID}/friends/{friendID}/accept", s.postFriendAccept)
+	full.Post("/users/{userID}/friends/{friendID}/block", s.postFriendBlock)
+	full.Delete("/users/{userID}/friends/{friendID}", s.deleteFriend)
+	read.Get("/users/{userID}/feed", s.getFeed)
+
+	full.Post("/users/{userID}/import", s.postUserImport)
+
+	// Account and its access tokens are managed in the Mini App only
+	app := api.With(s.DenyAccessTokens())
+
+	app.Post("/users/{userID}/deletion", s.postAccountDeletion)
+	app.Delete("/users/{userID}/deletion", s.deleteAccountDeletion)
+
+	app.Get("/users/{userID}/tokens", s.getAccessTokens)
+	app.Post("/users/{userID}/tokens", s.postAccessToken)
+	app.Delete("/users/{userID}/tokens/{tokenID}", s.deleteAccessToken)
+}
+
 func getInt64FromURLParams(r *http.Request, key string, required bool) (int64, error) {
 	strValue := chi.URLParam(r, key)
 	if strValue == "" {
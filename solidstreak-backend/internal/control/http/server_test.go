@@ -0,0 +1,274 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	atRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken/repo"
+	fPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship"
+	fRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship/repo"
+	hRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit/repo"
+	sessRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session/repo"
+	tcRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat/repo"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	usrRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user/repo"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+const (
+	testBotToken      = "123456:test-bot-token"
+	testSessionSecret = "test-session-secret"
+)
+
+type testEnv struct {
+	db      *memdb.DB
+	res     resources.Resources
+	handler http.Handler
+}
+
+func newTestEnv() *testEnv {
+	db := memdb.New()
+	env := &testEnv{
+		db: db,
+		res: resources.Resources{
+			TgBotAPIToken: testBotToken,
+			TgBotUsername: "solidstreak_bot",
+			Logger:        slog.New(slog.NewTextHandler(io.Discard, nil)),
+			UoW:           uow.InitMemory(),
+			UsrRepo:       usrRepo.InitMemory(db),
+			TCRepo:        tcRepo.InitMemory(db),
+			HabitRepo:     hRepo.InitMemory(db),
+			FriendRepo:    fRepo.InitMemory(db),
+			TokenRepo:     atRepo.InitMemory(db),
+			SessionRepo:   sessRepo.InitMemory(db),
+		},
+	}
+	env.initHandler()
+	return env
+}
+
+// initHandler builds the handler of the environment resources, again after replacing them
+func (env *testEnv) initHandler() {
+	env.handler = Server{
+		RequestTimeout:             5 * time.Second,
+		ExportTimeout:              time.Minute,
+		InitDataMaxAge:             time.Hour,
+		SessionSecret:              testSessionSecret,
+		SessionTTL:                 time.Hour,
+		SessionRefreshTTL:          24 * time.Hour,
+		SessionMaxLifetime:         7 * 24 * time.Hour,
+		LoginWidgetMaxAge:          time.Hour,
+		HabitInviteTTL:             time.Hour,
+		HabitTrashRetention:        30 * 24 * time.Hour,
+		HabitCheckBackfillDays:     7,
+		AccountDeletionGracePeriod: 30 * 24 * time.Hour,
+		Res:                        env.res,
+	}.router()
+}
+
+// lastQueryID makes initData of each call unique
+var lastQueryID atomic.Int64
+
+// initData returns Telegram Mini App init data of the user signed with the test bot token
+func initData(tgID int64, extra url.Values) string {
+	values := url.Values{}
+	for k, v := range extra {
+		values[k] = v
+	}
+	user, _ := json.Marshal(InitDataUser{TgID: tgID, TgUsername: "user" + strconv.FormatInt(tgID, 10), TgFirstName: "User"})
+	values.Set("user", string(user))
+	if values.Get("auth_date") == "" {
+		values.Set("auth_date", strconv.FormatInt(time.Now().Unix(), 10))
+	}
+	// Each launch of the Mini App gets its own query_id, so initData isn't repeated
+	if values.Get("query_id") == "" {
+		values.Set("query_id", "AAH"+strconv.FormatInt(lastQueryID.Add(1), 10))
+	}
+
+	return signInitData(values, testBotToken)
+}
+
+// signInitData adds hash of values as Telegram does and encodes them
+func signInitData(values url.Values, token string) string {
+	pairs := make([]string, 0, len(values))
+	for k, v := range values {
+		pairs = append(pairs, k+"="+v[0])
+	}
+	sort.Strings(pairs)
+	values.Set("hash", sign(strings.Join(pairs, "\n"), token))
+
+	return values.Encode()
+}
+
+// addUser registers the user as the Mini App does on start
+func (env *testEnv) addUser(t *testing.T, tgID int64) *usrPkg.User {
+	t.Helper()
+
+	u := usrPkg.NewUser(tgID, "user"+strconv.FormatInt(tgID, 10), "User", "", "en", false)
+	if err := env.res.UsrRepo.Upsert(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func (env *testEnv) befriend(t *testing.T, u, other *usrPkg.User) {
+	t.Helper()
+
+	f := fPkg.NewRequest(u.ID, other.ID)
+	f.Accept()
+	if err := env.res.FriendRepo.Save(context.Background(), f); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// do sends API request on behalf of the user with specified Telegram ID, unauthenticated if it's 0.
+// Body is sent as is if it's []byte and as JSON otherwise
+func (env *testEnv) do(t *testing.T, method, path string, tgID int64, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	header := http.Header{}
+	if tgID != 0 {
+		header.Set("X-Telegram-InitData", initData(tgID, nil))
+	}
+	return env.doWithHeader(t, method, path, header, body)
+}
+
+// doWithBearer sends API request authenticated by the session or access token
+func (env *testEnv) doWithBearer(t *testing.T, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	return env.doWithHeader(t, method, path, http.Header{"Authorization": {"Bearer " + token}}, body)
+}
+
+func (env *testEnv) doWithHeader(t *testing.T, method, path string, header http.Header, body any) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reqBody io.Reader
+	switch b := body.(type) {
+	case nil:
+	case []byte:
+		reqBody = bytes.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	r := httptest.NewRequest(method, "/api/v1"+path, reqBody)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, r)
+
+	return w
+}
+
+// data decodes "data" of successful response
+func data[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want 200: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Data T `json:"data"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("couldn't decode response: %v", err)
+	}
+	return resp.Data
+}
+
+// wantError checks the response is the error with specified HTTP code
+func wantError(t *testing.T, w *httptest.ResponseRecorder, code int) apperrors.Error {
+	t.Helper()
+
+	if w.Code != code {
+		t.Fatalf("got status %d, want %d: %s", w.Code, code, w.Body)
+	}
+	var resp ErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || len(resp.Errors) != 1 {
+		t.Fatalf("unexpected error response %q", w.Body)
+	}
+	return resp.Errors[0]
+}
+
+func TestAuthentication(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	path := "/api/v1/users/" + strconv.FormatInt(u.ID, 10)
+
+	tampered := strings.Replace(initData(100, nil), "user100", "user200", 1)
+
+	tests := []struct {
+		name     string
+		initData string
+		wantCode int
+	}{
+		{"missing init data", "", http.StatusUnauthorized},
+		{"tampered init data", tampered, http.StatusUnauthorized},
+		{"init data without hash", "user=%7B%22id%22%3A100%7D", http.StatusUnauthorized},
+		{"valid init data", initData(100, nil), http.StatusOK},
+		{"unknown user", initData(200, nil), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.initData != "" {
+				r.Header.Set("X-Telegram-InitData", tt.initData)
+			}
+			w := httptest.NewRecorder()
+			env.handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Errorf("got status %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+		})
+	}
+}
+
+func TestInvalidURLParams(t *testing.T) {
+	env := newTestEnv()
+	env.addUser(t, 100)
+
+	for _, path := range []string{
+		"/users/abc",
+		"/users/abc/habits",
+		"/users/1/habits/abc",
+		"/users/1/habits?status=unknown",
+		"/users/1/habits?with_checks=maybe",
+		"/users/1/habits/1/checks?from=yesterday",
+		"/users/1/friends?status=unknown",
+	} {
+		t.Run(path, func(t *testing.T) {
+			wantError(t, env.do(t, http.MethodGet, path, 100, nil), http.StatusBadRequest)
+		})
+	}
+}
+
+func TestUnknownAPIRoute(t *testing.T) {
+	env := newTestEnv()
+	env.addUser(t, 100)
+
+	wantError(t, env.do(t, http.MethodGet, "/unknown", 100, nil), http.StatusNotFound)
+	wantError(t, env.do(t, http.MethodGet, "/users/1/unknown", 100, nil), http.StatusNotFound)
+}
@@ -0,0 +1,232 @@
+package http
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	sessPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	sessUsecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/session"
+)
+
+// SessionExpired is the code of authentication errors telling the client to refresh its session
+const SessionExpired = "session_expired"
+
+// Type of session tokens, so other tokens signed with the secret couldn't be used as ones
+const sessionTokenType = "session"
+
+var errSessionExpired = errors.New("session token has expired")
+
+type Session struct {
+	Token            string       `json:"token"` // Sent in "Authorization: Bearer" header instead of initData
+	ExpiresAt        time.Time    `json:"expiresAt"`
+	RefreshToken     string       `json:"refreshToken"` // Exchanged once for a new session when it expires
+	RefreshExpiresAt time.Time    `json:"refreshExpiresAt"`
+	User             *usrPkg.User `json:"user"`
+}
+
+type PostSessionResponse struct {
+	Data *Session `json:"data"`
+}
+
+type SessionRefresh struct {
+	RefreshToken *string `json:"refreshToken"`
+}
+
+type PostSessionRefreshRequest struct {
+	Data *SessionRefresh `json:"data"`
+}
+
+// tokenClaims is the signed payload of session tokens
+type tokenClaims struct {
+	Type      string `json:"typ"`
+	UserID    int64  `json:"uid"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// postSession exchanges initData validated by the middleware for a session of the user. Each
+// initData is exchanged once, so a leaked one couldn't start another session, and it's kept by
+// the storage for as long as it could be accepted
+func (s Server) postSession(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	userTgID, ok := r.Context().Value(ctxKeyUserTgID{}).(int64)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
+
+	var user *usrPkg.User
+	if user, err = s.Res.UsrRepo.GetByTgID(r.Context(), userTgID); err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	var usedUntil *time.Time
+	if authDate, ok := r.Context().Value(ctxKeyInitDataAuthDate{}).(time.Time); ok && s.InitDataMaxAge > 0 {
+		until := authDate.Add(s.InitDataMaxAge)
+		usedUntil = &until
+	}
+
+	var (
+		refreshToken *sessPkg.RefreshToken
+		secret       string
+	)
+	initData := r.Header.Get("X-Telegram-InitData")
+	refreshToken, secret, err = sessUsecases.StartWithInitData(r.Context(), s.Res, user, initData, usedUntil, now, s.SessionRefreshTTL, s.SessionMaxLifetime)
+	if err != nil {
+		if errors.Is(err, sessUsecases.ErrInitDataUsed) {
+			err = apperrors.ErrUnauthorized(err.Error()).WithCode(InitDataExpired)
+		}
+		return
+	}
+
+	response := PostSessionResponse{Data: s.newSession(user, refreshToken, secret, now)}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// postSessionRefresh issues a new session for the refresh token, which is passed in the body
+// since the expired session couldn't authenticate the request
+func (s Server) postSessionRefresh(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	var req PostSessionRefreshRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err = decoder.Decode(&req); err != nil {
+		err = apperrors.ErrBadRequest("invalid request payload")
+		return
+	}
+
+	if req.Data == nil || req.Data.RefreshToken == nil {
+		err = apperrors.ErrBadRequest("refresh token is required")
+		return
+	}
+
+	now := time.Now()
+
+	var (
+		user         *usrPkg.User
+		refreshToken *sessPkg.RefreshToken
+		secret       string
+	)
+	user, refreshToken, secret, err = sessUsecases.Refresh(r.Context(), s.Res, *req.Data.RefreshToken, now, s.SessionRefreshTTL, s.SessionMaxLifetime)
+	if err != nil {
+		if errors.Is(err, sessUsecases.ErrExpired) {
+			err = apperrors.ErrUnauthorized(err.Error()).WithCode(SessionExpired)
+		}
+		return
+	}
+
+	response := PostSessionResponse{Data: s.newSession(user, refreshToken, secret, now)}
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// startSession starts a session of the user's new login
+func (s Server) startSession(ctx context.Context, u *usrPkg.User, now time.Time) (*Session, error) {
+	refreshToken, secret, err := sessUsecases.Start(ctx, s.Res, u, now, s.SessionRefreshTTL, s.SessionMaxLifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newSession(u, refreshToken, secret, now), nil
+}
+
+// newSession returns the session along with the refresh token, which it never outlives
+func (s Server) newSession(u *usrPkg.User, refreshToken *sessPkg.RefreshToken, secret string, now time.Time) *Session {
+	sess := &Session{
+		ExpiresAt:        now.Add(s.SessionTTL).Truncate(time.Second),
+		RefreshToken:     secret,
+		RefreshExpiresAt: refreshToken.ExpiresAt,
+		User:             u,
+	}
+	if sess.ExpiresAt.After(refreshToken.ExpiresAt) {
+		sess.ExpiresAt = refreshToken.ExpiresAt
+	}
+	sess.Token = signToken(tokenClaims{Type: sessionTokenType, UserID: u.ID, ExpiresAt: sess.ExpiresAt.Unix()}, s.SessionSecret)
+	return sess
+}
+
+// signToken encodes claims and their HMAC-SHA256 signature as "<payload>.<signature>" in base64url
+func signToken(c tokenClaims, secret string) string {
+	payload, _ := json.Marshal(c)
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(tokenSignature(encoded, secret))
+}
+
+// parseToken verifies the token of specified type and returns ID of the user it's issued for
+func parseToken(token, typ, secret string, now time.Time) (int64, error) {
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, errors.New("malformed token")
+	}
+
+	decodedSig, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(decodedSig, tokenSignature(encoded, secret)) {
+		return 0, errors.New("invalid token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, errors.New("malformed token")
+	}
+	var c tokenClaims
+	if err = json.Unmarshal(payload, &c); err != nil {
+		return 0, errors.New("malformed token")
+	}
+
+	if c.Type != typ {
+		return 0, errors.New("unexpected token type")
+	}
+	if !now.Before(time.Unix(c.ExpiresAt, 0)) {
+		return 0, errSessionExpired
+	}
+
+	return c.UserID, nil
+}
+
+func tokenSignature(payload, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
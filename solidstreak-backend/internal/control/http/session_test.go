@@ -0,0 +1,198 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+func TestParseToken(t *testing.T) {
+	now := time.Now()
+	claims := tokenClaims{Type: sessionTokenType, UserID: 42, ExpiresAt: now.Add(time.Hour).Unix()}
+	token := signToken(claims, testSessionSecret)
+
+	payload, sig, _ := strings.Cut(token, ".")
+	forged := signToken(tokenClaims{Type: sessionTokenType, UserID: 43, ExpiresAt: claims.ExpiresAt}, "another-secret")
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+
+	tests := []struct {
+		name        string
+		token       string
+		typ         string
+		now         time.Time
+		wantErr     bool
+		wantExpired bool
+	}{
+		{"valid", token, sessionTokenType, now, false, false},
+		{"expired", token, sessionTokenType, now.Add(time.Hour), true, true},
+		{"another type", token, "refresh", now, true, false},
+		{"signed with another secret", forged, sessionTokenType, now, true, false},
+		{"payload replaced", forgedPayload + "." + sig, sessionTokenType, now, true, false},
+		{"without signature", payload, sessionTokenType, now, true, false},
+		{"malformed", "not a token", sessionTokenType, now, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, err := parseToken(tt.token, tt.typ, testSessionSecret, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+			if errors.Is(err, errSessionExpired) != tt.wantExpired {
+				t.Errorf("got error %v, want expiration %t", err, tt.wantExpired)
+			}
+			if err == nil && userID != 42 {
+				t.Errorf("got user ID %d, want 42", userID)
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	path := "/users/" + strconv.FormatInt(u.ID, 10)
+
+	getWithToken := func(token string) *httptest.ResponseRecorder {
+		return env.doWithBearer(t, http.MethodGet, path, token, nil)
+	}
+
+	// Unregistered users couldn't start a session
+	wantError(t, env.do(t, http.MethodPost, "/auth/session", 200, nil), http.StatusNotFound)
+	wantError(t, env.do(t, http.MethodPost, "/auth/session", 0, nil), http.StatusUnauthorized)
+
+	sess := data[Session](t, env.do(t, http.MethodPost, "/auth/session", 100, nil))
+	if sess.User == nil || sess.User.ID != u.ID {
+		t.Fatalf("got session of user %+v, want %d", sess.User, u.ID)
+	}
+	if !sess.ExpiresAt.Before(sess.RefreshExpiresAt) {
+		t.Errorf("session expires at %v, after refresh token at %v", sess.ExpiresAt, sess.RefreshExpiresAt)
+	}
+
+	if got := data[*usrPkg.User](t, getWithToken(sess.Token)); got.ID != u.ID {
+		t.Errorf("got user %+v, want %d", got, u.ID)
+	}
+
+	// Refresh token couldn't authenticate requests and session token couldn't be refreshed
+	wantError(t, getWithToken(sess.RefreshToken), http.StatusUnauthorized)
+	wantError(t, env.do(t, http.MethodPost, "/auth/refresh", 0, PostSessionRefreshRequest{Data: &SessionRefresh{RefreshToken: &sess.Token}}), http.StatusUnauthorized)
+	wantError(t, env.do(t, http.MethodPost, "/auth/refresh", 0, PostSessionRefreshRequest{Data: &SessionRefresh{}}), http.StatusBadRequest)
+
+	expired := signToken(tokenClaims{Type: sessionTokenType, UserID: u.ID, ExpiresAt: time.Now().Add(-time.Second).Unix()}, testSessionSecret)
+	if apperror := wantError(t, getWithToken(expired), http.StatusUnauthorized); apperror.Code != SessionExpired {
+		t.Errorf("got error code %q for expired session, want %q", apperror.Code, SessionExpired)
+	}
+
+	refreshed := data[Session](t, env.do(t, http.MethodPost, "/auth/refresh", 0, PostSessionRefreshRequest{Data: &SessionRefresh{RefreshToken: &sess.RefreshToken}}))
+	data[*usrPkg.User](t, getWithToken(refreshed.Token))
+	if refreshed.RefreshToken == sess.RefreshToken {
+		t.Errorf("refresh token isn't rotated")
+	}
+
+	// InitData is still accepted instead of the session
+	data[*usrPkg.User](t, env.do(t, http.MethodGet, path, 100, nil))
+}
+
+// refresh exchanges the refresh token for a new session
+func (env *testEnv) refresh(t *testing.T, refreshToken string) *httptest.ResponseRecorder {
+	t.Helper()
+	return env.do(t, http.MethodPost, "/auth/refresh", 0, PostSessionRefreshRequest{Data: &SessionRefresh{RefreshToken: &refreshToken}})
+}
+
+func TestSessionRefreshTokenReuse(t *testing.T) {
+	env := newTestEnv()
+	env.addUser(t, 100)
+
+	sess := data[Session](t, env.do(t, http.MethodPost, "/auth/session", 100, nil))
+	rotated := data[Session](t, env.refresh(t, sess.RefreshToken))
+
+	// Reusing the rotated token, e.g. the leaked one, revokes the tokens rotated from it
+	wantError(t, env.refresh(t, sess.RefreshToken), http.StatusUnauthorized)
+	wantError(t, env.refresh(t, rotated.RefreshToken), http.StatusUnauthorized)
+	if len(env.db.RefreshTokens) != 0 {
+		t.Errorf("got %d refresh tokens left, want none", len(env.db.RefreshTokens))
+	}
+
+	// Other logins of the user aren't affected
+	first := data[Session](t, env.do(t, http.MethodPost, "/auth/session", 100, nil))
+	second := data[Session](t, env.do(t, http.MethodPost, "/auth/session", 100, nil))
+	data[Session](t, env.refresh(t, first.RefreshToken))
+	wantError(t, env.refresh(t, first.RefreshToken), http.StatusUnauthorized)
+	data[Session](t, env.refresh(t, second.RefreshToken))
+}
+
+func TestSessionInitDataReuse(t *testing.T) {
+	env := newTestEnv()
+	env.addUser(t, 100)
+
+	startWith := func(initData string) *httptest.ResponseRecorder {
+		header := http.Header{}
+		header.Set("X-Telegram-InitData", initData)
+		return env.doWithHeader(t, http.MethodPost, "/auth/session", header, nil)
+	}
+
+	// InitData is exchanged once, e.g. the leaked one couldn't start another session
+	leaked := initData(100, nil)
+	data[Session](t, startWith(leaked))
+	if apperror := wantError(t, startWith(leaked), http.StatusUnauthorized); apperror.Code != InitDataExpired {
+		t.Errorf("got error code %q for reused initData, want %q", apperror.Code, InitDataExpired)
+	}
+
+	// It's kept until it expires, and expired ones are cleaned up on the next exchange
+	for _, exp := range env.db.UsedInitData {
+		if exp == nil || exp.After(time.Now().Add(time.Hour)) {
+			t.Fatalf("used initData is kept until %v, want by its max age", exp)
+		}
+		*exp = time.Now().Add(-time.Second)
+	}
+	data[Session](t, startWith(initData(100, nil)))
+	if len(env.db.UsedInitData) != 1 {
+		t.Errorf("got %d used initData, want 1", len(env.db.UsedInitData))
+	}
+}
+
+func TestSessionLifetime(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+
+	sess := data[Session](t, env.do(t, http.MethodPost, "/auth/session", 100, nil))
+
+	// The login is moved back, so its next refresh token would outlive the max lifetime
+	for _, rt := range env.db.RefreshTokens {
+		rt.LoginAt = rt.LoginAt.Add(-7*24*time.Hour + time.Hour)
+	}
+	refreshed := data[Session](t, env.refresh(t, sess.RefreshToken))
+	if limit := time.Now().Add(time.Hour); refreshed.RefreshExpiresAt.After(limit) || refreshed.ExpiresAt.After(refreshed.RefreshExpiresAt) {
+		t.Errorf("session expires at %v and refresh token at %v, want both by %v", refreshed.ExpiresAt, refreshed.RefreshExpiresAt, limit)
+	}
+
+	for _, rt := range env.db.RefreshTokens {
+		rt.ExpiresAt = time.Now().Add(-time.Second)
+	}
+	if apperror := wantError(t, env.refresh(t, refreshed.RefreshToken), http.StatusUnauthorized); apperror.Code != SessionExpired {
+		t.Errorf("got error code %q for expired refresh token, want %q", apperror.Code, SessionExpired)
+	}
+
+	// Expired tokens are cleaned up on the next login, and all of them are revoked on account deletion
+	data[Session](t, env.do(t, http.MethodPost, "/auth/session", 100, nil))
+	if len(env.db.RefreshTokens) != 1 {
+		t.Errorf("got %d refresh tokens, want 1", len(env.db.RefreshTokens))
+	}
+	u.DeletionScheduledAt = ptr(time.Now())
+	if err := env.res.UsrRepo.UpdateDeletionScheduledAt(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.res.UsrRepo.Delete(context.Background(), u.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if len(env.db.RefreshTokens) != 0 {
+		t.Errorf("got %d refresh tokens of deleted user, want none", len(env.db.RefreshTokens))
+	}
+}
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Timeout sets the deadline of handling the request, none if timeout is zero. Storage queries are
+// cancelled once it's exceeded or the client disconnects
+func (s Server) Timeout(timeout time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
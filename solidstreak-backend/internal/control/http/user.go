@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/url"
@@ -25,12 +26,13 @@ type InitDataTgChat struct {
 }
 
 type InputUser struct {
-	TgID        int64  `json:"tgId"`
-	TgUsername  string `json:"tgUsername"`
-	TgFirstName string `json:"tgFirstName"`
-	TgLastName  string `json:"tgLastName"`
-	TgLangCode  string `json:"tgLangCode"`
-	TgIsBot     bool   `json:"tgIsBot"`
+	TgID        int64   `json:"tgId"`
+	TgUsername  string  `json:"tgUsername"`
+	TgFirstName string  `json:"tgFirstName"`
+	TgLastName  string  `json:"tgLastName"`
+	TgLangCode  string  `json:"tgLangCode"`
+	TgIsBot     bool    `json:"tgIsBot"`
+	Timezone    *string `json:"timezone"`
 }
 
 type TgChat struct {
@@ -50,6 +52,14 @@ type GetUserResponse struct {
 	Data *usrPkg.User `json:"data"`
 }
 
+type UserTimezone struct {
+	Timezone *string `json:"timezone"`
+}
+
+type PutUserTimezoneRequest struct {
+	Data *UserTimezone `json:"data"`
+}
+
 func (s Server) postUserInfo(w http.ResponseWriter, r *http.Request) {
 	var err error
 
@@ -125,39 +135,66 @@ func (s Server) postUserInfo(w http.ResponseWriter, r *http.Request) {
 		inputUser.TgIsBot,
 	)
 
-	userExists := false
-	if userExists, err = s.Res.UsrRepo.IsExists(user); err != nil {
-		return
-	}
-	if userExists {
-		err = s.Res.UsrRepo.Update(user)
-	} else {
-		err = s.Res.UsrRepo.Create(user)
+	// Time zone reported by the Mini App is used only until the user's time zone is known
+	if inputUser.Timezone != nil && usrPkg.ValidateTimezone(*inputUser.Timezone) == nil {
+		user.Timezone = *inputUser.Timezone
 	}
+
+	// User and chat are saved together, so the chat never refers to a user that failed to save
+	err = s.Res.UoW.Do(r.Context(), func(ctx context.Context) error {
+		if err := s.Res.UsrRepo.Upsert(ctx, user); err != nil {
+			return err
+		}
+		return s.Res.TCRepo.Upsert(ctx, tcPkg.NewChat(inputTgChat.TgID, user.ID))
+	})
 	if err != nil {
 		return
 	}
 
-	tgChat := tcPkg.NewChat(inputTgChat.TgID, user.ID)
+	json.NewEncoder(w).Encode(GetUserResponse{Data: user})
+}
+
+func (s Server) getUser(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	logger := s.Res.Logger
+
+	// Adding request ID to request context
+	reqID, _ := r.Context().Value(ctxKeyRequestID{}).(string)
+	if reqID != "" {
+		logger = logger.With("requestId", reqID)
+	}
+
+	defer func() {
+		if err != nil {
+			processError(w, logger, err)
+		}
+	}()
+
+	requester, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
+	if !ok {
+		err = apperrors.ErrUnauthorized("couldn't identify user")
+		return
+	}
 
-	chatExists := false
-	if chatExists, err = s.Res.TCRepo.IsExistsByTgID(tgChat.TgID); err != nil {
+	var userId int64
+	if userId, err = getInt64FromURLParams(r, "userId", true); err != nil {
 		return
 	}
 
-	if chatExists {
-		err = s.Res.TCRepo.Update(tgChat)
-	} else {
-		err = s.Res.TCRepo.Create(tgChat)
+	if err = s.checkCanView(r.Context(), requester.ID, userId); err != nil {
+		return
 	}
-	if err != nil {
+
+	var user *usrPkg.User
+	if user, err = s.Res.UsrRepo.GetByID(r.Context(), userId); err != nil {
 		return
 	}
 
 	json.NewEncoder(w).Encode(GetUserResponse{Data: user})
 }
 
-func (s Server) getUser(w http.ResponseWriter, r *http.Request) {
+func (s Server) putUserTimezone(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	logger := s.Res.Logger
@@ -174,7 +211,7 @@ func (s Server) getUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	userTgID, ok := r.Context().Value(ctxKeyUserTgID{}).(int64)
+	user, ok := r.Context().Value(ctxKeyUser{}).(*usrPkg.User)
 	if !ok {
 		err = apperrors.ErrUnauthorized("couldn't identify user")
 		return
@@ -185,14 +222,30 @@ func (s Server) getUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user *usrPkg.User
-	if user, err = s.Res.UsrRepo.GetByID(userId); err != nil {
+	var req PutUserTimezoneRequest
+
+	decoder := json.NewDecoder(r.Body)
+	if err = decoder.Decode(&req); err != nil {
+		err = apperrors.ErrBadRequest("invalid request payload")
+		return
+	}
+
+	if req.Data == nil || req.Data.Timezone == nil {
+		err = apperrors.ErrBadRequest("time zone is required")
+		return
+	}
+	if err = usrPkg.ValidateTimezone(*req.Data.Timezone); err != nil {
+		err = apperrors.ErrBadRequest(err.Error())
+		return
+	}
+
+	if user.ID != userId {
+		err = apperrors.ErrForbidden("couldn't update time zone for another user")
 		return
 	}
 
-	// Временное ограничение. Как появится возможность просмтатривать карточки чужих юзеров, убрать это условие
-	if user.TgID != userTgID {
-		err = apperrors.ErrUnauthorized("couldn't get user info for another user")
+	user.Timezone = *req.Data.Timezone
+	if err = s.Res.UsrRepo.UpdateTimezone(r.Context(), user); err != nil {
 		return
 	}
 
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+func userInfoRequest(tgID, chatTgID int64, timezone string) PostUserInfoRequest {
+	input := &InputUser{TgID: tgID, TgUsername: "user" + strconv.FormatInt(tgID, 10), TgFirstName: "User"}
+	if timezone != "" {
+		input.Timezone = &timezone
+	}
+	return PostUserInfoRequest{Data: &UserInfoData{User: input, TgChat: &TgChat{TgID: chatTgID}}}
+}
+
+func TestPostUserInfo(t *testing.T) {
+	env := newTestEnv()
+
+	created := data[*usrPkg.User](t, env.do(t, http.MethodPost, "/user-info/upsert", 100, userInfoRequest(100, 100, "Europe/Berlin")))
+	if created.ID == 0 || created.TgID != 100 || created.Timezone != "Europe/Berlin" {
+		t.Fatalf("unexpected created user %+v", created)
+	}
+	if c, ok := env.db.TgChats[100]; !ok || c.UserID != created.ID {
+		t.Fatalf("telegram chat isn't saved: %+v", env.db.TgChats)
+	}
+
+	// Known time zone isn't replaced by the one reported by the Mini App
+	updated := data[*usrPkg.User](t, env.do(t, http.MethodPost, "/user-info/upsert", 100, userInfoRequest(100, 100, "Asia/Tokyo")))
+	if updated.ID != created.ID || updated.Timezone != "Europe/Berlin" {
+		t.Errorf("unexpected updated user %+v", updated)
+	}
+	if len(env.db.Users) != 1 {
+		t.Errorf("got %d users, want 1", len(env.db.Users))
+	}
+}
+
+func TestPostUserInfoValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		req  any
+	}{
+		{"invalid payload", []byte("{")},
+		{"missing data", PostUserInfoRequest{}},
+		{"missing user", PostUserInfoRequest{Data: &UserInfoData{TgChat: &TgChat{TgID: 100}}}},
+		{"missing chat", PostUserInfoRequest{Data: &UserInfoData{User: &InputUser{TgID: 100}}}},
+		{"user mismatching init data", userInfoRequest(200, 100, "")},
+		{"chat mismatching init data", userInfoRequest(100, 200, "")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+
+			wantError(t, env.do(t, http.MethodPost, "/user-info/upsert", 100, tt.req), http.StatusBadRequest)
+			if len(env.db.Users) != 0 {
+				t.Errorf("unexpected users %+v", env.db.Users)
+			}
+		})
+	}
+}
+
+func TestPostUserInfoGroupChat(t *testing.T) {
+	env := newTestEnv()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/user-info/upsert", strings.NewReader(`{"data":{"user":{"tgId":100,"tgUsername":"user100","tgFirstName":"User"},"tgChat":{"tgId":-500}}}`))
+	r.Header.Set("X-Telegram-InitData", initData(100, url.Values{"chat": {`{"id":-500}`}}))
+	w := httptest.NewRecorder()
+	env.handler.ServeHTTP(w, r)
+
+	u := data[*usrPkg.User](t, w)
+	if c, ok := env.db.TgChats[-500]; !ok || c.UserID != u.ID {
+		t.Errorf("group chat isn't saved: %+v", env.db.TgChats)
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	friend := env.addUser(t, 200)
+	stranger := env.addUser(t, 300)
+	env.befriend(t, u, friend)
+
+	if got := data[*usrPkg.User](t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(u.ID, 10), 100, nil)); got.ID != u.ID {
+		t.Errorf("got user %d, want %d", got.ID, u.ID)
+	}
+	if got := data[*usrPkg.User](t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(friend.ID, 10), 100, nil)); got.ID != friend.ID {
+		t.Errorf("got user %d, want friend %d", got.ID, friend.ID)
+	}
+	wantError(t, env.do(t, http.MethodGet, "/users/"+strconv.FormatInt(stranger.ID, 10), 100, nil), http.StatusForbidden)
+}
+
+func TestPutUserTimezone(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	other := env.addUser(t, 200)
+	path := "/users/" + strconv.FormatInt(u.ID, 10) + "/timezone"
+
+	tz := func(s string) PutUserTimezoneRequest {
+		return PutUserTimezoneRequest{Data: &UserTimezone{Timezone: &s}}
+	}
+
+	if got := data[*usrPkg.User](t, env.do(t, http.MethodPut, path, 100, tz("America/New_York"))); got.Timezone != "America/New_York" {
+		t.Errorf("got time zone %q in response", got.Timezone)
+	}
+	if stored, _ := env.res.UsrRepo.GetByID(context.Background(), u.ID); stored.Timezone != "America/New_York" {
+		t.Errorf("got stored time zone %q", stored.Timezone)
+	}
+
+	wantError(t, env.do(t, http.MethodPut, path, 100, tz("Mars/Olympus")), http.StatusBadRequest)
+	wantError(t, env.do(t, http.MethodPut, path, 100, PutUserTimezoneRequest{}), http.StatusBadRequest)
+	wantError(t, env.do(t, http.MethodPut, "/users/"+strconv.FormatInt(other.ID, 10)+"/timezone", 100, tz("UTC")), http.StatusForbidden)
+}
+
+func TestAccountDeletion(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	other := env.addUser(t, 200)
+	path := "/users/" + strconv.FormatInt(u.ID, 10) + "/deletion"
+
+	confirm := PostAccountDeletionRequest{Data: &AccountDeletion{Confirmation: usrPkg.DeletionConfirmation}}
+
+	wantError(t, env.do(t, http.MethodPost, path, 100, PostAccountDeletionRequest{Data: &AccountDeletion{Confirmation: "yes"}}), http.StatusBadRequest)
+	wantError(t, env.do(t, http.MethodPost, "/users/"+strconv.FormatInt(other.ID, 10)+"/deletion", 100, confirm), http.StatusForbidden)
+
+	scheduled := data[*usrPkg.User](t, env.do(t, http.MethodPost, path, 100, confirm))
+	if scheduled.DeletionScheduledAt == nil {
+		t.Fatal("account deletion isn't scheduled")
+	}
+	if stored, _ := env.res.UsrRepo.GetByID(context.Background(), u.ID); stored.DeletionScheduledAt == nil {
+		t.Error("account deletion isn't saved")
+	}
+
+	wantError(t, env.do(t, http.MethodDelete, "/users/"+strconv.FormatInt(other.ID, 10)+"/deletion", 100, nil), http.StatusForbidden)
+
+	if cancelled := data[*usrPkg.User](t, env.do(t, http.MethodDelete, path, 100, nil)); cancelled.DeletionScheduledAt != nil {
+		t.Error("account deletion isn't cancelled")
+	}
+	if stored, _ := env.res.UsrRepo.GetByID(context.Background(), u.ID); stored.DeletionScheduledAt != nil {
+		t.Error("account deletion cancellation isn't saved")
+	}
+}
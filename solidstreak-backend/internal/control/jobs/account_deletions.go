@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	usecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/tgbot"
+)
+
+// AccountDeletions deletes accounts which deletion grace period is over along with all their data.
+// The user is notified in the bot, which chat is looked up before it's deleted
+type AccountDeletions struct {
+	Every time.Duration
+	Res   resources.Resources
+}
+
+func (j AccountDeletions) Name() string {
+	return "account_deletions"
+}
+
+func (j AccountDeletions) Interval() time.Duration {
+	return j.Every
+}
+
+func (j AccountDeletions) Do(ctx context.Context, logger *slog.Logger) error {
+	users, err := j.Res.UsrRepo.GetScheduledForDeletion(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, u := range users {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Failed deletion doesn't prevent deleting the others, it's retried next time
+		if err = j.delete(ctx, u, logger); err != nil {
+			logger.Error("account deletion error", "userId", u.ID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (j AccountDeletions) delete(ctx context.Context, u *usrPkg.User, logger *slog.Logger) error {
+	tc, err := j.Res.TCRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		logger.Warn("telegram chat of account to delete not found", "userId", u.ID, "error", err)
+	}
+
+	// Deletion could be cancelled since the users due for it are got
+	deleted, err := j.Res.UsrRepo.Delete(ctx, u.ID, time.Now())
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		logger.Info("account deletion skipped as cancelled", "userId", u.ID)
+		return nil
+	}
+
+	logger.Info("account deleted", "userId", u.ID)
+
+	if tc == nil {
+		return nil
+	}
+	if err = usecases.SendReplyMsg(j.Res, tc, "Your account and all your data have been deleted\nSend /start to start over"); err != nil {
+		logger.Warn("account deletion notification error", "userId", u.ID, "error", err)
+	}
+
+	return nil
+}
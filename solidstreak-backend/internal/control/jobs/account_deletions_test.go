@@ -0,0 +1,64 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	tcRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat/repo"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	usrRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user/repo"
+)
+
+func TestAccountDeletionsSkipCancelled(t *testing.T) {
+	ctx := context.Background()
+	db := memdb.New()
+	j := AccountDeletions{Res: resources.Resources{
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+		UsrRepo: usrRepo.InitMemory(db),
+		TCRepo:  tcRepo.InitMemory(db),
+	}}
+
+	scheduledAt := time.Now().Add(-time.Minute)
+	var users []*usrPkg.User
+	for _, tgID := range []int64{100, 200} {
+		u := usrPkg.NewUser(tgID, "", "User", "", "en", false)
+		if err := j.Res.UsrRepo.Upsert(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		u.DeletionScheduledAt = &scheduledAt
+		if err := j.Res.UsrRepo.UpdateDeletionScheduledAt(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+
+	due, err := j.Res.UsrRepo.GetScheduledForDeletion(ctx, time.Now())
+	if err != nil || len(due) != 2 {
+		t.Fatalf("got users %+v due for deletion, error %v, want 2", due, err)
+	}
+
+	// The first user cancels the deletion after the job has got users due for it
+	cancelled := users[0]
+	cancelled.DeletionScheduledAt = nil
+	if err = j.Res.UsrRepo.UpdateDeletionScheduledAt(ctx, cancelled); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range due {
+		if err = j.delete(ctx, u, j.Res.Logger); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok := db.Users[cancelled.ID]; !ok {
+		t.Error("user who cancelled deletion is deleted")
+	}
+	if _, ok := db.Users[users[1].ID]; ok {
+		t.Error("user due for deletion isn't deleted")
+	}
+}
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+// ChecksPartitions keeps monthly partitions of habit checks created for the current month in UTC
+// and MonthsAhead upcoming months, along with the default partition as a safety net. Partitions
+// of past months with wrong bounds, as legacy hand-written ones, are recreated with correct ones
+type ChecksPartitions struct {
+	MonthsAhead int
+	Every       time.Duration
+	Res         resources.Resources
+}
+
+func (j ChecksPartitions) Name() string {
+	return "checks_partitions"
+}
+
+func (j ChecksPartitions) Interval() time.Duration {
+	return j.Every
+}
+
+func (j ChecksPartitions) Do(ctx context.Context, logger *slog.Logger) error {
+	created, err := j.Res.PartRepo.EnsureChecksDefaultPartition(ctx)
+	if err != nil {
+		return err
+	}
+	if created {
+		logger.Info("default checks partition created")
+	}
+
+	// The month doesn't depend on the server's time zone. Users' dates in the neighbouring months
+	// are covered by the upcoming partitions and the default one
+	month := date.TodayIn(time.UTC).MonthStart()
+	for i := 0; i <= j.MonthsAhead; i++ {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if created, err = j.Res.PartRepo.EnsureChecksMonthPartition(ctx, month); err != nil {
+			return err
+		}
+		if created {
+			logger.Info("checks partition created", "month", month.String())
+		}
+		month = month.AddDate(0, 1, 0)
+	}
+
+	partitions, err := j.Res.PartRepo.GetChecksPartitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	fixed := false
+	for _, p := range partitions {
+		if !p.WrongBounds {
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err = j.Res.PartRepo.EnsureChecksMonthPartition(ctx, *p.Month); err != nil {
+			return err
+		}
+		logger.Info("checks partition with wrong bounds fixed", "month", p.Month.String(), "bounds", p.Bounds)
+		fixed = true
+	}
+	if fixed {
+		if partitions, err = j.Res.PartRepo.GetChecksPartitions(ctx); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("checks partitions layout", "partitions", partitions)
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	hRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit/repo"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+// fakePartitionRepo records the months partitions are ensured for, as there are no partitions
+// in the in-memory storage. Ensuring a partition fixes its bounds
+type fakePartitionRepo struct {
+	ensured    []string
+	partitions []*hRepo.Partition
+}
+
+func (r *fakePartitionRepo) EnsureChecksDefaultPartition(context.Context) (bool, error) {
+	return false, nil
+}
+
+func (r *fakePartitionRepo) EnsureChecksMonthPartition(ctx context.Context, month date.Date) (bool, error) {
+	r.ensured = append(r.ensured, month.String())
+	for _, p := range r.partitions {
+		if p.Month != nil && p.Month.Compare(month) == 0 {
+			p.WrongBounds = false
+		}
+	}
+	return true, nil
+}
+
+func (r *fakePartitionRepo) GetChecksPartitions(context.Context) ([]*hRepo.Partition, error) {
+	return r.partitions, nil
+}
+
+func TestChecksPartitions(t *testing.T) {
+	legacy := date.Date(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	correct := date.Date(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	repo := &fakePartitionRepo{partitions: []*hRepo.Partition{
+		{Name: "user_habit_checks_default"},
+		{Name: "user_habit_checks_y2024m05", Month: &legacy, WrongBounds: true},
+		{Name: "user_habit_checks_y2024m06", Month: &correct},
+	}}
+	j := ChecksPartitions{MonthsAhead: 2, Res: resources.Resources{PartRepo: repo}}
+
+	if err := j.Do(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil))); err != nil {
+		t.Fatal(err)
+	}
+
+	month := date.TodayIn(time.UTC).MonthStart()
+	want := []string{month.String(), month.AddDate(0, 1, 0).String(), month.AddDate(0, 2, 0).String(), "2024-05-01"}
+	if !slices.Equal(repo.ensured, want) {
+		t.Errorf("got partitions ensured for %v, want %v", repo.ensured, want)
+	}
+	if repo.partitions[1].WrongBounds {
+		t.Error("legacy partition isn't fixed")
+	}
+}
@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	tcPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	usecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/tgbot"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+// HabitReminders sends reminders of habits which reminder time has come in their owners' time
+// zones. Reminders are claimed in storage before sending, so each one is sent at most once a day
+// regardless of restarts and replicas. Habits already checked off or not scheduled for the day
+// are skipped
+type HabitReminders struct {
+	Every time.Duration
+	Res   resources.Resources
+}
+
+func (j HabitReminders) Name() string {
+	return "habit_reminders"
+}
+
+func (j HabitReminders) Interval() time.Duration {
+	return j.Every
+}
+
+func (j HabitReminders) Do(ctx context.Context, logger *slog.Logger) error {
+	reminders, err := j.Res.HabitRepo.ClaimDueReminders(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, rm := range reminders {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Failed reminder doesn't prevent sending the others
+		if err = j.send(ctx, rm, logger); err != nil {
+			logger.Error("habit reminder sending error", "habitId", rm.Habit.ID, "userId", rm.UserID, "error", err)
+		}
+	}
+
+	return nil
+}
+
+func (j HabitReminders) send(ctx context.Context, rm *hPkg.Reminder, logger *slog.Logger) error {
+	var (
+		err error
+		u   *usrPkg.User
+		tc  *tcPkg.Chat
+	)
+
+	if u, err = j.Res.UsrRepo.GetByID(ctx, rm.UserID); err != nil {
+		return err
+	}
+
+	if !rm.Habit.Schedule.IsDueOn(rm.Date, date.NewIn(rm.Habit.CreatedAt, u.Location())) {
+		logger.Debug("habit reminder skipped, habit isn't scheduled", "habitId", rm.Habit.ID, "userId", rm.UserID)
+		return nil
+	}
+
+	var checks []*hPkg.HabitCheck
+	if checks, err = j.Res.HabitRepo.GetUserHabitsCompletedChecks(ctx, rm.UserID, []int64{rm.Habit.ID}, &rm.Date, &rm.Date); err != nil {
+		return err
+	}
+	if len(checks) > 0 {
+		logger.Debug("habit reminder skipped, habit is checked off", "habitId", rm.Habit.ID, "userId", rm.UserID)
+		return nil
+	}
+
+	if tc, err = j.Res.TCRepo.GetByUserID(ctx, rm.UserID); err != nil {
+		return err
+	}
+
+	if err = usecases.SendReplyMsg(j.Res, tc, "Reminder: \""+rm.Habit.Title+"\"\nDon't forget to check it off today, see /habits"); err != nil {
+		return err
+	}
+
+	logger.Info("habit reminder sent", "habitId", rm.Habit.ID, "userId", rm.UserID)
+
+	return nil
+}
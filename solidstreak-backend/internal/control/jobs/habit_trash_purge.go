@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+)
+
+// Max number of habits purged in a single transaction
+const habitTrashPurgeBatch = 100
+
+// HabitTrashPurge permanently deletes habits kept in the trash longer than the retention period
+// along with their checks and memberships
+type HabitTrashPurge struct {
+	Retention time.Duration
+	Every     time.Duration
+	Res       resources.Resources
+}
+
+func (j HabitTrashPurge) Name() string {
+	return "habit_trash_purge"
+}
+
+func (j HabitTrashPurge) Interval() time.Duration {
+	return j.Every
+}
+
+func (j HabitTrashPurge) Do(ctx context.Context, logger *slog.Logger) error {
+	deletedBefore := time.Now().Add(-j.Retention)
+
+	total := 0
+	for ctx.Err() == nil {
+		purged, err := j.Res.HabitRepo.PurgeTrashed(ctx, deletedBefore, habitTrashPurgeBatch)
+		if err != nil {
+			return err
+		}
+		total += purged
+		if purged < habitTrashPurgeBatch {
+			break
+		}
+	}
+
+	if total > 0 {
+		logger.Info("trashed habits purged", "habits", total, "deletedBefore", deletedBefore)
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+)
+
+type Job interface {
+	Name() string
+	Interval() time.Duration
+	Do(context.Context, *slog.Logger) error
+}
+
+// Scheduler runs every job once on start and then repeatedly with the job's interval
+type Scheduler struct {
+	Jobs []Job
+	Res  resources.Resources
+}
+
+func (s Scheduler) Run(ctx context.Context, doneCh chan struct{}) {
+	defer func() { doneCh <- struct{}{} }()
+
+	s.Res.Logger.Info("job scheduler started")
+
+	var wg sync.WaitGroup
+	for _, j := range s.Jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runJob(ctx, j)
+		}()
+	}
+	wg.Wait()
+
+	s.Res.Logger.Info("job scheduler stopped")
+}
+
+func (s Scheduler) runJob(ctx context.Context, j Job) {
+	logger := s.Res.Logger.With("job", j.Name())
+
+	ticker := time.NewTicker(j.Interval())
+	defer ticker.Stop()
+
+	for {
+		s.runJobOnce(ctx, logger, j)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s Scheduler) runJobOnce(ctx context.Context, logger *slog.Logger, j Job) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("panic recovered in job", "panic", r)
+		}
+	}()
+
+	logger.Debug("job started")
+
+	if err := j.Do(ctx, logger); err != nil {
+		logger.Error("job error", "error", err)
+		return
+	}
+
+	logger.Debug("job finished")
+}
@@ -0,0 +1,88 @@
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	tcPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	atUsecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/accesstoken"
+	usecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/tgbot"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+const newTokenUsageMsgText = `Usage: /new_token <scope> <name>
+
+Scopes:
+read - view your habits, checks and friends
+checks - only check off habits
+full - everything but managing tokens and deleting your account`
+
+func (eh EventHandler) sendAccessTokens(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat) error {
+	tokens, err := atUsecases.List(ctx, eh.Res, usr, usr.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(tokens) == 0 {
+		return usecases.SendReplyMsg(eh.Res, tc, "You have no access tokens\nSee /new_token to create one")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your access tokens\n")
+	for _, t := range tokens {
+		sb.WriteString("\n" + strconv.FormatInt(t.ID, 10) + ". " + t.Name + " (" + string(t.Scope) + "), created on " +
+			t.CreatedAt.In(usr.Location()).Format("2 January 2006"))
+	}
+	sb.WriteString("\n\nSend /revoke_token <id> to revoke one")
+
+	return usecases.SendReplyMsg(eh.Res, tc, sb.String())
+}
+
+// createAccessToken creates the token and sends its secret, which is shown once. Tokens are
+// created in the private chat with the bot only, so the secret isn't seen by others
+func (eh EventHandler) createAccessToken(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat, args string) error {
+	if tc.TgID != usr.TgID {
+		return usecases.SendReplyMsg(eh.Res, tc, "Access tokens could be created in the private chat with the bot only")
+	}
+
+	scope, name, _ := strings.Cut(strings.TrimSpace(args), " ")
+	if _, ok := atPkg.ScopeMapping[scope]; !ok || strings.TrimSpace(name) == "" {
+		return usecases.SendReplyMsg(eh.Res, tc, newTokenUsageMsgText)
+	}
+
+	t, secret, err := atUsecases.Create(ctx, eh.Res, usr, usr.ID, name, scope)
+	if err != nil {
+		var apperror apperrors.Error
+		if errors.As(err, &apperror) && apperror.HTTPCode == 400 {
+			return usecases.SendReplyMsg(eh.Res, tc, "Couldn't create the token: "+apperror.Detail)
+		}
+		return err
+	}
+	eh.Res.Logger.Info("access token created", "userId", usr.ID, "accessTokenId", t.ID, "scope", t.Scope)
+
+	return usecases.SendReplyMsg(eh.Res, tc, "Access token \""+t.Name+"\" created:\n\n"+secret+
+		"\n\nSend it in \"Authorization: Bearer\" header of API requests. Keep it secret, it's shown only once")
+}
+
+func (eh EventHandler) revokeAccessToken(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat, args string) error {
+	tokenID, err := strconv.ParseInt(strings.TrimSpace(args), 10, 64)
+	if err != nil {
+		return usecases.SendReplyMsg(eh.Res, tc, "Usage: /revoke_token <id>\nSee /tokens for IDs of your tokens")
+	}
+
+	t, err := atUsecases.Revoke(ctx, eh.Res, usr, usr.ID, tokenID)
+	if err != nil {
+		var apperror apperrors.Error
+		if errors.As(err, &apperror) && apperror.HTTPCode == 404 {
+			return usecases.SendReplyMsg(eh.Res, tc, "Access token not found\nSee /tokens for IDs of your tokens")
+		}
+		return err
+	}
+	eh.Res.Logger.Info("access token revoked", "userId", usr.ID, "accessTokenId", t.ID)
+
+	return usecases.SendReplyMsg(eh.Res, tc, "Access token \""+t.Name+"\" revoked")
+}
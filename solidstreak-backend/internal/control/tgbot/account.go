@@ -0,0 +1,64 @@
+package tgbot
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	tcPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	usecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/tgbot"
+)
+
+const deleteAccountCallbackData = "delete_account:confirm"
+
+func deletionScheduledMsgText(usr *usrPkg.User) string {
+	return "Your account will be deleted on " + usr.DeletionScheduledAt.In(usr.Location()).Format("2 January 2006 15:04") +
+		"\nSend /cancel_deletion to keep it"
+}
+
+// askAccountDeletion asks to confirm account deletion with the button
+func (eh EventHandler) askAccountDeletion(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat) error {
+	if usr.DeletionScheduledAt != nil {
+		return usecases.SendReplyMsg(eh.Res, tc, deletionScheduledMsgText(usr))
+	}
+
+	days := max(int(eh.AccountDeletionGracePeriod.Hours()/24), 1)
+	text := "Your account will be deleted along with your checks, friends and habits no one else takes part in. " +
+		"You can cancel the deletion within " + pluralize(days, "day") + ", then it can't be undone\n\nDelete your account?"
+	kb := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Delete my account", deleteAccountCallbackData),
+	))
+
+	return usecases.SendReplyMsgWithKeyboard(eh.Res, tc, text, kb)
+}
+
+func (eh EventHandler) confirmAccountDeletion(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat, cq *tgbotapi.CallbackQuery) error {
+	if usr.DeletionScheduledAt == nil {
+		usr.ScheduleDeletion(eh.AccountDeletionGracePeriod)
+		if err := eh.Res.UsrRepo.UpdateDeletionScheduledAt(ctx, usr); err != nil {
+			return err
+		}
+		eh.Res.Logger.Info("account deletion scheduled", "userId", usr.ID, "deletionScheduledAt", usr.DeletionScheduledAt)
+	}
+
+	if err := usecases.AnswerCallbackQuery(eh.Res, cq.ID, "Account deletion scheduled"); err != nil {
+		return err
+	}
+
+	return usecases.SendReplyMsg(eh.Res, tc, deletionScheduledMsgText(usr))
+}
+
+func (eh EventHandler) cancelAccountDeletion(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat) error {
+	if usr.DeletionScheduledAt == nil {
+		return usecases.SendReplyMsg(eh.Res, tc, "Your account isn't scheduled for deletion")
+	}
+
+	usr.CancelDeletion()
+	if err := eh.Res.UsrRepo.UpdateDeletionScheduledAt(ctx, usr); err != nil {
+		return err
+	}
+	eh.Res.Logger.Info("account deletion cancelled", "userId", usr.ID)
+
+	return usecases.SendReplyMsg(eh.Res, tc, "Account deletion cancelled, welcome back!")
+}
@@ -0,0 +1,185 @@
+package tgbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	tcPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	hUsecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/habit"
+	usecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/tgbot"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+const checkCallbackPrefix = "check:"
+
+const helpMsgText = `Solid Streak helps you keep up your habits
+
+/habits - today's habits, tap one to check it off
+/stats - streaks and completion of your habits
+/tokens - your access tokens for the API
+/new_token - create an access token
+/revoke_token - revoke an access token
+/delete_account - delete your account and all your data
+/cancel_deletion - cancel account deletion
+/help - this message
+
+Push "Open" button to manage your habits in the app`
+
+func greetingMsgText(usr *usrPkg.User) string {
+	return "Hello, " + usr.TgFirstName + "!\nPush \"Open\" button to start using bot\nSee /help for the list of commands"
+}
+
+func (eh EventHandler) handleMessage(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat, msg *tgbotapi.Message) error {
+	if !msg.IsCommand() {
+		return usecases.SendReplyMsg(eh.Res, tc, greetingMsgText(usr))
+	}
+
+	switch msg.Command() {
+	case "start":
+		// Habit invite deep link
+		if token, ok := strings.CutPrefix(msg.CommandArguments(), hPkg.InviteStartParamPrefix); ok {
+			return eh.joinHabit(ctx, usr, tc, token)
+		}
+		return usecases.SendReplyMsg(eh.Res, tc, greetingMsgText(usr))
+	case "help":
+		return usecases.SendReplyMsg(eh.Res, tc, helpMsgText)
+	case "habits":
+		return eh.sendTodayHabits(ctx, usr, tc)
+	case "stats":
+		return eh.sendStats(ctx, usr, tc)
+	case "tokens":
+		return eh.sendAccessTokens(ctx, usr, tc)
+	case "new_token":
+		return eh.createAccessToken(ctx, usr, tc, msg.CommandArguments())
+	case "revoke_token":
+		return eh.revokeAccessToken(ctx, usr, tc, msg.CommandArguments())
+	case "delete_account":
+		return eh.askAccountDeletion(ctx, usr, tc)
+	case "cancel_deletion":
+		return eh.cancelAccountDeletion(ctx, usr, tc)
+	default:
+		return usecases.SendReplyMsg(eh.Res, tc, "Unknown command /"+msg.Command()+"\nSee /help for the list of commands")
+	}
+}
+
+func (eh EventHandler) sendTodayHabits(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat) error {
+	habits, err := usecases.GetTodayHabits(ctx, eh.Res, usr)
+	if err != nil {
+		return err
+	}
+
+	if len(habits) == 0 {
+		return usecases.SendReplyMsg(eh.Res, tc, "No habits scheduled for today\nPush \"Open\" button to add one")
+	}
+
+	return usecases.SendReplyMsgWithKeyboard(eh.Res, tc, "Today's habits\nTap a habit to check it off", todayHabitsKeyboard(habits))
+}
+
+func (eh EventHandler) joinHabit(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat, token string) error {
+	h, err := hUsecases.Join(ctx, eh.Res, usr, token, false)
+	if err != nil {
+		var apperror apperrors.Error
+		if errors.As(err, &apperror) && (apperror.HTTPCode == 400 || apperror.HTTPCode == 404) {
+			return usecases.SendReplyMsg(eh.Res, tc, "Couldn't join the habit: "+apperror.Detail)
+		}
+		return err
+	}
+
+	return usecases.SendReplyMsg(eh.Res, tc, "You've joined \""+h.Title+"\"\nSee /habits to check it off")
+}
+
+func (eh EventHandler) sendStats(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat) error {
+	habits, err := usecases.GetHabitsStats(ctx, eh.Res, usr)
+	if err != nil {
+		return err
+	}
+
+	if len(habits) == 0 {
+		return usecases.SendReplyMsg(eh.Res, tc, "You have no habits yet\nPush \"Open\" button to add one")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Your habits for the last year\n")
+	for _, h := range habits {
+		fmt.Fprintf(&sb, "\n%s\nCurrent streak: %s\nLongest streak: %s\nCompletion: %.0f%%\n",
+			h.Title,
+			pluralize(h.Stats.CurrentStreak, h.Stats.StreakUnit),
+			pluralize(h.Stats.LongestStreak, h.Stats.StreakUnit),
+			h.Stats.CompletionRate*100,
+		)
+	}
+
+	return usecases.SendReplyMsg(eh.Res, tc, sb.String())
+}
+
+func (eh EventHandler) handleCallbackQuery(ctx context.Context, usr *usrPkg.User, tc *tcPkg.Chat, cq *tgbotapi.CallbackQuery) error {
+	if cq.Data == deleteAccountCallbackData {
+		return eh.confirmAccountDeletion(ctx, usr, tc, cq)
+	}
+
+	idStr, ok := strings.CutPrefix(cq.Data, checkCallbackPrefix)
+	if !ok {
+		return usecases.AnswerCallbackQuery(eh.Res, cq.ID, "Unknown action")
+	}
+	habitID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return usecases.AnswerCallbackQuery(eh.Res, cq.ID, "Unknown action")
+	}
+
+	h, hc, err := usecases.ToggleTodayCheck(ctx, eh.Res, usr, habitID)
+	if err != nil {
+		var apperror apperrors.Error
+		if errors.As(err, &apperror) && apperror.HTTPCode == 404 {
+			return usecases.AnswerCallbackQuery(eh.Res, cq.ID, "Habit not found")
+		}
+		return err
+	}
+
+	answer := "\"" + h.Title + "\" unchecked"
+	if hc.Completed {
+		answer = "\"" + h.Title + "\" checked off"
+	}
+	if err = usecases.AnswerCallbackQuery(eh.Res, cq.ID, answer); err != nil {
+		return err
+	}
+
+	if cq.Message == nil {
+		return nil
+	}
+
+	habits, err := usecases.GetTodayHabits(ctx, eh.Res, usr)
+	if err != nil {
+		return err
+	}
+
+	return usecases.EditMsgKeyboard(eh.Res, tc, cq.Message.MessageID, todayHabitsKeyboard(habits))
+}
+
+func todayHabitsKeyboard(habits []*hPkg.Habit) tgbotapi.InlineKeyboardMarkup {
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(habits))
+	for _, h := range habits {
+		text := "⬜ " + h.Title
+		if usecases.IsCompletedToday(h) {
+			text = "✅ " + h.Title
+		}
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(text, checkCallbackPrefix+strconv.FormatInt(h.ID, 10)),
+		))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return strconv.Itoa(n) + " " + unit + "s"
+}
@@ -2,6 +2,7 @@ package tgbot
 
 import (
 	"context"
+	"time"
 
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
 	"github.com/google/uuid"
@@ -9,22 +10,28 @@ import (
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// EventFetcher receives updates by long polling, or from Webhook if it's set, and runs no more
+// than MaxEventHandlers event handlers at once
 type EventFetcher struct {
-	TgBotUpdsOffset  int
-	TgBotUpdsTimeout int
-	MaxEventHandlers int
-	Res              resources.Resources
+	TgBotUpdsOffset            int
+	TgBotUpdsTimeout           int
+	MaxEventHandlers           int
+	EventHandlerTimeout        time.Duration
+	Webhook                    *Webhook
+	AccountDeletionGracePeriod time.Duration
+	Res                        resources.Resources
 }
 
 func (ef EventFetcher) Run(ctx context.Context, doneCh chan struct{}) {
 	defer func() { doneCh <- struct{}{} }()
 
-	ef.Res.Logger.Info("event fetcher started")
-
-	updConfig := tgbotapi.NewUpdate(ef.TgBotUpdsOffset)
-	updConfig.Timeout = ef.TgBotUpdsTimeout
+	upds, err := ef.updates()
+	if err != nil {
+		ef.Res.Logger.Error("event fetcher starting error", "error", err)
+		return
+	}
 
-	upds := ef.Res.TgBotAPI.GetUpdatesChan(updConfig)
+	ef.Res.Logger.Info("event fetcher started")
 
 	handlers := make(map[string]struct{}, ef.MaxEventHandlers)
 	handlerDoneCh := make(chan string, ef.MaxEventHandlers)
@@ -60,12 +67,18 @@ loop:
 			handlers[handlerCode] = struct{}{}
 			ef.Res.Logger.Debug("running new event handler", "handlerCode", handlerCode)
 			go EventHandler{
-				Code: handlerCode,
-				Res:  ef.Res,
+				Code:                       handlerCode,
+				Timeout:                    ef.EventHandlerTimeout,
+				AccountDeletionGracePeriod: ef.AccountDeletionGracePeriod,
+				Res:                        ef.Res,
 			}.Run(handlerDoneCh, &upd)
 		}
 	}
 
+	if ef.Webhook != nil {
+		ef.stopWebhook()
+	}
+
 	if len(handlers) > 0 {
 		ef.Res.Logger.Info("waiting for event handlers to finish")
 	}
@@ -76,3 +89,51 @@ loop:
 
 	ef.Res.Logger.Info("event fetcher stopped")
 }
+
+func (ef EventFetcher) updates() (tgbotapi.UpdatesChannel, error) {
+	if ef.Webhook != nil {
+		if err := ef.Webhook.register(ef.Res); err != nil {
+			return nil, err
+		}
+		ef.Res.Logger.Info("telegram webhook registered", "path", ef.Webhook.Path())
+		return ef.Webhook.updates(), nil
+	}
+
+	// Telegram rejects long polling while a webhook is registered, e.g. after switching from webhook mode
+	if err := deleteWebhook(ef.Res); err != nil {
+		return nil, err
+	}
+
+	updConfig := tgbotapi.NewUpdate(ef.TgBotUpdsOffset)
+	updConfig.Timeout = ef.TgBotUpdsTimeout
+
+	return ef.Res.TgBotAPI.GetUpdatesChan(updConfig), nil
+}
+
+func (ef EventFetcher) stopWebhook() {
+	if !ef.Webhook.KeepOnShutdown {
+		if err := deleteWebhook(ef.Res); err != nil {
+			ef.Res.Logger.Error("telegram webhook deregistration error", "error", err)
+		} else {
+			ef.Res.Logger.Info("telegram webhook deregistered")
+		}
+	}
+
+	ef.Webhook.close()
+
+	// Updates accepted before closing are still handled
+	for {
+		select {
+		case upd := <-ef.Webhook.updates():
+			ef.Res.Logger.Info("new update received", "update", upd)
+			EventHandler{
+				Code:                       uuid.NewString()[:8],
+				Timeout:                    ef.EventHandlerTimeout,
+				AccountDeletionGracePeriod: ef.AccountDeletionGracePeriod,
+				Res:                        ef.Res,
+			}.Run(make(chan string, 1), &upd)
+		default:
+			return
+		}
+	}
+}
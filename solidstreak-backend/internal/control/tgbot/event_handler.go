@@ -1,6 +1,9 @@
 package tgbot
 
 import (
+	"context"
+	"time"
+
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
@@ -10,8 +13,10 @@ import (
 )
 
 type EventHandler struct {
-	Code string
-	Res  resources.Resources
+	Code                       string
+	Timeout                    time.Duration // Deadline of handling the update, no deadline if not set
+	AccountDeletionGracePeriod time.Duration
+	Res                        resources.Resources
 }
 
 func (eh EventHandler) Run(doneCh chan string, upd *tgbotapi.Update) {
@@ -38,18 +43,31 @@ func (eh EventHandler) Run(doneCh chan string, upd *tgbotapi.Update) {
 
 	eh.Res.Logger = eh.Res.Logger.With("handlerCode", eh.Code)
 
-	var usr *usrPkg.User
-	if usr, err = usecases.MapUserToInnerAndSave(eh.Res, upd.SentFrom()); err != nil {
-		return
+	// Handling isn't bound to the fetcher's context, which waits for handlers to finish on shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	if eh.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), eh.Timeout)
 	}
-	eh.Res.Logger.Debug("user mapped to inner model and saved to DB", "user", usr)
+	defer cancel()
 
-	if tc, err = usecases.MapTgChatToInnerAndSave(eh.Res, upd.FromChat(), usr); err != nil {
+	// User and chat are saved together, so the chat never refers to a user that failed to save
+	var usr *usrPkg.User
+	err = eh.Res.UoW.Do(ctx, func(ctx context.Context) (err error) {
+		if usr, err = usecases.MapUserToInnerAndSave(ctx, eh.Res, upd.SentFrom()); err != nil {
+			return err
+		}
+		tc, err = usecases.MapTgChatToInnerAndSave(ctx, eh.Res, upd.FromChat(), usr)
+		return err
+	})
+	if err != nil {
 		return
 	}
-	eh.Res.Logger.Debug("telegram chat mapped to inner model and saved to DB", "tgChat", tc)
+	eh.Res.Logger.Debug("user and telegram chat mapped to inner model and saved to DB", "user", usr, "tgChat", tc)
 
-	if err = usecases.SendReplyMsg(eh.Res, tc, "Hello, "+usr.TgFirstName+"!\nPush \"Open\" button to start using bot"); err != nil {
-		return
+	switch {
+	case upd.CallbackQuery != nil:
+		err = eh.handleCallbackQuery(ctx, usr, tc, upd.CallbackQuery)
+	case upd.Message != nil:
+		err = eh.handleMessage(ctx, usr, tc, upd.Message)
 	}
 }
@@ -0,0 +1,394 @@
+package tgbot
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+const testUserTgID = 100
+
+func commandUpdate(text string) *tgbotapi.Update {
+	cmdLen := len(text)
+	if i := strings.Index(text, " "); i >= 0 {
+		cmdLen = i
+	}
+	return &tgbotapi.Update{Message: &tgbotapi.Message{
+		From:     &tgbotapi.User{ID: testUserTgID, FirstName: "Alex", UserName: "alex"},
+		Chat:     &tgbotapi.Chat{ID: testUserTgID},
+		Text:     text,
+		Entities: []tgbotapi.MessageEntity{{Type: "bot_command", Offset: 0, Length: cmdLen}},
+	}}
+}
+
+func textUpdate(text string) *tgbotapi.Update {
+	return &tgbotapi.Update{Message: &tgbotapi.Message{
+		From: &tgbotapi.User{ID: testUserTgID, FirstName: "Alex", UserName: "alex"},
+		Chat: &tgbotapi.Chat{ID: testUserTgID},
+		Text: text,
+	}}
+}
+
+func callbackUpdate(data string) *tgbotapi.Update {
+	return &tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:   "callback-id",
+		From: &tgbotapi.User{ID: testUserTgID, FirstName: "Alex", UserName: "alex"},
+		Message: &tgbotapi.Message{
+			MessageID: 42,
+			Chat:      &tgbotapi.Chat{ID: testUserTgID},
+		},
+		Data: data,
+	}}
+}
+
+func (env *testEnv) handle(t *testing.T, upd *tgbotapi.Update) {
+	t.Helper()
+
+	doneCh := make(chan string, 1)
+	EventHandler{Code: "test", Res: env.res}.Run(doneCh, upd)
+	if code := <-doneCh; code != "test" {
+		t.Fatalf("unexpected handler code %q", code)
+	}
+}
+
+// addHabit adds the habit of the test user, who gets ID 1 once the handler registers them
+func (env *testEnv) addHabit(title string) *hPkg.Habit {
+	return env.addHabitOf(1, title)
+}
+
+func (env *testEnv) addHabitOf(creatorID int64, title string) *hPkg.Habit {
+	h := hPkg.NewHabit(title, "", hPkg.Green, hPkg.DailySchedule(), nil, "", "", creatorID, false)
+	h.CreatedAt = time.Now().AddDate(0, 0, -10)
+	h.JoinedAt = h.CreatedAt
+	env.res.HabitRepo.Create(context.Background(), h)
+	return h
+}
+
+func (env *testEnv) check(hc *hPkg.HabitCheck) {
+	env.res.HabitRepo.SetUserHabitCheck(context.Background(), hc)
+}
+
+// checks returns all stored checks ordered by habit and date
+func (env *testEnv) checks() []*hPkg.HabitCheck {
+	keys := slices.SortedFunc(maps.Keys(env.db.Checks), func(a, b memdb.CheckKey) int {
+		return cmp.Or(cmp.Compare(a.HabitID, b.HabitID), cmp.Compare(a.UserID, b.UserID), strings.Compare(a.CheckDate, b.CheckDate))
+	})
+	checks := []*hPkg.HabitCheck{}
+	for _, k := range keys {
+		checks = append(checks, env.db.Checks[k])
+	}
+	return checks
+}
+
+func (env *testEnv) user(t *testing.T) *usrPkg.User {
+	t.Helper()
+
+	u, err := env.res.UsrRepo.GetByTgID(context.Background(), testUserTgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func (env *testEnv) lastSentMsg(t *testing.T) tgbotapi.MessageConfig {
+	t.Helper()
+
+	if len(env.bot.sent) == 0 {
+		t.Fatal("no messages sent")
+	}
+	msg, ok := env.bot.sent[len(env.bot.sent)-1].(tgbotapi.MessageConfig)
+	if !ok {
+		t.Fatalf("unexpected sent chattable %T", env.bot.sent[len(env.bot.sent)-1])
+	}
+	if msg.ChatID != testUserTgID {
+		t.Errorf("message sent to chat %d, want %d", msg.ChatID, testUserTgID)
+	}
+	return msg
+}
+
+func TestEventHandlerCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		upd      *tgbotapi.Update
+		wantText string
+	}{
+		{"start", commandUpdate("/start"), "Hello, Alex!"},
+		{"help", commandUpdate("/help"), "/habits - today's habits"},
+		{"unknown command", commandUpdate("/unknown arg"), "Unknown command /unknown"},
+		{"plain text", textUpdate("hi"), "Hello, Alex!"},
+		{"habits without habits", commandUpdate("/habits"), "No habits scheduled for today"},
+		{"stats without habits", commandUpdate("/stats"), "You have no habits yet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+
+			env.handle(t, tt.upd)
+
+			if msg := env.lastSentMsg(t); !strings.Contains(msg.Text, tt.wantText) {
+				t.Errorf("reply %q doesn't contain %q", msg.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestEventHandlerHabitsCommand(t *testing.T) {
+	env := newTestEnv()
+	env.addHabit("Read")
+	done := env.addHabit("Run")
+	archived := env.addHabit("Archived")
+	env.db.Habits[archived.ID].Archived = true
+	env.check(&hPkg.HabitCheck{HabitID: done.ID, UserID: 1, CheckDate: date.TodayIn(time.UTC), Completed: true})
+
+	env.handle(t, commandUpdate("/habits"))
+
+	msg := env.lastSentMsg(t)
+	kb, ok := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok {
+		t.Fatalf("unexpected reply markup %T", msg.ReplyMarkup)
+	}
+	if len(kb.InlineKeyboard) != 2 {
+		t.Fatalf("got %d keyboard rows, want 2", len(kb.InlineKeyboard))
+	}
+
+	want := []struct{ text, data string }{
+		{"⬜ Read", "check:1"},
+		{"✅ Run", "check:2"},
+	}
+	for i, w := range want {
+		btn := kb.InlineKeyboard[i][0]
+		if btn.Text != w.text || btn.CallbackData == nil || *btn.CallbackData != w.data {
+			t.Errorf("button %d is %q with data %v, want %q with data %q", i, btn.Text, btn.CallbackData, w.text, w.data)
+		}
+	}
+}
+
+func TestEventHandlerCheckCallback(t *testing.T) {
+	env := newTestEnv()
+	h := env.addHabit("Read")
+
+	// Checking off
+	env.handle(t, callbackUpdate("check:1"))
+
+	checks := env.checks()
+	if len(checks) != 1 || !checks[0].Completed || checks[0].HabitID != h.ID {
+		t.Fatalf("habit isn't checked off: %+v", checks)
+	}
+	if checks[0].CheckDate.Compare(date.TodayIn(time.UTC)) != 0 {
+		t.Errorf("habit checked off on %s, want today", checks[0].CheckDate)
+	}
+	if len(env.bot.requests) != 2 {
+		t.Fatalf("got %d requests, want callback answer and keyboard edit", len(env.bot.requests))
+	}
+	answer, ok := env.bot.requests[0].(tgbotapi.CallbackConfig)
+	if !ok || answer.CallbackQueryID != "callback-id" || answer.Text != "\"Read\" checked off" {
+		t.Errorf("unexpected callback answer %+v", env.bot.requests[0])
+	}
+	edit, ok := env.bot.requests[1].(tgbotapi.EditMessageReplyMarkupConfig)
+	if !ok || edit.MessageID != 42 || edit.ReplyMarkup.InlineKeyboard[0][0].Text != "✅ Read" {
+		t.Errorf("unexpected keyboard edit %+v", env.bot.requests[1])
+	}
+
+	// Unchecking
+	env.handle(t, callbackUpdate("check:1"))
+
+	if checks = env.checks(); len(checks) != 1 || checks[0].Completed {
+		t.Fatalf("habit isn't unchecked: %+v", checks)
+	}
+	if answer, _ := env.bot.requests[2].(tgbotapi.CallbackConfig); answer.Text != "\"Read\" unchecked" {
+		t.Errorf("unexpected callback answer %+v", env.bot.requests[2])
+	}
+}
+
+func TestEventHandlerCheckCallbackQuantitative(t *testing.T) {
+	env := newTestEnv()
+	h := env.addHabit("Water")
+	target := 2.0
+	env.db.Habits[h.ID].Target, env.db.Habits[h.ID].Unit = &target, "L"
+
+	env.handle(t, callbackUpdate("check:1"))
+
+	checks := env.checks()
+	if len(checks) != 1 {
+		t.Fatalf("got %d checks, want 1", len(checks))
+	}
+	hc := checks[0]
+	if !hc.Completed || hc.Amount == nil || *hc.Amount != target {
+		t.Errorf("habit isn't checked off with target amount: %+v", hc)
+	}
+}
+
+func TestEventHandlerInvalidCallbacks(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		wantAnswer string
+	}{
+		{"unknown action", "delete:1", "Unknown action"},
+		{"invalid habit ID", "check:abc", "Unknown action"},
+		{"unknown habit", "check:99", "Habit not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+
+			env.handle(t, callbackUpdate(tt.data))
+
+			if len(env.bot.requests) != 1 {
+				t.Fatalf("got %d requests, want 1", len(env.bot.requests))
+			}
+			if answer, _ := env.bot.requests[0].(tgbotapi.CallbackConfig); answer.Text != tt.wantAnswer {
+				t.Errorf("callback answered with %q, want %q", answer.Text, tt.wantAnswer)
+			}
+			if checks := env.checks(); len(checks) != 0 {
+				t.Errorf("unexpected checks %+v", checks)
+			}
+		})
+	}
+}
+
+func TestEventHandlerStatsCommand(t *testing.T) {
+	env := newTestEnv()
+	h := env.addHabit("Read")
+	today := date.TodayIn(time.UTC)
+	for i := 1; i <= 3; i++ {
+		env.check(&hPkg.HabitCheck{HabitID: h.ID, UserID: 1, CheckDate: today.AddDate(0, 0, -i), Completed: true})
+	}
+
+	env.handle(t, commandUpdate("/stats"))
+
+	msg := env.lastSentMsg(t)
+	for _, want := range []string{"Read", "Current streak: 3 days", "Longest streak: 3 days"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("reply %q doesn't contain %q", msg.Text, want)
+		}
+	}
+}
+
+func TestEventHandlerStartJoin(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		expiresIn  time.Duration
+		wantText   string
+		wantJoined bool
+	}{
+		{"valid invite", "valid", time.Hour, "You've joined \"Read\"", true},
+		{"expired invite", "expired", -time.Hour, "Couldn't join the habit: habit invite has expired", false},
+		{"unknown invite", "unknown", 0, "Couldn't join the habit: couldn't find habit invite", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+			h := env.addHabitOf(2, "Read") // Habit of another user
+			if tt.expiresIn != 0 {
+				env.res.HabitRepo.CreateInvite(context.Background(), &hPkg.Invite{Token: tt.token, HabitID: h.ID, CreatorID: 2, ExpiresAt: time.Now().Add(tt.expiresIn)})
+			}
+
+			env.handle(t, commandUpdate("/start "+hPkg.InviteStartParamPrefix+tt.token))
+
+			if msg := env.lastSentMsg(t); !strings.Contains(msg.Text, tt.wantText) {
+				t.Errorf("reply %q doesn't contain %q", msg.Text, tt.wantText)
+			}
+			if m, ok := env.db.Members[memdb.MemberKey{HabitID: h.ID, UserID: 1}]; (ok && m.Active) != tt.wantJoined {
+				t.Errorf("user joined is %t, want %t", ok && m.Active, tt.wantJoined)
+			}
+		})
+	}
+}
+
+func TestEventHandlerAccountDeletion(t *testing.T) {
+	env := newTestEnv()
+
+	env.handle(t, commandUpdate("/delete_account"))
+
+	msg := env.lastSentMsg(t)
+	kb, ok := msg.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok || len(kb.InlineKeyboard) != 1 || *kb.InlineKeyboard[0][0].CallbackData != deleteAccountCallbackData {
+		t.Fatalf("unexpected reply markup %+v", msg.ReplyMarkup)
+	}
+	if env.user(t).DeletionScheduledAt != nil {
+		t.Fatal("account deletion scheduled without confirmation")
+	}
+
+	// Confirming
+	env.handle(t, callbackUpdate(deleteAccountCallbackData))
+
+	if env.user(t).DeletionScheduledAt == nil {
+		t.Fatal("account deletion isn't scheduled")
+	}
+	if msg := env.lastSentMsg(t); !strings.Contains(msg.Text, "Your account will be deleted on") {
+		t.Errorf("unexpected reply %q", msg.Text)
+	}
+
+	// Cancelling
+	env.handle(t, commandUpdate("/cancel_deletion"))
+
+	if env.user(t).DeletionScheduledAt != nil {
+		t.Fatal("account deletion isn't cancelled")
+	}
+	if msg := env.lastSentMsg(t); !strings.Contains(msg.Text, "Account deletion cancelled") {
+		t.Errorf("unexpected reply %q", msg.Text)
+	}
+}
+
+func TestEventHandlerAccessTokens(t *testing.T) {
+	env := newTestEnv()
+
+	env.handle(t, commandUpdate("/tokens"))
+	if msg := env.lastSentMsg(t); !strings.Contains(msg.Text, "You have no access tokens") {
+		t.Errorf("unexpected reply %q", msg.Text)
+	}
+
+	env.handle(t, commandUpdate("/new_token admin Home automation"))
+	if msg := env.lastSentMsg(t); !strings.Contains(msg.Text, "Usage: /new_token") {
+		t.Errorf("unexpected reply %q to invalid scope", msg.Text)
+	}
+
+	env.handle(t, commandUpdate("/new_token checks Home automation"))
+	if len(env.db.AccessTokens) != 1 {
+		t.Fatalf("got %d tokens, want 1", len(env.db.AccessTokens))
+	}
+	token := env.db.AccessTokens[1]
+	if token.Name != "Home automation" || token.Scope != atPkg.Checks {
+		t.Errorf("unexpected token %+v", token)
+	}
+	msg := env.lastSentMsg(t)
+	secret := strings.Fields(strings.SplitN(msg.Text, "\n\n", 3)[1])[0]
+	if !strings.HasPrefix(secret, atPkg.Prefix) || atPkg.HashSecret(secret) != token.Hash {
+		t.Errorf("reply %q doesn't contain the token secret", msg.Text)
+	}
+
+	env.handle(t, commandUpdate("/tokens"))
+	if msg := env.lastSentMsg(t); !strings.Contains(msg.Text, "1. Home automation (checks)") || strings.Contains(msg.Text, secret) {
+		t.Errorf("unexpected reply %q", msg.Text)
+	}
+
+	env.handle(t, commandUpdate("/revoke_token 2"))
+	if msg := env.lastSentMsg(t); !strings.Contains(msg.Text, "Access token not found") {
+		t.Errorf("unexpected reply %q", msg.Text)
+	}
+
+	env.handle(t, commandUpdate("/revoke_token 1"))
+	if msg := env.lastSentMsg(t); !strings.Contains(msg.Text, "\"Home automation\" revoked") {
+		t.Errorf("unexpected reply %q", msg.Text)
+	}
+	if len(env.db.AccessTokens) != 0 {
+		t.Errorf("token isn't revoked")
+	}
+}
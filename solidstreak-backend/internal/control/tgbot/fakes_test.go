@@ -0,0 +1,75 @@
+package tgbot
+
+import (
+	"io"
+	"log/slog"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	atRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken/repo"
+	fRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship/repo"
+	hRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit/repo"
+	sessRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session/repo"
+	tcRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat/repo"
+	usrRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user/repo"
+)
+
+// fakeBotAPI records everything sent to Telegram
+type fakeBotAPI struct {
+	mu       sync.Mutex
+	sent     []tgbotapi.Chattable
+	requests []tgbotapi.Chattable
+}
+
+func (b *fakeBotAPI) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = append(b.sent, c)
+	return tgbotapi.Message{MessageID: len(b.sent)}, nil
+}
+
+func (b *fakeBotAPI) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.requests = append(b.requests, c)
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (b *fakeBotAPI) MakeRequest(endpoint string, params tgbotapi.Params) (*tgbotapi.APIResponse, error) {
+	return &tgbotapi.APIResponse{Ok: true}, nil
+}
+
+func (b *fakeBotAPI) GetUpdatesChan(tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel {
+	return make(chan tgbotapi.Update)
+}
+
+// testEnv runs the handler with the in-memory repositories, as HTTP tests do, and the fake bot
+type testEnv struct {
+	bot *fakeBotAPI
+	db  *memdb.DB
+	res resources.Resources
+}
+
+func newTestEnv() *testEnv {
+	db := memdb.New()
+	env := &testEnv{
+		bot: &fakeBotAPI{},
+		db:  db,
+	}
+	env.res = resources.Resources{
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+		TgBotAPI:    env.bot,
+		UoW:         uow.InitMemory(),
+		UsrRepo:     usrRepo.InitMemory(db),
+		TCRepo:      tcRepo.InitMemory(db),
+		HabitRepo:   hRepo.InitMemory(db),
+		FriendRepo:  fRepo.InitMemory(db),
+		TokenRepo:   atRepo.InitMemory(db),
+		SessionRepo: sessRepo.InitMemory(db),
+	}
+	return env
+}
@@ -0,0 +1,111 @@
+package tgbot
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+)
+
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+
+	webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+)
+
+// Telegram allows 1-256 characters A-Z, a-z, 0-9, _ and - in webhook secret tokens
+var webhookSecretRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// Webhook receives updates pushed by Telegram to the web server and passes them to the event
+// fetcher. Requests are authenticated by the secret token Telegram sends with every update
+type Webhook struct {
+	URL            *url.URL
+	Secret         string
+	KeepOnShutdown bool // Leaves the webhook registered on shutdown, e.g. while other replicas keep running
+	upds           chan tgbotapi.Update
+	closed         chan struct{}
+	closeOnce      sync.Once
+}
+
+func NewWebhook(rawURL, secret string, bufSize int, keepOnShutdown bool) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, errors.New("invalid telegram webhook URL, absolute https URL expected")
+	}
+	if !webhookSecretRegexp.MatchString(secret) {
+		return nil, errors.New("invalid telegram webhook secret, 1-256 characters A-Z, a-z, 0-9, _ and - expected")
+	}
+
+	return &Webhook{
+		URL:            u,
+		Secret:         secret,
+		KeepOnShutdown: keepOnShutdown,
+		upds:           make(chan tgbotapi.Update, bufSize),
+		closed:         make(chan struct{}),
+	}, nil
+}
+
+// Path returns the web server route Telegram pushes updates to
+func (wh *Webhook) Path() string {
+	if wh.URL.Path == "" {
+		return "/"
+	}
+	return wh.URL.Path
+}
+
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get(webhookSecretHeader)), []byte(wh.Secret)) != 1 {
+		http.Error(w, "invalid secret token", http.StatusUnauthorized)
+		return
+	}
+
+	var upd tgbotapi.Update
+	if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+		http.Error(w, "invalid update", http.StatusBadRequest)
+		return
+	}
+
+	// Waiting for the event fetcher to take the update, Telegram redelivers it if it's not accepted
+	select {
+	case wh.upds <- upd:
+		w.WriteHeader(http.StatusOK)
+	case <-wh.closed:
+		http.Error(w, "bot is shutting down", http.StatusServiceUnavailable)
+	case <-r.Context().Done():
+	}
+}
+
+func (wh *Webhook) updates() tgbotapi.UpdatesChannel {
+	return wh.upds
+}
+
+// close stops accepting updates
+func (wh *Webhook) close() {
+	wh.closeOnce.Do(func() { close(wh.closed) })
+}
+
+func (wh *Webhook) register(res resources.Resources) error {
+	params := tgbotapi.Params{}
+	params["url"] = wh.URL.String()
+	params["secret_token"] = wh.Secret
+	params.AddNonZero("max_connections", min(cap(wh.upds), 100))
+	if err := params.AddInterface("allowed_updates", []string{"message", "callback_query"}); err != nil {
+		return err
+	}
+
+	_, err := res.TgBotAPI.MakeRequest("setWebhook", params)
+	return err
+}
+
+func deleteWebhook(res resources.Resources) error {
+	_, err := res.TgBotAPI.Request(tgbotapi.DeleteWebhookConfig{})
+	return err
+}
@@ -0,0 +1,86 @@
+package accesstoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Scope limits what requests authenticated by the token are allowed to do. Scopes don't include
+// each other, except the full one including all others
+type Scope string
+
+const (
+	Read   Scope = "read"   // Viewing habits, checks and friends, but not exporting all data
+	Checks Scope = "checks" // Recording habit checks only, without viewing anything
+	Full   Scope = "full"   // Everything but managing access tokens and deleting the account
+)
+
+var ScopeMapping = map[string]Scope{
+	string(Read):   Read,
+	string(Checks): Checks,
+	string(Full):   Full,
+}
+
+// Allows tells if the scope includes the required one
+func (s Scope) Allows(required Scope) bool {
+	return s == Full || s == required
+}
+
+// Prefix of token secrets, which tells them apart from session tokens
+const Prefix = "sst_"
+
+// MaxPerUser is the number of access tokens a user could have
+const MaxPerUser = 20
+
+const maxNameLen = 64
+
+// Token is the user's personal access token for scripts and integrations. Its secret is shown
+// once on creation, only its hash is stored
+type Token struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"userId"`
+	Name      string    `json:"name"`
+	Scope     Scope     `json:"scope"`
+	Hash      string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// New returns the token along with its secret
+func New(userID int64, name string, scope Scope) (*Token, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := Prefix + base64.RawURLEncoding.EncodeToString(b)
+
+	return &Token{
+		UserID:    userID,
+		Name:      name,
+		Scope:     scope,
+		Hash:      HashSecret(secret),
+		CreatedAt: time.Now(),
+	}, secret, nil
+}
+
+// HashSecret returns the hash the token with the secret is stored by. Secrets are random, so
+// a plain SHA-256 is enough to keep them unrecoverable
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func ValidateName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("access token name is required")
+	}
+	if utf8.RuneCountInString(name) > maxNameLen {
+		return errors.New("access token name is too long")
+	}
+	return nil
+}
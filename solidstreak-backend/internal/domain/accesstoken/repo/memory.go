@@ -0,0 +1,77 @@
+package repo
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// memRepo keeps access tokens in memory with the same semantics as pgRepo
+type memRepo struct {
+	db *memdb.DB
+}
+
+func initMemRepo(db *memdb.DB) *memRepo {
+	return &memRepo{db}
+}
+
+func (r memRepo) Create(ctx context.Context, t *atPkg.Token) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	t.ID = r.db.NextID("access_tokens")
+	copied := *t
+	r.db.AccessTokens[t.ID] = &copied
+
+	return nil
+}
+
+func (r memRepo) GetByHash(ctx context.Context, hash string) (*atPkg.Token, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	for _, t := range r.db.AccessTokens {
+		if t.Hash == hash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+
+	return nil, apperrors.ErrNotFound("couldn't find access token")
+}
+
+func (r memRepo) GetByUserID(ctx context.Context, userID int64) ([]*atPkg.Token, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	tokens := []*atPkg.Token{}
+	for _, t := range r.db.AccessTokens {
+		if t.UserID != userID {
+			continue
+		}
+		copied := *t
+		tokens = append(tokens, &copied)
+	}
+	slices.SortFunc(tokens, func(a, b *atPkg.Token) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.ID, a.ID))
+	})
+
+	return tokens, nil
+}
+
+func (r memRepo) Delete(ctx context.Context, userID, id int64) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	t, ok := r.db.AccessTokens[id]
+	if !ok || t.UserID != userID {
+		return apperrors.ErrNotFound("couldn't find access token")
+	}
+	delete(r.db.AccessTokens, id)
+
+	return nil
+}
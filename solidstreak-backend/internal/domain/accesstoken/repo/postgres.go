@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+type pgRepo struct {
+	pool *pgxpool.Pool
+}
+
+func initPGRepo(p *pgxpool.Pool) *pgRepo {
+	return &pgRepo{p}
+}
+
+func (r pgRepo) Create(ctx context.Context, t *atPkg.Token) error {
+	sql := `
+		INSERT INTO access_tokens (user_id, name, scope, hash, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
+		sql,
+		t.UserID,
+		t.Name,
+		t.Scope,
+		t.Hash,
+		t.CreatedAt,
+	).Scan(&t.ID)
+
+	return err
+}
+
+func (r pgRepo) GetByHash(ctx context.Context, hash string) (*atPkg.Token, error) {
+	sql := `
+		SELECT id, user_id, name, scope, hash, created_at
+		FROM access_tokens
+		WHERE hash = $1
+	`
+	t := &atPkg.Token{}
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
+		sql,
+		hash,
+	).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Name,
+		&t.Scope,
+		&t.Hash,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apperrors.ErrNotFound("couldn't find access token")
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// GetByUserID returns user's access tokens, the latest first
+func (r pgRepo) GetByUserID(ctx context.Context, userID int64) ([]*atPkg.Token, error) {
+	sql := `
+		SELECT id, user_id, name, scope, hash, created_at
+		FROM access_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+	rows, err := uow.Conn(ctx, r.pool).Query(ctx, sql, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*atPkg.Token{}
+	for rows.Next() {
+		t := &atPkg.Token{}
+		err = rows.Scan(
+			&t.ID,
+			&t.UserID,
+			&t.Name,
+			&t.Scope,
+			&t.Hash,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return tokens, nil
+}
+
+// Delete revokes the user's access token
+func (r pgRepo) Delete(ctx context.Context, userID, id int64) error {
+	sql := `DELETE FROM access_tokens WHERE id = $1 AND user_id = $2`
+	tag, err := uow.Conn(ctx, r.pool).Exec(
+		ctx,
+		sql,
+		id,
+		userID,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return apperrors.ErrNotFound("couldn't find access token")
+	}
+
+	return nil
+}
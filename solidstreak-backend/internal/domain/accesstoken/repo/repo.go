@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	Create(context.Context, *atPkg.Token) error
+	GetByHash(context.Context, string) (*atPkg.Token, error)
+	GetByUserID(context.Context, int64) ([]*atPkg.Token, error)
+	Delete(ctx context.Context, userID, id int64) error
+}
+
+func Init(p *pgxpool.Pool) Repo {
+	return initPGRepo(p)
+}
+
+// InitMemory returns the repository keeping data in the in-memory storage instead of PostgreSQL
+func InitMemory(db *memdb.DB) Repo {
+	return initMemRepo(db)
+}
@@ -0,0 +1,62 @@
+package friendship
+
+import "time"
+
+type Status string
+
+const (
+	Pending  Status = "pending"
+	Accepted Status = "accepted"
+	Blocked  Status = "blocked"
+)
+
+var StatusMapping = map[string]Status{
+	string(Pending):  Pending,
+	string(Accepted): Accepted,
+	string(Blocked):  Blocked,
+}
+
+// Friendship is the relationship between two users. It's requested by the requester and
+// accepted by the addressee. The requester of blocked relationship is the user who blocked
+// the other one, only they could remove it
+type Friendship struct {
+	RequesterID int64     `json:"requesterId"`
+	AddresseeID int64     `json:"addresseeId"`
+	Status      Status    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func NewRequest(requesterID, addresseeID int64) *Friendship {
+	now := time.Now()
+	return &Friendship{
+		RequesterID: requesterID,
+		AddresseeID: addresseeID,
+		Status:      Pending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+func NewBlock(blockerID, blockedID int64) *Friendship {
+	f := NewRequest(blockerID, blockedID)
+	f.Status = Blocked
+	return f
+}
+
+// OtherID returns ID of the user related to the specified one
+func (f *Friendship) OtherID(userID int64) int64 {
+	if f.RequesterID == userID {
+		return f.AddresseeID
+	}
+	return f.RequesterID
+}
+
+func (f *Friendship) IsIncomingFor(userID int64) bool {
+	return f.AddresseeID == userID
+}
+
+func (f *Friendship) Accept() {
+	f.Status = Accepted
+	f.UpdatedAt = time.Now()
+}
@@ -0,0 +1,70 @@
+package repo
+
+import (
+	"context"
+	"slices"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	fPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// memRepo keeps relationships in memory with the same semantics as pgRepo
+type memRepo struct {
+	db *memdb.DB
+}
+
+func initMemRepo(db *memdb.DB) *memRepo {
+	return &memRepo{db}
+}
+
+func (r memRepo) Get(ctx context.Context, userID, otherID int64) (*fPkg.Friendship, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	f, ok := r.db.Friendships[memdb.NewPairKey(userID, otherID)]
+	if !ok {
+		return nil, apperrors.ErrNotFound("couldn't find relationship with specified user")
+	}
+
+	copied := *f
+	return &copied, nil
+}
+
+func (r memRepo) Save(ctx context.Context, f *fPkg.Friendship) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	copied := *f
+	r.db.Friendships[memdb.NewPairKey(f.RequesterID, f.AddresseeID)] = &copied
+
+	return nil
+}
+
+func (r memRepo) Delete(ctx context.Context, userID, otherID int64) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	delete(r.db.Friendships, memdb.NewPairKey(userID, otherID))
+
+	return nil
+}
+
+func (r memRepo) GetByUserIDAndStatus(ctx context.Context, userID int64, status fPkg.Status) ([]*fPkg.Friendship, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	friendships := []*fPkg.Friendship{}
+	for _, f := range r.db.Friendships {
+		if f.Status != status || !(f.RequesterID == userID || (f.AddresseeID == userID && f.Status != fPkg.Blocked)) {
+			continue
+		}
+		copied := *f
+		friendships = append(friendships, &copied)
+	}
+	slices.SortFunc(friendships, func(a, b *fPkg.Friendship) int {
+		return b.UpdatedAt.Compare(a.UpdatedAt)
+	})
+
+	return friendships, nil
+}
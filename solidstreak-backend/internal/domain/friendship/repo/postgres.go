@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	fPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+type pgRepo struct {
+	pool *pgxpool.Pool
+}
+
+func initPGRepo(p *pgxpool.Pool) *pgRepo {
+	return &pgRepo{p}
+}
+
+// Get returns the relationship between two users regardless of its direction
+func (r pgRepo) Get(ctx context.Context, userID, otherID int64) (*fPkg.Friendship, error) {
+	sql := `
+		SELECT requester_id, addressee_id, status, created_at, updated_at
+		FROM friendships
+		WHERE
+			(requester_id = $1 AND addressee_id = $2)
+			OR (requester_id = $2 AND addressee_id = $1)
+	`
+	f := &fPkg.Friendship{}
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
+		sql,
+		userID,
+		otherID,
+	).Scan(
+		&f.RequesterID,
+		&f.AddresseeID,
+		&f.Status,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apperrors.ErrNotFound("couldn't find relationship with specified user")
+		}
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Save creates or replaces the relationship between two users, a pair of users has a single one
+func (r pgRepo) Save(ctx context.Context, f *fPkg.Friendship) error {
+	sql := `
+		INSERT INTO friendships (requester_id, addressee_id, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT ((LEAST(requester_id, addressee_id)), (GREATEST(requester_id, addressee_id))) DO UPDATE SET
+			requester_id = EXCLUDED.requester_id,
+			addressee_id = EXCLUDED.addressee_id,
+			status = EXCLUDED.status,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := uow.Conn(ctx, r.pool).Exec(
+		ctx,
+		sql,
+		f.RequesterID,
+		f.AddresseeID,
+		f.Status,
+		f.CreatedAt,
+		f.UpdatedAt,
+	)
+
+	return err
+}
+
+func (r pgRepo) Delete(ctx context.Context, userID, otherID int64) error {
+	sql := `
+		DELETE FROM friendships
+		WHERE
+			(requester_id = $1 AND addressee_id = $2)
+			OR (requester_id = $2 AND addressee_id = $1)
+	`
+	_, err := uow.Conn(ctx, r.pool).Exec(
+		ctx,
+		sql,
+		userID,
+		otherID,
+	)
+
+	return err
+}
+
+// GetByUserIDAndStatus returns user's relationships with specified status. Blocked ones are
+// returned only if blocked by the user
+func (r pgRepo) GetByUserIDAndStatus(ctx context.Context, userID int64, status fPkg.Status) ([]*fPkg.Friendship, error) {
+	sql := `
+		SELECT requester_id, addressee_id, status, created_at, updated_at
+		FROM friendships
+		WHERE
+			status = $2
+			AND (requester_id = $1 OR (addressee_id = $1 AND status <> 'blocked'))
+		ORDER BY updated_at DESC
+	`
+	rows, err := uow.Conn(ctx, r.pool).Query(ctx, sql, userID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	friendships := []*fPkg.Friendship{}
+	for rows.Next() {
+		f := &fPkg.Friendship{}
+		err = rows.Scan(
+			&f.RequesterID,
+			&f.AddresseeID,
+			&f.Status,
+			&f.CreatedAt,
+			&f.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		friendships = append(friendships, f)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return friendships, nil
+}
@@ -0,0 +1,25 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	fPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/friendship"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	Get(ctx context.Context, userID, otherID int64) (*fPkg.Friendship, error)
+	Save(context.Context, *fPkg.Friendship) error
+	Delete(ctx context.Context, userID, otherID int64) error
+	GetByUserIDAndStatus(context.Context, int64, fPkg.Status) ([]*fPkg.Friendship, error)
+}
+
+func Init(p *pgxpool.Pool) Repo {
+	return initPGRepo(p)
+}
+
+// InitMemory returns the repository keeping data in the in-memory storage instead of PostgreSQL
+func InitMemory(db *memdb.DB) Repo {
+	return initMemRepo(db)
+}
@@ -0,0 +1,54 @@
+package habit
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+// Codes of check date validation errors, which let clients tell rejection reasons apart
+const (
+	CheckDateInFuture       = "check_date_in_future"
+	CheckDateBeforeCreation = "check_date_before_creation"
+	CheckDateOutOfBackfill  = "check_date_out_of_backfill"
+)
+
+// CheckDateError is the rejection of the date a check is recorded for
+type CheckDateError struct {
+	Code    string
+	Message string
+}
+
+func (e CheckDateError) Error() string {
+	return e.Message
+}
+
+// CheckDatePolicy limits the dates checks could be recorded for
+type CheckDatePolicy struct {
+	BackfillDays int  // How many days before today checks could be recorded for
+	Import       bool // Imported history is limited by today only, since it predates habits creation in the app
+}
+
+// ValidateCheckDate returns CheckDateError if the check couldn't be recorded for the date. Today and
+// habit's creation date are taken in the user's time zone
+func (h *Habit) ValidateCheckDate(checkDate, today date.Date, loc *time.Location, p CheckDatePolicy) error {
+	if checkDate.After(today) {
+		return CheckDateError{Code: CheckDateInFuture, Message: "habit check date couldn't be in the future"}
+	}
+	if p.Import {
+		return nil
+	}
+
+	if checkDate.Before(date.NewIn(h.CreatedAt, loc)) {
+		return CheckDateError{Code: CheckDateBeforeCreation, Message: "habit check date couldn't be before habit creation"}
+	}
+	if checkDate.Before(today.AddDate(0, 0, -p.BackfillDays)) {
+		return CheckDateError{
+			Code:    CheckDateOutOfBackfill,
+			Message: "habit could be checked for the last " + strconv.Itoa(p.BackfillDays) + " days only",
+		}
+	}
+
+	return nil
+}
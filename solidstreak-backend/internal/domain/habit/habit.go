@@ -1,23 +1,33 @@
 package habit
 
 import (
+	"errors"
 	"time"
+	"unicode/utf8"
 
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
 )
 
 type Habit struct {
-	ID          int64         `json:"id"`
-	Active      bool          `json:"active"`
-	Archived    bool          `json:"archived"`
-	Title       string        `json:"title"`
-	Description string        `json:"description"`
-	Color       Color         `json:"color"`
-	CreatorID   int64         `json:"creatorId"`
-	IsPublic    bool          `json:"isPublic"`
-	CreatedAt   time.Time     `json:"createdAt"`
-	UpdatedAt   time.Time     `json:"updatedAt"`
-	Checks      []*HabitCheck `json:"checks"`
+	ID           int64         `json:"id"`
+	Active       bool          `json:"active"`
+	Archived     bool          `json:"archived"`
+	Title        string        `json:"title"`
+	Description  string        `json:"description"`
+	Color        Color         `json:"color"`
+	Schedule     Schedule      `json:"schedule"`
+	Target       *float64      `json:"target,omitempty"` // Amount to reach in a check, only for quantitative habits
+	Unit         string        `json:"unit,omitempty"`
+	ReminderTime string        `json:"reminderTime,omitempty"` // "HH:MM" in each participant's time zone, empty if reminder is off
+	CreatorID    int64         `json:"creatorId"`
+	IsPublic     bool          `json:"isPublic"` // Visibility in the participant's profile
+	JoinedAt     time.Time     `json:"joinedAt"` // When the participant joined, creation time for the creator
+	CreatedAt    time.Time     `json:"createdAt"`
+	UpdatedAt    time.Time     `json:"updatedAt"`
+	ArchivedAt   *time.Time    `json:"archivedAt,omitempty"` // When the habit was archived, set for archived habits only
+	DeletedAt    *time.Time    `json:"deletedAt,omitempty"`  // When the habit was moved to the trash, set for habits from the trash only
+	Checks       []*HabitCheck `json:"checks"`
+	Stats        *Stats        `json:"stats,omitempty"`
 }
 
 type HabitStatus string
@@ -61,19 +71,144 @@ type HabitCheck struct {
 	UserID    int64     `json:"-"`
 	CheckDate date.Date `json:"checkDate"`
 	Completed bool      `json:"completed"`
+	Amount    *float64  `json:"amount,omitempty"` // Recorded amount, only for quantitative habits
 	CheckedAt time.Time `json:"checkedAt"`
 }
 
-func NewHabit(title, description string, color Color, creatorID int64, isPublic bool) *Habit {
+// Reminder is a habit reminder due to be sent to the user on the specified date
+type Reminder struct {
+	Habit  *Habit
+	UserID int64
+	Date   date.Date
+}
+
+func NewHabit(title, description string, color Color, schedule Schedule, target *float64, unit, reminderTime string, creatorID int64, isPublic bool) *Habit {
+	now := time.Now()
 	return &Habit{
-		Active:      true,
-		Archived:    false,
-		Title:       title,
-		Description: description,
-		Color:       color,
-		IsPublic:    isPublic,
-		CreatorID:   creatorID,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		Active:       true,
+		Archived:     false,
+		Title:        title,
+		Description:  description,
+		Color:        color,
+		Schedule:     schedule,
+		Target:       target,
+		Unit:         unit,
+		ReminderTime: reminderTime,
+		IsPublic:     isPublic,
+		CreatorID:    creatorID,
+		JoinedAt:     now,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+func ValidateTarget(target *float64, unit string) error {
+	if target == nil {
+		if unit != "" {
+			return errors.New("habit unit is allowed only for habits with target")
+		}
+		return nil
+	}
+	if *target <= 0 {
+		return errors.New("habit target must be positive")
+	}
+	if utf8.RuneCountInString(unit) > 32 {
+		return errors.New("habit unit must be at most 32 characters long")
+	}
+	return nil
+}
+
+func ValidateReminderTime(reminderTime string) error {
+	if reminderTime == "" {
+		return nil
+	}
+	if _, err := time.Parse("15:04", reminderTime); err != nil {
+		return errors.New("habit reminder time must be in HH:MM format")
+	}
+	return nil
+}
+
+// SetArchived archives the habit or returns it from the archive. Archiving time is kept as is
+// unless the habit's status changes
+func (h *Habit) SetArchived(archived bool) {
+	if h.Archived == archived {
+		return
+	}
+	h.Archived = archived
+	if archived {
+		now := time.Now()
+		h.ArchivedAt = &now
+	} else {
+		h.ArchivedAt = nil
+	}
+}
+
+// Trash deletes the habit, it's kept in the trash until it's restored or purged
+func (h *Habit) Trash() {
+	now := time.Now()
+	h.Active = false
+	h.DeletedAt = &now
+	h.UpdatedAt = now
+}
+
+// Restore returns the habit from the trash
+func (h *Habit) Restore() {
+	h.Active = true
+	h.DeletedAt = nil
+	h.UpdatedAt = time.Now()
+}
+
+func (h *Habit) IsQuantitative() bool {
+	return h.Target != nil
+}
+
+func (h *Habit) IsCreator(userID int64) bool {
+	return h.CreatorID == userID
+}
+
+// HasSameSettings reports whether habits have equal settings shared by all participants,
+// which only the creator is allowed to change
+func (h *Habit) HasSameSettings(o *Habit) bool {
+	return h.Archived == o.Archived &&
+		h.Title == o.Title &&
+		h.Description == o.Description &&
+		h.Color == o.Color &&
+		h.Schedule.Equal(o.Schedule) &&
+		(h.Target == nil) == (o.Target == nil) &&
+		(h.Target == nil || *h.Target == *o.Target) &&
+		h.Unit == o.Unit &&
+		h.ReminderTime == o.ReminderTime
+}
+
+// NewCheck creates user's check of the habit for the specified date. Completion of
+// quantitative habits is derived from the recorded amount and their target
+func (h *Habit) NewCheck(userID int64, checkDate date.Date, completed *bool, amount *float64) (*HabitCheck, error) {
+	hc := &HabitCheck{
+		HabitID:   h.ID,
+		UserID:    userID,
+		CheckDate: checkDate,
+		CheckedAt: time.Now(),
+	}
+
+	if h.IsQuantitative() {
+		if amount == nil {
+			return nil, errors.New("habit check amount is required")
+		}
+		if *amount < 0 {
+			return nil, errors.New("habit check amount couldn't be negative")
+		}
+		hc.Amount = amount
+		hc.Completed = *amount >= *h.Target
+		return hc, nil
 	}
+
+	if amount != nil {
+		return nil, errors.New("habit check amount is allowed only for habits with target")
+	}
+	if completed == nil {
+		return nil, errors.New("habit completion status is required")
+	}
+	hc.Completed = *completed
+
+	return hc, nil
 }
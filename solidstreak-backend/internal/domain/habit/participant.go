@@ -0,0 +1,65 @@
+package habit
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+// InviteStartParamPrefix prefixes invite tokens in bot and Mini App deep links start parameters
+const InviteStartParamPrefix = "join_"
+
+// Participant is a user sharing the habit
+type Participant struct {
+	UserID      int64     `json:"userId"`
+	TgUsername  string    `json:"tgUsername"`
+	TgFirstName string    `json:"tgFirstName"`
+	TgLastName  string    `json:"tgLastName"`
+	IsCreator   bool      `json:"isCreator"`
+	JoinedAt    time.Time `json:"joinedAt"`
+}
+
+// Membership is user's participation in the habit, kept after leaving it
+type Membership struct {
+	HabitID  int64     `json:"habitId"`
+	UserID   int64     `json:"userId"`
+	Active   bool      `json:"active"`
+	IsPublic bool      `json:"isPublic"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+// Invite allows any user having its token to join the habit until it expires
+type Invite struct {
+	Token     string    `json:"token"`
+	HabitID   int64     `json:"habitId"`
+	CreatorID int64     `json:"creatorId"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func NewInvite(habitID, creatorID int64, ttl time.Duration) (*Invite, error) {
+	// Token fits Telegram deep links start parameter: up to 64 characters A-Z, a-z, 0-9, _ and -
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &Invite{
+		Token:     base64.RawURLEncoding.EncodeToString(b),
+		HabitID:   habitID,
+		CreatorID: creatorID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}, nil
+}
+
+func (i *Invite) IsExpired() bool {
+	return time.Now().After(i.ExpiresAt)
+}
+
+// StartParam returns start parameter of bot and Mini App deep links
+func (i *Invite) StartParam() string {
+	return InviteStartParamPrefix + i.Token
+}
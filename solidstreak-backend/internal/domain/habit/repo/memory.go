@@ -0,0 +1,451 @@
+package repo
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// memRepo keeps habits in memory with the same semantics as pgRepo
+type memRepo struct {
+	db *memdb.DB
+}
+
+func initMemRepo(db *memdb.DB) *memRepo {
+	return &memRepo{db}
+}
+
+// memberHabit returns a copy of the habit with the participant's settings, as the queries joining
+// habits with users_habits do
+func memberHabit(h *hPkg.Habit, m *memdb.Member) *hPkg.Habit {
+	copied := *h
+	copied.Schedule.Weekdays = slices.Clone(h.Schedule.Weekdays)
+	if h.Target != nil {
+		target := *h.Target
+		copied.Target = &target
+	}
+	if h.ArchivedAt != nil {
+		archivedAt := *h.ArchivedAt
+		copied.ArchivedAt = &archivedAt
+	}
+	copied.IsPublic = m.IsPublic
+	copied.JoinedAt = m.JoinedAt
+	copied.DeletedAt = nil
+	copied.Checks = nil
+	copied.Stats = nil
+	return &copied
+}
+
+func copyCheck(hc *hPkg.HabitCheck) *hPkg.HabitCheck {
+	copied := *hc
+	if hc.Amount != nil {
+		amount := *hc.Amount
+		copied.Amount = &amount
+	}
+	return &copied
+}
+
+func (r memRepo) Create(ctx context.Context, h *hPkg.Habit) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	r.create(h)
+
+	return nil
+}
+
+func (r memRepo) create(h *hPkg.Habit) {
+	h.ID = r.db.NextID("habits")
+	r.db.Habits[h.ID] = memberHabit(h, &memdb.Member{})
+	r.db.Members[memdb.MemberKey{HabitID: h.ID, UserID: h.CreatorID}] = &memdb.Member{
+		Active:   true,
+		IsPublic: h.IsPublic,
+		JoinedAt: h.JoinedAt,
+	}
+}
+
+func (r memRepo) Update(ctx context.Context, h *hPkg.Habit) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	stored, ok := r.db.Habits[h.ID]
+	if !ok {
+		return nil
+	}
+
+	updated := memberHabit(h, &memdb.Member{})
+	updated.CreatorID = stored.CreatorID
+	updated.CreatedAt = stored.CreatedAt
+	if !h.Active {
+		updated.DeletedAt = stored.DeletedAt
+		if updated.DeletedAt == nil {
+			deletedAt := h.UpdatedAt
+			updated.DeletedAt = &deletedAt
+		}
+	}
+	r.db.Habits[h.ID] = updated
+
+	return nil
+}
+
+func (r memRepo) GetByOwnerIDAndStatus(ctx context.Context, ownerID int64, status hPkg.HabitStatus, requestedByOwner bool) ([]*hPkg.Habit, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	habits := []*hPkg.Habit{}
+	for k, m := range r.db.Members {
+		h := r.db.Habits[k.HabitID]
+		if k.UserID != ownerID || !m.Active || !h.Active || (!requestedByOwner && !m.IsPublic) {
+			continue
+		}
+		if (status == hPkg.Active && h.Archived) || (status == hPkg.Archived && !h.Archived) {
+			continue
+		}
+		habits = append(habits, memberHabit(h, m))
+	}
+	slices.SortFunc(habits, func(a, b *hPkg.Habit) int { return cmp.Compare(a.ID, b.ID) })
+
+	return habits, nil
+}
+
+func (r memRepo) GetByIDAndOwnerID(ctx context.Context, id int64, ownerID int64, requestedByOwner bool) (*hPkg.Habit, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	h, ok := r.db.Habits[id]
+	m, isMember := r.db.Members[memdb.MemberKey{HabitID: id, UserID: ownerID}]
+	if !ok || !isMember || !m.Active || (!requestedByOwner && !m.IsPublic) {
+		return nil, apperrors.ErrNotFound("couldn't find habit for specified user")
+	}
+
+	return memberHabit(h, m), nil
+}
+
+func (r memRepo) ForEachByMemberID(ctx context.Context, userID int64, fn func(*hPkg.Habit) error) error {
+	r.db.Lock()
+	habits := []*hPkg.Habit{}
+	for k, m := range r.db.Members {
+		if k.UserID == userID {
+			habits = append(habits, memberHabit(r.db.Habits[k.HabitID], m))
+		}
+	}
+	r.db.Unlock()
+	slices.SortFunc(habits, func(a, b *hPkg.Habit) int { return cmp.Compare(a.ID, b.ID) })
+
+	return forEach(habits, fn)
+}
+
+func (r memRepo) GetTrashedByCreatorID(ctx context.Context, creatorID int64) ([]*hPkg.Habit, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	habits := []*hPkg.Habit{}
+	for id, h := range r.db.Habits {
+		m, ok := r.db.Members[memdb.MemberKey{HabitID: id, UserID: creatorID}]
+		if h.Active || h.CreatorID != creatorID || !ok {
+			continue
+		}
+		trashed := memberHabit(h, m)
+		deletedAt := *h.DeletedAt
+		trashed.DeletedAt = &deletedAt
+		habits = append(habits, trashed)
+	}
+	slices.SortFunc(habits, func(a, b *hPkg.Habit) int {
+		return cmp.Or(b.DeletedAt.Compare(*a.DeletedAt), cmp.Compare(b.ID, a.ID))
+	})
+
+	return habits, nil
+}
+
+func (r memRepo) PurgeTrashed(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	trashed := []*hPkg.Habit{}
+	for _, h := range r.db.Habits {
+		if !h.Active && h.DeletedAt != nil && !h.DeletedAt.After(deletedBefore) {
+			trashed = append(trashed, h)
+		}
+	}
+	slices.SortFunc(trashed, func(a, b *hPkg.Habit) int { return a.DeletedAt.Compare(*b.DeletedAt) })
+	if len(trashed) > limit {
+		trashed = trashed[:limit]
+	}
+
+	for _, h := range trashed {
+		for token, i := range r.db.Invites {
+			if i.HabitID == h.ID {
+				delete(r.db.Invites, token)
+			}
+		}
+		for k := range r.db.Checks {
+			if k.HabitID == h.ID {
+				delete(r.db.Checks, k)
+			}
+		}
+		for k := range r.db.Members {
+			if k.HabitID == h.ID {
+				delete(r.db.Members, k)
+			}
+		}
+		delete(r.db.Habits, h.ID)
+	}
+
+	return len(trashed), nil
+}
+
+func (r memRepo) ClaimDueReminders(ctx context.Context, now time.Time) ([]*hPkg.Reminder, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	reminders := []*hPkg.Reminder{}
+	for k, m := range r.db.Members {
+		h := r.db.Habits[k.HabitID]
+		u, ok := r.db.Users[k.UserID]
+		if !ok || !m.Active || !h.Active || h.Archived || h.ReminderTime == "" {
+			continue
+		}
+
+		local := now.In(u.Location())
+		localDate := date.New(local)
+		if local.Format("15:04") < h.ReminderTime || m.ReminderSentOn >= localDate.String() {
+			continue
+		}
+
+		m.ReminderSentOn = localDate.String()
+		reminders = append(reminders, &hPkg.Reminder{Habit: memberHabit(h, m), UserID: k.UserID, Date: localDate})
+	}
+
+	return reminders, nil
+}
+
+func (r memRepo) SetUserHabitCheck(ctx context.Context, hc *hPkg.HabitCheck) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	r.db.Checks[memdb.NewCheckKey(hc)] = copyCheck(hc)
+
+	return nil
+}
+
+func (r memRepo) RecalcChecksCompletion(ctx context.Context, h *hPkg.Habit) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	for k, hc := range r.db.Checks {
+		if k.HabitID == h.ID && hc.Amount != nil {
+			hc.Completed = h.Target != nil && *hc.Amount >= *h.Target
+		}
+	}
+
+	return nil
+}
+
+func (r memRepo) GetUserHabitsCompletedChecks(ctx context.Context, userID int64, habitIDs []int64, from, to *date.Date) ([]*hPkg.HabitCheck, error) {
+	return r.getUserHabitsChecks(userID, habitIDs, from, to, true), nil
+}
+
+func (r memRepo) GetUserHabitsChecks(ctx context.Context, userID int64, habitIDs []int64, from, to *date.Date) ([]*hPkg.HabitCheck, error) {
+	return r.getUserHabitsChecks(userID, habitIDs, from, to, false), nil
+}
+
+func (r memRepo) getUserHabitsChecks(userID int64, habitIDs []int64, from, to *date.Date, completedOnly bool) []*hPkg.HabitCheck {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	checks := []*hPkg.HabitCheck{}
+	// Comparisons with NULL bounds are never true in SQL
+	if from == nil || to == nil {
+		return checks
+	}
+
+	for k, hc := range r.db.Checks {
+		if k.UserID != userID || !slices.Contains(habitIDs, k.HabitID) || (completedOnly && !hc.Completed) {
+			continue
+		}
+		if k.CheckDate < from.String() || k.CheckDate > to.String() {
+			continue
+		}
+		checks = append(checks, copyCheck(hc))
+	}
+	slices.SortFunc(checks, func(a, b *hPkg.HabitCheck) int {
+		return cmp.Or(a.CheckDate.Compare(b.CheckDate), cmp.Compare(a.HabitID, b.HabitID))
+	})
+
+	return checks
+}
+
+func (r memRepo) ForEachUserCheck(ctx context.Context, userID int64, fn func(*hPkg.HabitCheck) error) error {
+	r.db.Lock()
+	checks := []*hPkg.HabitCheck{}
+	for k, hc := range r.db.Checks {
+		if k.UserID == userID {
+			checks = append(checks, copyCheck(hc))
+		}
+	}
+	r.db.Unlock()
+	slices.SortFunc(checks, func(a, b *hPkg.HabitCheck) int {
+		return cmp.Or(cmp.Compare(a.HabitID, b.HabitID), a.CheckDate.Compare(b.CheckDate))
+	})
+
+	return forEach(checks, fn)
+}
+
+func (r memRepo) ImportHabits(ctx context.Context, userID int64, habits []*hPkg.Habit) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	for _, h := range habits {
+		if h.ID == 0 {
+			r.create(h)
+		} else {
+			if stored, ok := r.db.Habits[h.ID]; ok {
+				stored.CreatedAt = h.CreatedAt
+			}
+			if m, ok := r.db.Members[memdb.MemberKey{HabitID: h.ID, UserID: userID}]; ok {
+				m.JoinedAt = h.JoinedAt
+			}
+		}
+
+		for _, hc := range h.Checks {
+			hc.HabitID = h.ID
+			r.db.Checks[memdb.NewCheckKey(hc)] = copyCheck(hc)
+		}
+	}
+
+	return nil
+}
+
+func (r memRepo) SetMemberVisibility(ctx context.Context, habitID, userID int64, isPublic bool) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	if m, ok := r.db.Members[memdb.MemberKey{HabitID: habitID, UserID: userID}]; ok {
+		m.IsPublic = isPublic
+	}
+
+	return nil
+}
+
+func (r memRepo) AddMember(ctx context.Context, habitID, userID int64, isPublic bool, joinedAt time.Time) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	k := memdb.MemberKey{HabitID: habitID, UserID: userID}
+	if m, ok := r.db.Members[k]; ok {
+		m.Active = true
+		return nil
+	}
+	r.db.Members[k] = &memdb.Member{Active: true, IsPublic: isPublic, JoinedAt: joinedAt}
+
+	return nil
+}
+
+func (r memRepo) RemoveMember(ctx context.Context, habitID, userID int64) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	if m, ok := r.db.Members[memdb.MemberKey{HabitID: habitID, UserID: userID}]; ok {
+		m.Active = false
+	}
+
+	return nil
+}
+
+// LockParticipants does nothing, since the in-memory unit of work isn't a transaction
+func (r memRepo) LockParticipants(ctx context.Context, habitID int64) error {
+	return nil
+}
+
+func (r memRepo) GetParticipants(ctx context.Context, habitID int64) ([]*hPkg.Participant, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	participants := []*hPkg.Participant{}
+	h, ok := r.db.Habits[habitID]
+	if !ok {
+		return participants, nil
+	}
+	for k, m := range r.db.Members {
+		u, ok := r.db.Users[k.UserID]
+		if k.HabitID != habitID || !m.Active || !ok {
+			continue
+		}
+		participants = append(participants, &hPkg.Participant{
+			UserID:      u.ID,
+			TgUsername:  u.TgUsername,
+			TgFirstName: u.TgFirstName,
+			TgLastName:  u.TgLastName,
+			IsCreator:   u.ID == h.CreatorID,
+			JoinedAt:    m.JoinedAt,
+		})
+	}
+	slices.SortFunc(participants, func(a, b *hPkg.Participant) int {
+		return cmp.Or(a.JoinedAt.Compare(b.JoinedAt), cmp.Compare(a.UserID, b.UserID))
+	})
+
+	return participants, nil
+}
+
+func (r memRepo) ForEachMembershipByUserID(ctx context.Context, userID int64, fn func(*hPkg.Membership) error) error {
+	r.db.Lock()
+	memberships := []*hPkg.Membership{}
+	for k, m := range r.db.Members {
+		if k.UserID != userID {
+			continue
+		}
+		memberships = append(memberships, &hPkg.Membership{
+			HabitID:  k.HabitID,
+			UserID:   k.UserID,
+			Active:   m.Active,
+			IsPublic: m.IsPublic,
+			JoinedAt: m.JoinedAt,
+		})
+	}
+	r.db.Unlock()
+	slices.SortFunc(memberships, func(a, b *hPkg.Membership) int {
+		return cmp.Or(a.JoinedAt.Compare(b.JoinedAt), cmp.Compare(a.HabitID, b.HabitID))
+	})
+
+	return forEach(memberships, fn)
+}
+
+func (r memRepo) CreateInvite(ctx context.Context, i *hPkg.Invite) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	copied := *i
+	r.db.Invites[i.Token] = &copied
+
+	return nil
+}
+
+func (r memRepo) GetInviteByToken(ctx context.Context, token string) (*hPkg.Invite, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	i, ok := r.db.Invites[token]
+	if !ok {
+		return nil, apperrors.ErrNotFound("couldn't find habit invite")
+	}
+
+	copied := *i
+	return &copied, nil
+}
+
+// forEach calls fn for the items until its first error. Items are copied under the lock and passed after
+// releasing it, so fn may use the repository
+func forEach[T any](items []T, fn func(T) error) error {
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,91 +2,111 @@ package repo
 
 import (
 	"context"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
 	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
 	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
 )
 
+// querier runs queries in the pool or in a transaction
+type querier interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}
+
 type pgRepo struct {
-	c context.Context
 	p *pgxpool.Pool
 }
 
-func initPGRepo(c context.Context, p *pgxpool.Pool) *pgRepo {
-	return &pgRepo{c, p}
+func initPGRepo(p *pgxpool.Pool) *pgRepo {
+	return &pgRepo{p}
 }
 
-func (r pgRepo) Create(h *hPkg.Habit) error {
-	sql := `
-		WITH habit AS (
-			INSERT INTO habits (active, archived, title, description, color, creator_id, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-			RETURNING id, creator_id
-		)
-		INSERT INTO users_habits (active, user_id, habit_id, is_public)
-		SELECT TRUE, habit.creator_id, habit.id, $9 FROM habit
-		RETURNING habit_id
-	`
-	err := r.p.QueryRow(
-		r.c,
-		sql,
+const createSQL = `
+	WITH habit AS (
+		INSERT INTO habits (active, archived, title, description, color, schedule, target_value, unit, reminder_time, creator_id, created_at, updated_at, archived_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, '')::TIME, $10, $11, $12, $13)
+		RETURNING id, creator_id
+	)
+	INSERT INTO users_habits (active, user_id, habit_id, is_public, joined_at)
+	SELECT TRUE, habit.creator_id, habit.id, $14, $15 FROM habit
+	RETURNING habit_id
+`
+
+func (r pgRepo) Create(ctx context.Context, h *hPkg.Habit) error {
+	return create(ctx, uow.Conn(ctx, r.p), h)
+}
+
+func create(ctx context.Context, q querier, h *hPkg.Habit) error {
+	err := q.QueryRow(ctx,
+		createSQL,
 		h.Active,
 		h.Archived,
 		h.Title,
 		h.Description,
 		h.Color,
+		h.Schedule,
+		h.Target,
+		h.Unit,
+		h.ReminderTime,
 		h.CreatorID,
 		h.CreatedAt,
 		h.UpdatedAt,
+		h.ArchivedAt,
 		h.IsPublic,
+		h.JoinedAt,
 	).Scan(&h.ID)
 
 	return err
 }
 
-func (r pgRepo) Update(h *hPkg.Habit) error {
+// Update saves habit's settings shared by all participants. Participant's own ones are saved by SetMemberVisibility
+func (r pgRepo) Update(ctx context.Context, h *hPkg.Habit) error {
 	sql := `
-		WITH habit AS (
-			UPDATE habits SET
-				active = $1,
-				archived = $2,
-				title = $3,
-				description = $4,
-				color = $5,
-				updated_at = $6
-			WHERE id = $7
-			RETURNING id, creator_id
-		)
-		UPDATE users_habits SET
-			is_public = $8
-		FROM habit h
-		WHERE 
-			users_habits.habit_id = h.id
-			AND users_habits.user_id = h.creator_id
+		UPDATE habits SET
+			active = $1,
+			archived = $2,
+			title = $3,
+			description = $4,
+			color = $5,
+			schedule = $6,
+			target_value = $7,
+			unit = NULLIF($8, ''),
+			reminder_time = NULLIF($9, '')::TIME,
+			updated_at = $10,
+			archived_at = $11,
+			deleted_at = CASE WHEN $1 THEN NULL ELSE COALESCE(deleted_at, $10) END
+		WHERE id = $12
 	`
-	_, err := r.p.Exec(
-		r.c,
+	_, err := uow.Conn(ctx, r.p).Exec(
+		ctx,
 		sql,
 		h.Active,
 		h.Archived,
 		h.Title,
 		h.Description,
 		h.Color,
+		h.Schedule,
+		h.Target,
+		h.Unit,
+		h.ReminderTime,
 		h.UpdatedAt,
+		h.ArchivedAt,
 		h.ID,
-		h.IsPublic,
 	)
 
 	return err
 }
 
-func (r pgRepo) GetByOwnerIDAndStatus(ownerID int64, status hPkg.HabitStatus, requestedByOwner bool) ([]*hPkg.Habit, error) {
+func (r pgRepo) GetByOwnerIDAndStatus(ctx context.Context, ownerID int64, status hPkg.HabitStatus, requestedByOwner bool) ([]*hPkg.Habit, error) {
 	sql := `
-		SELECT h.id, h.active, h.archived, h.title, h.description, h.color, h.creator_id, uh.is_public, h.created_at, h.updated_at
+		SELECT h.id, h.active, h.archived, h.title, h.description, h.color, h.schedule, h.target_value, COALESCE(h.unit, ''), COALESCE(to_char(h.reminder_time, 'HH24:MI'), ''), h.creator_id, uh.is_public, uh.joined_at, h.created_at, h.updated_at, h.archived_at
 		FROM habits h
 		JOIN users_habits uh ON 
 			h.id = uh.habit_id 
@@ -105,7 +125,7 @@ func (r pgRepo) GetByOwnerIDAndStatus(ownerID int64, status hPkg.HabitStatus, re
 		sql += " AND uh.is_public IS TRUE"
 	}
 
-	rows, err := r.p.Query(r.c, sql, ownerID)
+	rows, err := uow.Conn(ctx, r.p).Query(ctx, sql, ownerID)
 	if err != nil {
 		return nil, err
 	}
@@ -121,10 +141,16 @@ func (r pgRepo) GetByOwnerIDAndStatus(ownerID int64, status hPkg.HabitStatus, re
 			&h.Title,
 			&h.Description,
 			&h.Color,
+			&h.Schedule,
+			&h.Target,
+			&h.Unit,
+			&h.ReminderTime,
 			&h.CreatorID,
 			&h.IsPublic,
+			&h.JoinedAt,
 			&h.CreatedAt,
 			&h.UpdatedAt,
+			&h.ArchivedAt,
 		)
 		if err != nil {
 			return nil, err
@@ -138,14 +164,14 @@ func (r pgRepo) GetByOwnerIDAndStatus(ownerID int64, status hPkg.HabitStatus, re
 	return habits, nil
 }
 
-func (r pgRepo) GetByIDAndOwnerID(id int64, ownerID int64, requestedByOwner bool) (*hPkg.Habit, error) {
+func (r pgRepo) GetByIDAndOwnerID(ctx context.Context, id int64, ownerID int64, requestedByOwner bool) (*hPkg.Habit, error) {
 	sql := `
 		WITH habit AS (
 			SELECT *
 			FROM habits h
 			WHERE h.id = $1
 		)
-		SELECT h.id, h.active, h.archived, h.title, h.description, h.color, h.creator_id, uh.is_public, h.created_at, h.updated_at
+		SELECT h.id, h.active, h.archived, h.title, h.description, h.color, h.schedule, h.target_value, COALESCE(h.unit, ''), COALESCE(to_char(h.reminder_time, 'HH24:MI'), ''), h.creator_id, uh.is_public, uh.joined_at, h.created_at, h.updated_at, h.archived_at
 		FROM habit h
 		JOIN users_habits uh ON 
 			h.id = uh.habit_id 
@@ -158,8 +184,8 @@ func (r pgRepo) GetByIDAndOwnerID(id int64, ownerID int64, requestedByOwner bool
 	}
 
 	h := &hPkg.Habit{}
-	err := r.p.QueryRow(
-		r.c,
+	err := uow.Conn(ctx, r.p).QueryRow(
+		ctx,
 		sql,
 		id,
 		ownerID,
@@ -170,10 +196,16 @@ func (r pgRepo) GetByIDAndOwnerID(id int64, ownerID int64, requestedByOwner bool
 		&h.Title,
 		&h.Description,
 		&h.Color,
+		&h.Schedule,
+		&h.Target,
+		&h.Unit,
+		&h.ReminderTime,
 		&h.CreatorID,
 		&h.IsPublic,
+		&h.JoinedAt,
 		&h.CreatedAt,
 		&h.UpdatedAt,
+		&h.ArchivedAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -185,40 +217,234 @@ func (r pgRepo) GetByIDAndOwnerID(id int64, ownerID int64, requestedByOwner bool
 	return h, nil
 }
 
-func (r pgRepo) SetUserHabitCheck(hc *hPkg.HabitCheck) error {
+// ForEachByMemberID calls fn for all habits the user has ever participated in, including left and deleted
+// ones, with the user's membership settings. Habits are passed as they are read from the cursor, and fn's
+// error stops reading
+func (r pgRepo) ForEachByMemberID(ctx context.Context, userID int64, fn func(*hPkg.Habit) error) error {
 	sql := `
-		INSERT INTO user_habit_checks (user_id, habit_id, check_date, completed, checked_at)
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (habit_id, user_id, check_date) DO UPDATE SET
-			completed = EXCLUDED.completed,
-			checked_at = EXCLUDED.checked_at
+		SELECT h.id, h.active, h.archived, h.title, h.description, h.color, h.schedule, h.target_value, COALESCE(h.unit, ''), COALESCE(to_char(h.reminder_time, 'HH24:MI'), ''), h.creator_id, uh.is_public, uh.joined_at, h.created_at, h.updated_at, h.archived_at
+		FROM habits h
+		JOIN users_habits uh ON h.id = uh.habit_id
+		WHERE uh.user_id = $1
+		ORDER BY h.id ASC
 	`
-	_, err := r.p.Exec(
-		r.c,
-		sql,
+	rows, err := uow.Conn(ctx, r.p).Query(ctx, sql, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		h := &hPkg.Habit{}
+		err = rows.Scan(
+			&h.ID,
+			&h.Active,
+			&h.Archived,
+			&h.Title,
+			&h.Description,
+			&h.Color,
+			&h.Schedule,
+			&h.Target,
+			&h.Unit,
+			&h.ReminderTime,
+			&h.CreatorID,
+			&h.IsPublic,
+			&h.JoinedAt,
+			&h.CreatedAt,
+			&h.UpdatedAt,
+			&h.ArchivedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if err = fn(h); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// ClaimDueReminders marks reminders of habits which reminder time has come in their participants'
+// time zones as sent today and returns them. Claimed reminders aren't returned again the same day
+func (r pgRepo) ClaimDueReminders(ctx context.Context, now time.Time) ([]*hPkg.Reminder, error) {
+	sql := `
+		WITH due AS (
+			SELECT uh.habit_id, uh.user_id, ($1::TIMESTAMPTZ AT TIME ZONE COALESCE(u.timezone, 'UTC'))::DATE AS local_date
+			FROM users_habits uh
+			JOIN habits h ON h.id = uh.habit_id
+			JOIN users u ON u.id = uh.user_id
+			WHERE
+				uh.active IS TRUE
+				AND h.active IS TRUE
+				AND h.archived IS FALSE
+				AND h.reminder_time IS NOT NULL
+				AND ($1::TIMESTAMPTZ AT TIME ZONE COALESCE(u.timezone, 'UTC'))::TIME >= h.reminder_time
+				AND (
+					uh.reminder_sent_on IS NULL
+					OR uh.reminder_sent_on < ($1::TIMESTAMPTZ AT TIME ZONE COALESCE(u.timezone, 'UTC'))::DATE
+				)
+			FOR UPDATE OF uh SKIP LOCKED
+		),
+		claimed AS (
+			UPDATE users_habits uh SET
+				reminder_sent_on = due.local_date
+			FROM due
+			WHERE
+				uh.habit_id = due.habit_id
+				AND uh.user_id = due.user_id
+			RETURNING uh.habit_id, uh.user_id, uh.is_public, uh.joined_at, due.local_date
+		)
+		SELECT h.id, h.active, h.archived, h.title, h.description, h.color, h.schedule, h.target_value, COALESCE(h.unit, ''), COALESCE(to_char(h.reminder_time, 'HH24:MI'), ''), h.creator_id, c.is_public, c.joined_at, h.created_at, h.updated_at, h.archived_at, c.user_id, c.local_date
+		FROM claimed c
+		JOIN habits h ON h.id = c.habit_id
+	`
+	rows, err := uow.Conn(ctx, r.p).Query(ctx, sql, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reminders := []*hPkg.Reminder{}
+	for rows.Next() {
+		rm := &hPkg.Reminder{Habit: &hPkg.Habit{}}
+		err = rows.Scan(
+			&rm.Habit.ID,
+			&rm.Habit.Active,
+			&rm.Habit.Archived,
+			&rm.Habit.Title,
+			&rm.Habit.Description,
+			&rm.Habit.Color,
+			&rm.Habit.Schedule,
+			&rm.Habit.Target,
+			&rm.Habit.Unit,
+			&rm.Habit.ReminderTime,
+			&rm.Habit.CreatorID,
+			&rm.Habit.IsPublic,
+			&rm.Habit.JoinedAt,
+			&rm.Habit.CreatedAt,
+			&rm.Habit.UpdatedAt,
+			&rm.Habit.ArchivedAt,
+			&rm.UserID,
+			&rm.Date,
+		)
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, rm)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return reminders, nil
+}
+
+const setUserHabitCheckSQL = `
+	INSERT INTO user_habit_checks (user_id, habit_id, check_date, completed, amount, checked_at)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (habit_id, user_id, check_date) DO UPDATE SET
+		completed = EXCLUDED.completed,
+		amount = EXCLUDED.amount,
+		checked_at = EXCLUDED.checked_at
+`
+
+func (r pgRepo) SetUserHabitCheck(ctx context.Context, hc *hPkg.HabitCheck) error {
+	return setUserHabitCheck(ctx, uow.Conn(ctx, r.p), hc)
+}
+
+func setUserHabitCheck(ctx context.Context, q querier, hc *hPkg.HabitCheck) error {
+	_, err := q.Exec(ctx,
+		setUserHabitCheckSQL,
 		hc.UserID,
 		hc.HabitID,
 		hc.CheckDate,
 		hc.Completed,
+		hc.Amount,
 		hc.CheckedAt,
 	)
 
 	return err
 }
 
-func (r pgRepo) GetUserHabitsCompletedChecks(userID int64, habitIDs []int64, from, to *date.Date) ([]*hPkg.HabitCheck, error) {
+// RecalcChecksCompletion updates completion of quantitative habit's checks after its target change
+func (r pgRepo) RecalcChecksCompletion(ctx context.Context, h *hPkg.Habit) error {
 	sql := `
-		SELECT habit_id, user_id, completed, check_date, checked_at
+		UPDATE user_habit_checks SET
+			completed = amount >= $1
+		WHERE habit_id = $2
+			AND amount IS NOT NULL
+	`
+	_, err := uow.Conn(ctx, r.p).Exec(
+		ctx,
+		sql,
+		h.Target,
+		h.ID,
+	)
+
+	return err
+}
+
+// ForEachUserCheck calls fn for all user's checks of all habits as they are read from the cursor,
+// fn's error stops reading
+func (r pgRepo) ForEachUserCheck(ctx context.Context, userID int64, fn func(*hPkg.HabitCheck) error) error {
+	sql := `
+		SELECT habit_id, user_id, completed, amount, check_date, checked_at
+		FROM user_habit_checks
+		WHERE user_id = $1
+		ORDER BY habit_id ASC, check_date ASC
+	`
+	rows, err := uow.Conn(ctx, r.p).Query(ctx, sql, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		hc := &hPkg.HabitCheck{}
+		err = rows.Scan(
+			&hc.HabitID,
+			&hc.UserID,
+			&hc.Completed,
+			&hc.Amount,
+			&hc.CheckDate,
+			&hc.CheckedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if err = fn(hc); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r pgRepo) GetUserHabitsCompletedChecks(ctx context.Context, userID int64, habitIDs []int64, from, to *date.Date) ([]*hPkg.HabitCheck, error) {
+	return r.getUserHabitsChecks(ctx, userID, habitIDs, from, to, true)
+}
+
+func (r pgRepo) GetUserHabitsChecks(ctx context.Context, userID int64, habitIDs []int64, from, to *date.Date) ([]*hPkg.HabitCheck, error) {
+	return r.getUserHabitsChecks(ctx, userID, habitIDs, from, to, false)
+}
+
+func (r pgRepo) getUserHabitsChecks(ctx context.Context, userID int64, habitIDs []int64, from, to *date.Date, completedOnly bool) ([]*hPkg.HabitCheck, error) {
+	sql := `
+		SELECT habit_id, user_id, completed, amount, check_date, checked_at
 		FROM user_habit_checks
 		WHERE user_id = $1
 			AND habit_id = ANY($2)
 			AND check_date >= $3
 			AND check_date <= $4
-			AND completed IS TRUE
-		ORDER BY check_date ASC
 	`
-	rows, err := r.p.Query(
-		r.c,
+	if completedOnly {
+		sql += " AND completed IS TRUE"
+	}
+	sql += " ORDER BY check_date ASC"
+
+	rows, err := uow.Conn(ctx, r.p).Query(
+		ctx,
 		sql,
 		userID,
 		habitIDs,
@@ -237,6 +463,7 @@ func (r pgRepo) GetUserHabitsCompletedChecks(userID int64, habitIDs []int64, fro
 			&hc.HabitID,
 			&hc.UserID,
 			&hc.Completed,
+			&hc.Amount,
 			&hc.CheckDate,
 			&hc.CheckedAt,
 		)
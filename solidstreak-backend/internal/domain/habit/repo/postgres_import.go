@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+)
+
+// ImportHabits saves imported habits of the user with their checks in a single transaction. Habits
+// without ID are created, while creation and join times of existing ones are updated, since they
+// could be moved back to the earliest imported check
+func (r pgRepo) ImportHabits(ctx context.Context, userID int64, habits []*hPkg.Habit) error {
+	return pgx.BeginFunc(ctx, uow.Conn(ctx, r.p), func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+
+		for _, h := range habits {
+			if h.ID == 0 {
+				if err := create(ctx, tx, h); err != nil {
+					return err
+				}
+			} else {
+				batch.Queue(`UPDATE habits SET created_at = $1 WHERE id = $2`, h.CreatedAt, h.ID)
+				batch.Queue(`UPDATE users_habits SET joined_at = $1 WHERE habit_id = $2 AND user_id = $3`, h.JoinedAt, h.ID, userID)
+			}
+
+			for _, hc := range h.Checks {
+				hc.HabitID = h.ID
+				batch.Queue(setUserHabitCheckSQL, hc.UserID, hc.HabitID, hc.CheckDate, hc.Completed, hc.Amount, hc.CheckedAt)
+			}
+		}
+
+		return tx.SendBatch(ctx, batch).Close()
+	})
+}
@@ -0,0 +1,205 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+func (r pgRepo) SetMemberVisibility(ctx context.Context, habitID, userID int64, isPublic bool) error {
+	sql := `
+		UPDATE users_habits SET
+			is_public = $1
+		WHERE
+			habit_id = $2
+			AND user_id = $3
+	`
+	_, err := uow.Conn(ctx, r.p).Exec(
+		ctx,
+		sql,
+		isPublic,
+		habitID,
+		userID,
+	)
+
+	return err
+}
+
+// AddMember makes the user a participant of the habit. A participant who left the habit before
+// rejoins it with the former join time, since their checks are kept
+func (r pgRepo) AddMember(ctx context.Context, habitID, userID int64, isPublic bool, joinedAt time.Time) error {
+	sql := `
+		INSERT INTO users_habits (active, user_id, habit_id, is_public, joined_at)
+		VALUES (TRUE, $1, $2, $3, $4)
+		ON CONFLICT (user_id, habit_id) DO UPDATE SET
+			active = TRUE
+	`
+	_, err := uow.Conn(ctx, r.p).Exec(
+		ctx,
+		sql,
+		userID,
+		habitID,
+		isPublic,
+		joinedAt,
+	)
+
+	return err
+}
+
+func (r pgRepo) RemoveMember(ctx context.Context, habitID, userID int64) error {
+	sql := `
+		UPDATE users_habits SET
+			active = FALSE
+		WHERE
+			habit_id = $1
+			AND user_id = $2
+	`
+	_, err := uow.Conn(ctx, r.p).Exec(
+		ctx,
+		sql,
+		habitID,
+		userID,
+	)
+
+	return err
+}
+
+// LockParticipants locks the habit until the end of the unit of work, so nobody joins it or rejoins
+// meanwhile. Joining takes the same lock, and new memberships wait for it by their foreign key
+func (r pgRepo) LockParticipants(ctx context.Context, habitID int64) error {
+	sql := `
+		SELECT id
+		FROM habits
+		WHERE id = $1
+		FOR UPDATE
+	`
+	_, err := uow.Conn(ctx, r.p).Exec(ctx, sql, habitID)
+
+	return err
+}
+
+func (r pgRepo) GetParticipants(ctx context.Context, habitID int64) ([]*hPkg.Participant, error) {
+	sql := `
+		SELECT u.id, u.tg_username, COALESCE(u.tg_first_name, ''), COALESCE(u.tg_last_name, ''), u.id = h.creator_id, uh.joined_at
+		FROM users_habits uh
+		JOIN habits h ON h.id = uh.habit_id
+		JOIN users u ON u.id = uh.user_id
+		WHERE
+			uh.habit_id = $1
+			AND uh.active IS TRUE
+		ORDER BY uh.joined_at ASC, u.id ASC
+	`
+	rows, err := uow.Conn(ctx, r.p).Query(ctx, sql, habitID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	participants := []*hPkg.Participant{}
+	for rows.Next() {
+		p := &hPkg.Participant{}
+		err = rows.Scan(
+			&p.UserID,
+			&p.TgUsername,
+			&p.TgFirstName,
+			&p.TgLastName,
+			&p.IsCreator,
+			&p.JoinedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		participants = append(participants, p)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return participants, nil
+}
+
+// ForEachMembershipByUserID calls fn for all user's memberships, including left and deleted habits ones,
+// as they are read from the cursor. fn's error stops reading
+func (r pgRepo) ForEachMembershipByUserID(ctx context.Context, userID int64, fn func(*hPkg.Membership) error) error {
+	sql := `
+		SELECT habit_id, user_id, active, is_public, joined_at
+		FROM users_habits
+		WHERE user_id = $1
+		ORDER BY joined_at ASC, habit_id ASC
+	`
+	rows, err := uow.Conn(ctx, r.p).Query(ctx, sql, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := &hPkg.Membership{}
+		err = rows.Scan(
+			&m.HabitID,
+			&m.UserID,
+			&m.Active,
+			&m.IsPublic,
+			&m.JoinedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if err = fn(m); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (r pgRepo) CreateInvite(ctx context.Context, i *hPkg.Invite) error {
+	sql := `
+		INSERT INTO habit_invites (token, habit_id, creator_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	_, err := uow.Conn(ctx, r.p).Exec(
+		ctx,
+		sql,
+		i.Token,
+		i.HabitID,
+		i.CreatorID,
+		i.CreatedAt,
+		i.ExpiresAt,
+	)
+
+	return err
+}
+
+func (r pgRepo) GetInviteByToken(ctx context.Context, token string) (*hPkg.Invite, error) {
+	sql := `
+		SELECT token, habit_id, creator_id, created_at, expires_at
+		FROM habit_invites
+		WHERE token = $1
+	`
+	i := &hPkg.Invite{}
+	err := uow.Conn(ctx, r.p).QueryRow(
+		ctx,
+		sql,
+		token,
+	).Scan(
+		&i.Token,
+		&i.HabitID,
+		&i.CreatorID,
+		&i.CreatedAt,
+		&i.ExpiresAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apperrors.ErrNotFound("couldn't find habit invite")
+		}
+		return nil, err
+	}
+
+	return i, nil
+}
@@ -0,0 +1,201 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+const (
+	checksTable            = "user_habit_checks"
+	checksDefaultPartition = checksTable + "_default"
+
+	// Key of the advisory lock serializing partition maintenance between backend replicas
+	checksPartitionsLockKey int64 = 0x50415254 // "PART"
+)
+
+func checksMonthPartitionName(month date.Date) string {
+	t := time.Time(month)
+	return fmt.Sprintf("%s_y%04dm%02d", checksTable, t.Year(), int(t.Month()))
+}
+
+// checksMonthPartitionBounds returns half-open [month start, next month start) bounds of the month's
+// partition as PostgreSQL reports them
+func checksMonthPartitionBounds(month date.Date) string {
+	from := month.MonthStart()
+	return fmt.Sprintf("FOR VALUES FROM ('%s') TO ('%s')", from, from.AddDate(0, 1, 0))
+}
+
+// parseChecksMonthPartitionName returns the month of the monthly partition by its name
+func parseChecksMonthPartitionName(name string) (date.Date, bool) {
+	var year, month int
+	if _, err := fmt.Sscanf(name, checksTable+"_y%04dm%02d", &year, &month); err != nil || month < 1 || month > 12 {
+		return date.Date{}, false
+	}
+	d := date.Date(time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC))
+	return d, checksMonthPartitionName(d) == name
+}
+
+func (r pgRepo) EnsureChecksDefaultPartition(ctx context.Context) (bool, error) {
+	tx, err := uow.Conn(ctx, r.p).Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, checksPartitionsLockKey); err != nil {
+		return false, err
+	}
+
+	exists := false
+	if err = tx.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, checksDefaultPartition).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	sql := fmt.Sprintf(`CREATE TABLE %s PARTITION OF %s DEFAULT`, checksDefaultPartition, checksTable)
+	if _, err = tx.Exec(ctx, sql); err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// EnsureChecksMonthPartition creates the partition holding checks of the specified date's month
+// with half-open [month start, next month start) bounds. A partition with the expected name but
+// other bounds (legacy hand-written partitions ended on the last day of the month) is replaced,
+// and rows of the month stored in the default partition are moved to the new one.
+func (r pgRepo) EnsureChecksMonthPartition(ctx context.Context, month date.Date) (bool, error) {
+	tx, err := uow.Conn(ctx, r.p).Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err = tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, checksPartitionsLockKey); err != nil {
+		return false, err
+	}
+
+	var (
+		from       = month.MonthStart()
+		to         = from.AddDate(0, 1, 0)
+		name       = checksMonthPartitionName(from)
+		legacyName = name + "_legacy"
+		wantBounds = checksMonthPartitionBounds(from)
+		bounds     string
+		hasLegacy  bool
+	)
+
+	sql := `
+		SELECT pg_get_expr(c.relpartbound, c.oid)
+		FROM pg_class c
+		JOIN pg_inherits i ON i.inhrelid = c.oid
+		WHERE
+			i.inhparent = $1::regclass
+			AND c.relname = $2
+	`
+	err = tx.QueryRow(ctx, sql, checksTable, name).Scan(&bounds)
+	switch {
+	case err == pgx.ErrNoRows:
+	case err != nil:
+		return false, err
+	case bounds == wantBounds:
+		return false, nil
+	default:
+		// Keeping rows of the partition with wrong bounds aside until the correct one is created
+		if _, err = tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, checksTable, name)); err != nil {
+			return false, err
+		}
+		if _, err = tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, name, legacyName)); err != nil {
+			return false, err
+		}
+		hasLegacy = true
+	}
+
+	// While the month partition doesn't exist, its rows could only land in the default partition.
+	// They block the new partition creation, so the default partition is detached while they are moved
+	defaultHasRows := false
+	sql = fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE check_date >= $1 AND check_date < $2)`, checksTable)
+	if err = tx.QueryRow(ctx, sql, from, to).Scan(&defaultHasRows); err != nil {
+		return false, err
+	}
+
+	if defaultHasRows {
+		if _, err = tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s DETACH PARTITION %s`, checksTable, checksDefaultPartition)); err != nil {
+			return false, err
+		}
+	}
+
+	sql = fmt.Sprintf(`CREATE TABLE %s PARTITION OF %s %s`, name, checksTable, wantBounds)
+	if _, err = tx.Exec(ctx, sql); err != nil {
+		return false, err
+	}
+
+	if defaultHasRows {
+		sql = fmt.Sprintf(`
+			WITH moved AS (
+				DELETE FROM %s
+				WHERE check_date >= $1 AND check_date < $2
+				RETURNING *
+			)
+			INSERT INTO %s SELECT * FROM moved
+		`, checksDefaultPartition, checksTable)
+		if _, err = tx.Exec(ctx, sql, from, to); err != nil {
+			return false, err
+		}
+		if _, err = tx.Exec(ctx, fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s DEFAULT`, checksTable, checksDefaultPartition)); err != nil {
+			return false, err
+		}
+	}
+
+	if hasLegacy {
+		if _, err = tx.Exec(ctx, fmt.Sprintf(`INSERT INTO %s SELECT * FROM %s`, checksTable, legacyName)); err != nil {
+			return false, err
+		}
+		if _, err = tx.Exec(ctx, fmt.Sprintf(`DROP TABLE %s`, legacyName)); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// GetChecksPartitions returns partitions of habit checks, monthly ones along with their months
+func (r pgRepo) GetChecksPartitions(ctx context.Context) ([]*Partition, error) {
+	sql := `
+		SELECT c.relname, pg_get_expr(c.relpartbound, c.oid), GREATEST(c.reltuples, 0)::BIGINT
+		FROM pg_class c
+		JOIN pg_inherits i ON i.inhrelid = c.oid
+		WHERE i.inhparent = $1::regclass
+		ORDER BY c.relname ASC
+	`
+	rows, err := uow.Conn(ctx, r.p).Query(ctx, sql, checksTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := []*Partition{}
+	for rows.Next() {
+		p := &Partition{}
+		if err = rows.Scan(&p.Name, &p.Bounds, &p.Rows); err != nil {
+			return nil, err
+		}
+		if month, ok := parseChecksMonthPartitionName(p.Name); ok {
+			p.Month, p.WrongBounds = &month, p.Bounds != checksMonthPartitionBounds(month)
+		}
+		partitions = append(partitions, p)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return partitions, nil
+}
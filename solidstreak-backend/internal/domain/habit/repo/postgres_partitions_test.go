@@ -0,0 +1,189 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/migrations"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+// newTestPool connects to PostgreSQL set by POSTGRES_CONN_STRING and migrates a schema of its own,
+// which is dropped once the test ends. The test is skipped without PostgreSQL
+func newTestPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	connString := os.Getenv("POSTGRES_CONN_STRING")
+	if connString == "" {
+		t.Skip("POSTGRES_CONN_STRING isn't set")
+	}
+
+	ctx := context.Background()
+
+	admin, err := pgxpool.New(ctx, connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(admin.Close)
+
+	schema := fmt.Sprintf("test_partitions_%d", time.Now().UnixNano())
+	if _, err = admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`)
+	})
+
+	config, err := pgxpool.ParseConfig(connString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.ConnConfig.RuntimeParams["search_path"] = schema
+
+	p, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+
+	m, err := migrations.New(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func TestEnsureChecksMonthPartitionFixesLegacyBounds(t *testing.T) {
+	p := newTestPool(t)
+	ctx := context.Background()
+	r := InitPartitionRepo(p)
+
+	exec := func(sql string, args ...any) {
+		t.Helper()
+		if _, err := p.Exec(ctx, sql, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Legacy hand-written partitions ended on the 30th, so checks of the last days of the month
+	// landed in the default partition, as did checks of months without partitions
+	exec(`CREATE TABLE user_habit_checks_y2024m05 PARTITION OF user_habit_checks FOR VALUES FROM ('2024-05-01') TO ('2024-05-30')`)
+	exec(`INSERT INTO users (tg_id, tg_username, tg_lang_code, tg_is_bot, created_at) VALUES (100, 'user100', 'en', FALSE, NOW())`)
+	exec(`INSERT INTO habits (creator_id, title, created_at, updated_at) SELECT id, 'Read', NOW(), NOW() FROM users`)
+	for _, d := range []string{"2024-05-10", "2024-05-30", "2024-05-31", "2024-06-15", "2024-08-01"} {
+		exec(`
+			INSERT INTO user_habit_checks (user_id, habit_id, check_date, completed, checked_at)
+			SELECT creator_id, id, $1, TRUE, NOW() FROM habits
+		`, d)
+	}
+
+	wantPlacement := func(want map[string][]string) {
+		t.Helper()
+
+		rows, err := p.Query(ctx, `SELECT tableoid::regclass::TEXT, check_date FROM user_habit_checks ORDER BY check_date`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		got := map[string][]string{}
+		for rows.Next() {
+			var (
+				partition string
+				checkDate time.Time
+			)
+			if err = rows.Scan(&partition, &checkDate); err != nil {
+				t.Fatal(err)
+			}
+			got[partition] = append(got[partition], checkDate.Format(time.DateOnly))
+		}
+		if err = rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		for partition, dates := range want {
+			if !slices.Equal(got[partition], dates) {
+				t.Errorf("got checks of %v in %s, want %v", got[partition], partition, dates)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("got checks in partitions %v, want %v", got, want)
+		}
+	}
+
+	wantPlacement(map[string][]string{
+		"user_habit_checks_y2024m05": {"2024-05-10"},
+		"user_habit_checks_default":  {"2024-05-30", "2024-05-31", "2024-06-15", "2024-08-01"},
+	})
+
+	may := date.Date(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	june := may.AddDate(0, 1, 0)
+	for _, month := range []date.Date{may, june} {
+		created, err := r.EnsureChecksMonthPartition(ctx, month)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !created {
+			t.Errorf("partition of %s isn't created", month)
+		}
+	}
+
+	wantPlacement(map[string][]string{
+		"user_habit_checks_y2024m05": {"2024-05-10", "2024-05-30", "2024-05-31"},
+		"user_habit_checks_y2024m06": {"2024-06-15"},
+		"user_habit_checks_default":  {"2024-08-01"},
+	})
+
+	partitions, err := r.GetChecksPartitions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bounds := map[string]string{}
+	for _, part := range partitions {
+		bounds[part.Name] = part.Bounds
+		if part.WrongBounds {
+			t.Errorf("partition %s has wrong bounds %s", part.Name, part.Bounds)
+		}
+	}
+	want := map[string]string{
+		"user_habit_checks_default":  "DEFAULT",
+		"user_habit_checks_y2024m05": "FOR VALUES FROM ('2024-05-01') TO ('2024-06-01')",
+		"user_habit_checks_y2024m06": "FOR VALUES FROM ('2024-06-01') TO ('2024-07-01')",
+	}
+	if len(bounds) != len(want) {
+		t.Errorf("got partitions %v, want %v", bounds, want)
+	}
+	for name, b := range want {
+		if bounds[name] != b {
+			t.Errorf("got bounds %q of %s, want %q", bounds[name], name, b)
+		}
+	}
+
+	// The legacy partition is dropped once its rows are moved
+	legacyExists := true
+	if err = p.QueryRow(ctx, `SELECT to_regclass('user_habit_checks_y2024m05_legacy') IS NOT NULL`).Scan(&legacyExists); err != nil {
+		t.Fatal(err)
+	}
+	if legacyExists {
+		t.Error("legacy partition isn't dropped")
+	}
+
+	// Partitions with correct bounds are kept as is
+	created, err := r.EnsureChecksMonthPartition(ctx, may)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Error("partition with correct bounds is created again")
+	}
+}
@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+)
+
+// GetTrashedByCreatorID returns deleted habits of the creator, the latest deleted first
+func (r pgRepo) GetTrashedByCreatorID(ctx context.Context, creatorID int64) ([]*hPkg.Habit, error) {
+	sql := `
+		SELECT h.id, h.active, h.archived, h.title, h.description, h.color, h.schedule, h.target_value, COALESCE(h.unit, ''), COALESCE(to_char(h.reminder_time, 'HH24:MI'), ''), h.creator_id, uh.is_public, uh.joined_at, h.created_at, h.updated_at, h.archived_at, h.deleted_at
+		FROM habits h
+		JOIN users_habits uh ON
+			h.id = uh.habit_id
+			AND uh.user_id = h.creator_id
+		WHERE
+			h.active IS FALSE
+			AND h.creator_id = $1
+		ORDER BY h.deleted_at DESC, h.id DESC
+	`
+	rows, err := uow.Conn(ctx, r.p).Query(ctx, sql, creatorID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	habits := []*hPkg.Habit{}
+	for rows.Next() {
+		h := &hPkg.Habit{}
+		err = rows.Scan(
+			&h.ID,
+			&h.Active,
+			&h.Archived,
+			&h.Title,
+			&h.Description,
+			&h.Color,
+			&h.Schedule,
+			&h.Target,
+			&h.Unit,
+			&h.ReminderTime,
+			&h.CreatorID,
+			&h.IsPublic,
+			&h.JoinedAt,
+			&h.CreatedAt,
+			&h.UpdatedAt,
+			&h.ArchivedAt,
+			&h.DeletedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		habits = append(habits, h)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return habits, nil
+}
+
+// Habits are purged with everything referencing them. Checks are deleted through the partitioned
+// table, so from all its partitions
+var purgeHabitsSQLs = []string{
+	`DELETE FROM habit_invites WHERE habit_id = ANY($1)`,
+	`DELETE FROM user_habit_checks WHERE habit_id = ANY($1)`,
+	`DELETE FROM users_habits WHERE habit_id = ANY($1)`,
+	`DELETE FROM habits WHERE id = ANY($1)`,
+}
+
+// PurgeTrashed permanently deletes no more than limit habits deleted before the specified time
+// along with their checks, returns the number of purged habits
+func (r pgRepo) PurgeTrashed(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	purged := 0
+
+	err := pgx.BeginFunc(ctx, uow.Conn(ctx, r.p), func(tx pgx.Tx) error {
+		sql := `
+			SELECT id FROM habits
+			WHERE
+				active IS FALSE
+				AND deleted_at <= $1
+			ORDER BY deleted_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		`
+		rows, err := tx.Query(ctx, sql, deletedBefore, limit)
+		if err != nil {
+			return err
+		}
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+
+		for _, sql := range purgeHabitsSQLs {
+			if _, err = tx.Exec(ctx, sql, ids); err != nil {
+				return err
+			}
+		}
+		purged = len(ids)
+
+		return nil
+	})
+
+	return purged, err
+}
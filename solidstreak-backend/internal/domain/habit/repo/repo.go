@@ -2,21 +2,62 @@ package repo
 
 import (
 	"context"
+	"time"
 
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
 	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repo interface {
-	Create(*hPkg.Habit) error
-	Update(*hPkg.Habit) error
-	GetByOwnerIDAndStatus(int64, hPkg.HabitStatus, bool) ([]*hPkg.Habit, error)
-	GetByIDAndOwnerID(int64, int64, bool) (*hPkg.Habit, error)
-	SetUserHabitCheck(*hPkg.HabitCheck) error
-	GetUserHabitsCompletedChecks(int64, []int64, *date.Date, *date.Date) ([]*hPkg.HabitCheck, error)
+	Create(context.Context, *hPkg.Habit) error
+	Update(context.Context, *hPkg.Habit) error
+	GetByOwnerIDAndStatus(context.Context, int64, hPkg.HabitStatus, bool) ([]*hPkg.Habit, error)
+	GetByIDAndOwnerID(context.Context, int64, int64, bool) (*hPkg.Habit, error)
+	ForEachByMemberID(ctx context.Context, userID int64, fn func(*hPkg.Habit) error) error
+	GetTrashedByCreatorID(context.Context, int64) ([]*hPkg.Habit, error)
+	PurgeTrashed(ctx context.Context, deletedBefore time.Time, limit int) (int, error)
+	ClaimDueReminders(context.Context, time.Time) ([]*hPkg.Reminder, error)
+	SetUserHabitCheck(context.Context, *hPkg.HabitCheck) error
+	RecalcChecksCompletion(context.Context, *hPkg.Habit) error
+	GetUserHabitsCompletedChecks(context.Context, int64, []int64, *date.Date, *date.Date) ([]*hPkg.HabitCheck, error)
+	GetUserHabitsChecks(context.Context, int64, []int64, *date.Date, *date.Date) ([]*hPkg.HabitCheck, error)
+	ForEachUserCheck(ctx context.Context, userID int64, fn func(*hPkg.HabitCheck) error) error
+	ImportHabits(context.Context, int64, []*hPkg.Habit) error
+	SetMemberVisibility(ctx context.Context, habitID, userID int64, isPublic bool) error
+	AddMember(ctx context.Context, habitID, userID int64, isPublic bool, joinedAt time.Time) error
+	RemoveMember(ctx context.Context, habitID, userID int64) error
+	GetParticipants(ctx context.Context, habitID int64) ([]*hPkg.Participant, error)
+	LockParticipants(ctx context.Context, habitID int64) error
+	ForEachMembershipByUserID(ctx context.Context, userID int64, fn func(*hPkg.Membership) error) error
+	CreateInvite(context.Context, *hPkg.Invite) error
+	GetInviteByToken(context.Context, string) (*hPkg.Invite, error)
 }
 
-func Init(c context.Context, p *pgxpool.Pool) Repo {
-	return initPGRepo(c, p)
+type Partition struct {
+	Name        string     `json:"name"`
+	Bounds      string     `json:"bounds"`
+	Rows        int64      `json:"rows"`
+	Month       *date.Date `json:"month,omitempty"`       // Month of the monthly partition, nil for other ones
+	WrongBounds bool       `json:"wrongBounds,omitempty"` // Monthly partition's bounds differ from its month, as legacy hand-written ones do
+}
+
+type PartitionRepo interface {
+	EnsureChecksDefaultPartition(context.Context) (bool, error)
+	EnsureChecksMonthPartition(context.Context, date.Date) (bool, error)
+	GetChecksPartitions(context.Context) ([]*Partition, error)
+}
+
+func Init(p *pgxpool.Pool) Repo {
+	return initPGRepo(p)
+}
+
+// InitMemory returns the repository keeping data in the in-memory storage instead of PostgreSQL
+func InitMemory(db *memdb.DB) Repo {
+	return initMemRepo(db)
+}
+
+func InitPartitionRepo(p *pgxpool.Pool) PartitionRepo {
+	return initPGRepo(p)
 }
@@ -0,0 +1,129 @@
+package habit
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+type ScheduleType string
+
+const (
+	Daily         ScheduleType = "daily"
+	Weekdays      ScheduleType = "weekdays"
+	TimesPerWeek  ScheduleType = "times_per_week"
+	TimesPerMonth ScheduleType = "times_per_month"
+	EveryNDays    ScheduleType = "every_n_days"
+)
+
+var ScheduleTypeMapping = map[string]ScheduleType{
+	string(Daily):         Daily,
+	string(Weekdays):      Weekdays,
+	string(TimesPerWeek):  TimesPerWeek,
+	string(TimesPerMonth): TimesPerMonth,
+	string(EveryNDays):    EveryNDays,
+}
+
+type Schedule struct {
+	Type     ScheduleType `json:"type"`
+	Weekdays []int        `json:"weekdays,omitempty"` // Scheduled days of week for "weekdays", 0 is Sunday
+	Times    int          `json:"times,omitempty"`    // Target number of checks per period for "times_per_week" and "times_per_month"
+	Interval int          `json:"interval,omitempty"` // Days between scheduled days for "every_n_days"
+}
+
+func DailySchedule() Schedule {
+	return Schedule{Type: Daily}
+}
+
+func (s Schedule) Equal(o Schedule) bool {
+	return s.Type == o.Type && slices.Equal(s.Weekdays, o.Weekdays) && s.Times == o.Times && s.Interval == o.Interval
+}
+
+func (s Schedule) Validate() error {
+	if _, ok := ScheduleTypeMapping[string(s.Type)]; !ok {
+		return errors.New("invalid habit schedule type")
+	}
+
+	if s.Type != Weekdays && len(s.Weekdays) > 0 {
+		return errors.New("habit schedule weekdays are allowed only for \"" + string(Weekdays) + "\" schedule")
+	}
+	if s.Type != TimesPerWeek && s.Type != TimesPerMonth && s.Times != 0 {
+		return errors.New("habit schedule times are allowed only for per week and per month schedules")
+	}
+	if s.Type != EveryNDays && s.Interval != 0 {
+		return errors.New("habit schedule interval is allowed only for \"" + string(EveryNDays) + "\" schedule")
+	}
+
+	switch s.Type {
+	case Weekdays:
+		if len(s.Weekdays) == 0 {
+			return errors.New("habit schedule weekdays are required")
+		}
+		for i, wd := range s.Weekdays {
+			if wd < 0 || wd > 6 {
+				return errors.New("invalid habit schedule weekday " + strconv.Itoa(wd))
+			}
+			if slices.Contains(s.Weekdays[:i], wd) {
+				return errors.New("duplicated habit schedule weekday " + strconv.Itoa(wd))
+			}
+		}
+	case TimesPerWeek:
+		if s.Times < 1 || s.Times > 7 {
+			return errors.New("habit schedule times per week must be between 1 and 7")
+		}
+	case TimesPerMonth:
+		if s.Times < 1 || s.Times > 31 {
+			return errors.New("habit schedule times per month must be between 1 and 31")
+		}
+	case EveryNDays:
+		if s.Interval < 1 || s.Interval > 365 {
+			return errors.New("habit schedule interval must be between 1 and 365 days")
+		}
+	}
+
+	return nil
+}
+
+// IsDueOn reports whether the habit is expected to be checked on the specified date.
+// Every day is due for per period schedules. Anchor is the date "every_n_days" schedule counts from
+func (s Schedule) IsDueOn(d, anchor date.Date) bool {
+	switch s.Type {
+	case Weekdays:
+		return slices.Contains(s.Weekdays, int(d.Weekday()))
+	case EveryNDays:
+		days := d.DaysSince(anchor)
+		return days >= 0 && days%s.Interval == 0
+	default:
+		return true
+	}
+}
+
+// StreakUnit returns the unit streaks are measured in: scheduled days or periods meeting the target
+func (s Schedule) StreakUnit() string {
+	switch s.Type {
+	case TimesPerWeek:
+		return "week"
+	case TimesPerMonth:
+		return "month"
+	default:
+		return "day"
+	}
+}
+
+// periodStart returns the first day of the per period schedule's period containing the date.
+// Weeks start on Monday
+func (s Schedule) periodStart(d date.Date) date.Date {
+	if s.Type == TimesPerMonth {
+		return d.MonthStart()
+	}
+	return d.AddDate(0, 0, -((int(d.Weekday()) + 6) % 7))
+}
+
+func (s Schedule) nextPeriodStart(d date.Date) date.Date {
+	if s.Type == TimesPerMonth {
+		return d.AddDate(0, 1, 0)
+	}
+	return d.AddDate(0, 0, 7)
+}
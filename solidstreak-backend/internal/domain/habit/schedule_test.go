@@ -0,0 +1,116 @@
+package habit
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+// day parses the date in UTC, as dates come from requests
+func day(t *testing.T, s string) date.Date {
+	t.Helper()
+	d, err := date.Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// localDay returns the date at midnight in the location, as dates of the server's today are
+func localDay(t *testing.T, s string, loc *time.Location) date.Date {
+	t.Helper()
+	d, err := time.ParseInLocation(time.DateOnly, s, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return date.New(d)
+}
+
+func location(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestScheduleIsDueOn(t *testing.T) {
+	berlin := location(t, "Europe/Berlin")
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		d        date.Date
+		anchor   date.Date
+		want     bool
+	}{
+		{"daily", DailySchedule(), day(t, "2024-03-05"), day(t, "2024-03-01"), true},
+		{"weekdays scheduled", Schedule{Type: Weekdays, Weekdays: []int{1, 3, 5}}, day(t, "2024-03-06"), day(t, "2024-03-01"), true},
+		{"weekdays not scheduled", Schedule{Type: Weekdays, Weekdays: []int{1, 3, 5}}, day(t, "2024-03-07"), day(t, "2024-03-01"), false},
+		{"weekdays sunday", Schedule{Type: Weekdays, Weekdays: []int{0}}, day(t, "2024-03-10"), day(t, "2024-03-01"), true},
+		{"times per week", Schedule{Type: TimesPerWeek, Times: 3}, day(t, "2024-03-07"), day(t, "2024-03-01"), true},
+		{"times per month", Schedule{Type: TimesPerMonth, Times: 10}, day(t, "2024-03-07"), day(t, "2024-03-01"), true},
+		{"every n days anchor", Schedule{Type: EveryNDays, Interval: 3}, day(t, "2024-03-01"), day(t, "2024-03-01"), true},
+		{"every n days due", Schedule{Type: EveryNDays, Interval: 3}, day(t, "2024-03-07"), day(t, "2024-03-01"), true},
+		{"every n days not due", Schedule{Type: EveryNDays, Interval: 3}, day(t, "2024-03-08"), day(t, "2024-03-01"), false},
+		{"every n days before anchor", Schedule{Type: EveryNDays, Interval: 3}, day(t, "2024-02-27"), day(t, "2024-03-01"), false},
+		{"every n days over leap day", Schedule{Type: EveryNDays, Interval: 2}, day(t, "2024-03-01"), day(t, "2024-02-28"), true},
+		{"every n days without leap day", Schedule{Type: EveryNDays, Interval: 2}, day(t, "2023-03-01"), day(t, "2023-02-28"), false},
+		{"every n days over DST start", Schedule{Type: EveryNDays, Interval: 7}, localDay(t, "2024-04-01", berlin), localDay(t, "2024-03-25", berlin), true},
+		{"every n days over DST end", Schedule{Type: EveryNDays, Interval: 2}, localDay(t, "2024-10-28", berlin), localDay(t, "2024-10-26", berlin), true},
+		{"every n days mixed locations", Schedule{Type: EveryNDays, Interval: 7}, localDay(t, "2024-04-01", berlin), day(t, "2024-03-25"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.schedule.IsDueOn(tt.d, tt.anchor); got != tt.want {
+				t.Errorf("IsDueOn(%s, %s) = %v, want %v", tt.d, tt.anchor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulePeriodStart(t *testing.T) {
+	berlin := location(t, "Europe/Berlin")
+	saoPaulo := location(t, "America/Sao_Paulo") // Skipped midnight on DST start
+
+	weekly := Schedule{Type: TimesPerWeek, Times: 3}
+	monthly := Schedule{Type: TimesPerMonth, Times: 10}
+
+	tests := []struct {
+		name     string
+		schedule Schedule
+		d        date.Date
+		want     string
+		wantNext string
+	}{
+		{"week from monday", weekly, day(t, "2024-03-04"), "2024-03-04", "2024-03-11"},
+		{"week from sunday", weekly, day(t, "2024-03-10"), "2024-03-04", "2024-03-11"},
+		{"week over month end", weekly, day(t, "2024-03-01"), "2024-02-26", "2024-03-04"},
+		{"week over year end", weekly, day(t, "2025-01-01"), "2024-12-30", "2025-01-06"},
+		{"week of DST start", weekly, localDay(t, "2024-03-31", berlin), "2024-03-25", "2024-04-01"},
+		{"week of DST end", weekly, localDay(t, "2024-10-27", berlin), "2024-10-21", "2024-10-28"},
+		{"week of skipped midnight", weekly, localDay(t, "2018-11-06", saoPaulo), "2018-11-05", "2018-11-12"},
+		{"week before skipped midnight", weekly, localDay(t, "2018-11-04", saoPaulo), "2018-10-29", "2018-11-05"},
+		{"month from first day", monthly, day(t, "2024-03-01"), "2024-03-01", "2024-04-01"},
+		{"month from last day", monthly, day(t, "2024-03-31"), "2024-03-01", "2024-04-01"},
+		{"leap february", monthly, day(t, "2024-02-29"), "2024-02-01", "2024-03-01"},
+		{"month of DST start", monthly, localDay(t, "2024-03-31", berlin), "2024-03-01", "2024-04-01"},
+		{"month of skipped midnight", monthly, localDay(t, "2018-11-20", saoPaulo), "2018-11-01", "2018-12-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.schedule.periodStart(tt.d)
+			if got.String() != tt.want {
+				t.Errorf("periodStart(%s) = %s, want %s", tt.d, got, tt.want)
+			}
+			if next := tt.schedule.nextPeriodStart(got); next.String() != tt.wantNext {
+				t.Errorf("nextPeriodStart(%s) = %s, want %s", got, next, tt.wantNext)
+			}
+		})
+	}
+}
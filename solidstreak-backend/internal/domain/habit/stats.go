@@ -0,0 +1,175 @@
+package habit
+
+import (
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+type Stats struct {
+	From           date.Date `json:"from"`
+	To             date.Date `json:"to"`
+	StreakUnit     string    `json:"streakUnit"`
+	CurrentStreak  int       `json:"currentStreak"`
+	LongestStreak  int       `json:"longestStreak"`
+	TotalCompleted int       `json:"totalCompleted"`
+	TotalDays      int       `json:"totalDays"`
+	Expected       int       `json:"expected"`
+	CompletionRate float64   `json:"completionRate"`
+}
+
+// outcome is the result of a scheduled day or a period of per period schedule
+type outcome struct {
+	hits    int  // checks counted towards the target
+	target  int  // checks expected
+	success bool // target is met
+	pending bool // target isn't met yet, but still can be today
+}
+
+// CalcStats calculates habit statistics over the [from, to] range by habit's completed checks.
+// The range is narrowed to the days the habit existed: from its creation, or from joining it
+// for shared habit's participants, up to today, or up to its archiving for archived habits,
+// which have no current streak. Dates are taken in the habit owner's location. Streaks are
+// counted in scheduled days or in periods meeting the target, so days out of schedule don't
+// break them, and neither does today's missing check yet.
+func CalcStats(h *Habit, checks []*HabitCheck, from, to, today date.Date, loc *time.Location) *Stats {
+	start := from
+	if created := date.NewIn(h.CreatedAt, loc); start.Before(created) {
+		start = created
+	}
+	if !h.JoinedAt.IsZero() {
+		if joined := date.NewIn(h.JoinedAt, loc); start.Before(joined) {
+			start = joined
+		}
+	}
+	end := to
+	if end.After(today) {
+		end = today
+	}
+	if h.Archived && h.ArchivedAt != nil {
+		if archived := date.NewIn(*h.ArchivedAt, loc); end.After(archived) {
+			end = archived
+		}
+	}
+
+	stats := &Stats{From: start, To: end, StreakUnit: h.Schedule.StreakUnit()}
+	if end.Before(start) {
+		return stats
+	}
+
+	completed := make(map[string]bool, len(checks))
+	for _, hc := range checks {
+		if hc.Completed {
+			completed[hc.CheckDate.String()] = true
+		}
+	}
+
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		stats.TotalDays++
+		if completed[d.String()] {
+			stats.TotalCompleted++
+		}
+	}
+
+	inProgress := !h.Archived && end.Compare(today) == 0
+
+	var outcomes []outcome
+	switch h.Schedule.Type {
+	case TimesPerWeek, TimesPerMonth:
+		outcomes = periodOutcomes(h.Schedule, completed, start, end, inProgress)
+	default:
+		outcomes = dayOutcomes(h.Schedule, completed, start, end, date.NewIn(h.CreatedAt, loc), inProgress)
+	}
+
+	hits, streak := 0, 0
+	for _, o := range outcomes {
+		hits += o.hits
+		if o.pending {
+			stats.Expected += o.hits
+			continue
+		}
+		stats.Expected += o.target
+		if !o.success {
+			streak = 0
+			continue
+		}
+		streak++
+		if streak > stats.LongestStreak {
+			stats.LongestStreak = streak
+		}
+	}
+
+	if stats.Expected > 0 {
+		stats.CompletionRate = float64(hits) / float64(stats.Expected)
+	}
+
+	if !h.Archived {
+		stats.CurrentStreak = streak
+	}
+
+	return stats
+}
+
+func dayOutcomes(s Schedule, completed map[string]bool, start, end, anchor date.Date, inProgress bool) []outcome {
+	outcomes := []outcome{}
+
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if !s.IsDueOn(d, anchor) {
+			continue
+		}
+		o := outcome{target: 1}
+		if completed[d.String()] {
+			o.hits, o.success = 1, true
+		} else if inProgress && d.Compare(end) == 0 {
+			o.pending = true
+		}
+		outcomes = append(outcomes, o)
+	}
+
+	return outcomes
+}
+
+// periodOutcomes evaluates every week or month overlapping the range. The target of a period
+// cut by the range is prorated, except for the current period, which has the full target
+// and stays pending until it's met
+func periodOutcomes(s Schedule, completed map[string]bool, start, end date.Date, inProgress bool) []outcome {
+	outcomes := []outcome{}
+
+	for ps := s.periodStart(start); !ps.After(end); ps = s.nextPeriodStart(ps) {
+		pe := s.nextPeriodStart(ps).AddDate(0, 0, -1)
+
+		from, to := ps, pe
+		if from.Before(start) {
+			from = start
+		}
+		if to.After(end) {
+			to = end
+		}
+
+		done := 0
+		for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+			if completed[d.String()] {
+				done++
+			}
+		}
+
+		var (
+			days       = to.DaysSince(from) + 1
+			periodDays = pe.DaysSince(ps) + 1
+			current    = inProgress && pe.After(end)
+			target     = s.Times
+		)
+		if !current && days < periodDays {
+			target = (s.Times*days + periodDays - 1) / periodDays
+		}
+		if target > periodDays {
+			target = periodDays
+		}
+
+		o := outcome{hits: min(done, target), target: target, success: done >= target}
+		o.pending = current && !o.success
+		outcomes = append(outcomes, o)
+	}
+
+	return outcomes
+}
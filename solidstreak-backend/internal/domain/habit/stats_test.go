@@ -0,0 +1,179 @@
+package habit
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// completedChecks returns completed checks of the habit on the dates
+func completedChecks(t *testing.T, dates ...string) []*HabitCheck {
+	t.Helper()
+	checks := make([]*HabitCheck, 0, len(dates))
+	for _, s := range dates {
+		checks = append(checks, &HabitCheck{CheckDate: day(t, s), Completed: true})
+	}
+	return checks
+}
+
+func moment(t *testing.T, s string) time.Time {
+	t.Helper()
+	m, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestCalcStats(t *testing.T) {
+	berlin := location(t, "Europe/Berlin")
+	archivedAt := moment(t, "2024-03-05T12:00:00Z")
+	lateArchivedAt := moment(t, "2024-03-31T22:30:00Z") // 1 April in Berlin
+
+	tests := []struct {
+		name     string
+		habit    Habit
+		checks   []*HabitCheck
+		from, to string
+		today    string
+		loc      *time.Location
+		want     Stats
+	}{
+		{
+			name:   "daily",
+			habit:  Habit{Schedule: DailySchedule(), CreatedAt: moment(t, "2024-03-01T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-01", "2024-03-02", "2024-03-03", "2024-03-05", "2024-03-06", "2024-03-07"),
+			from:   "2024-03-01", to: "2024-03-07", today: "2024-03-10",
+			want: Stats{From: day(t, "2024-03-01"), To: day(t, "2024-03-07"), StreakUnit: "day", CurrentStreak: 3, LongestStreak: 3, TotalCompleted: 6, TotalDays: 7, Expected: 7, CompletionRate: 6.0 / 7},
+		},
+		{
+			name:   "daily without today's check yet",
+			habit:  Habit{Schedule: DailySchedule(), CreatedAt: moment(t, "2024-03-01T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-01", "2024-03-02", "2024-03-03", "2024-03-04"),
+			from:   "2024-03-01", to: "2024-03-31", today: "2024-03-05",
+			want: Stats{From: day(t, "2024-03-01"), To: day(t, "2024-03-05"), StreakUnit: "day", CurrentStreak: 4, LongestStreak: 4, TotalCompleted: 4, TotalDays: 5, Expected: 4, CompletionRate: 1},
+		},
+		{
+			name:   "weekdays",
+			habit:  Habit{Schedule: Schedule{Type: Weekdays, Weekdays: []int{1, 3, 5}}, CreatedAt: moment(t, "2024-03-01T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-04", "2024-03-06", "2024-03-07"),
+			from:   "2024-03-04", to: "2024-03-10", today: "2024-03-20",
+			want: Stats{From: day(t, "2024-03-04"), To: day(t, "2024-03-10"), StreakUnit: "day", CurrentStreak: 0, LongestStreak: 2, TotalCompleted: 3, TotalDays: 7, Expected: 3, CompletionRate: 2.0 / 3},
+		},
+		{
+			name:   "every n days counted from creation",
+			habit:  Habit{Schedule: Schedule{Type: EveryNDays, Interval: 3}, CreatedAt: moment(t, "2024-03-01T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-01", "2024-03-04", "2024-03-07"),
+			from:   "2024-03-02", to: "2024-03-10", today: "2024-03-10",
+			want: Stats{From: day(t, "2024-03-02"), To: day(t, "2024-03-10"), StreakUnit: "day", CurrentStreak: 2, LongestStreak: 2, TotalCompleted: 2, TotalDays: 9, Expected: 2, CompletionRate: 1},
+		},
+		{
+			name:   "times per week",
+			habit:  Habit{Schedule: Schedule{Type: TimesPerWeek, Times: 3}, CreatedAt: moment(t, "2024-03-04T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-04", "2024-03-05", "2024-03-06", "2024-03-11"),
+			from:   "2024-03-04", to: "2024-03-17", today: "2024-03-20",
+			want: Stats{From: day(t, "2024-03-04"), To: day(t, "2024-03-17"), StreakUnit: "week", CurrentStreak: 0, LongestStreak: 1, TotalCompleted: 4, TotalDays: 14, Expected: 6, CompletionRate: 4.0 / 6},
+		},
+		{
+			name:   "times per week in current week",
+			habit:  Habit{Schedule: Schedule{Type: TimesPerWeek, Times: 3}, CreatedAt: moment(t, "2024-03-04T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-04", "2024-03-05", "2024-03-06", "2024-03-11"),
+			from:   "2024-03-04", to: "2024-03-31", today: "2024-03-13",
+			want: Stats{From: day(t, "2024-03-04"), To: day(t, "2024-03-13"), StreakUnit: "week", CurrentStreak: 1, LongestStreak: 1, TotalCompleted: 4, TotalDays: 10, Expected: 4, CompletionRate: 1},
+		},
+		{
+			name:   "times per month prorated from creation",
+			habit:  Habit{Schedule: Schedule{Type: TimesPerMonth, Times: 10}, CreatedAt: moment(t, "2024-03-16T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-16", "2024-03-18", "2024-03-20", "2024-03-22", "2024-03-24", "2024-03-26"),
+			from:   "2024-03-01", to: "2024-03-31", today: "2024-04-10",
+			want: Stats{From: day(t, "2024-03-16"), To: day(t, "2024-03-31"), StreakUnit: "month", CurrentStreak: 1, LongestStreak: 1, TotalCompleted: 6, TotalDays: 16, Expected: 6, CompletionRate: 1},
+		},
+		{
+			name:   "from joining for participants",
+			habit:  Habit{Schedule: DailySchedule(), CreatedAt: moment(t, "2024-03-01T08:00:00Z"), JoinedAt: moment(t, "2024-03-05T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-05", "2024-03-06"),
+			from:   "2024-03-01", to: "2024-03-06", today: "2024-03-10",
+			want: Stats{From: day(t, "2024-03-05"), To: day(t, "2024-03-06"), StreakUnit: "day", CurrentStreak: 2, LongestStreak: 2, TotalCompleted: 2, TotalDays: 2, Expected: 2, CompletionRate: 1},
+		},
+		{
+			name:   "archived",
+			habit:  Habit{Archived: true, ArchivedAt: &archivedAt, Schedule: DailySchedule(), CreatedAt: moment(t, "2024-03-01T08:00:00Z"), UpdatedAt: moment(t, "2024-03-20T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-01", "2024-03-02", "2024-03-03", "2024-03-04", "2024-03-05"),
+			from:   "2024-03-01", to: "2024-03-31", today: "2024-03-20",
+			want: Stats{From: day(t, "2024-03-01"), To: day(t, "2024-03-05"), StreakUnit: "day", CurrentStreak: 0, LongestStreak: 5, TotalCompleted: 5, TotalDays: 5, Expected: 5, CompletionRate: 1},
+		},
+		{
+			name:   "archived before range",
+			habit:  Habit{Archived: true, ArchivedAt: &archivedAt, Schedule: DailySchedule(), CreatedAt: moment(t, "2024-03-01T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-05"),
+			from:   "2024-03-10", to: "2024-03-31", today: "2024-03-20",
+			want: Stats{From: day(t, "2024-03-10"), To: day(t, "2024-03-05"), StreakUnit: "day"},
+		},
+		{
+			name:  "future range",
+			habit: Habit{Schedule: DailySchedule(), CreatedAt: moment(t, "2024-03-01T08:00:00Z")},
+			from:  "2024-04-01", to: "2024-04-30", today: "2024-03-20",
+			want: Stats{From: day(t, "2024-04-01"), To: day(t, "2024-03-20"), StreakUnit: "day"},
+		},
+		{
+			name:  "range before creation",
+			habit: Habit{Schedule: Schedule{Type: TimesPerWeek, Times: 2}, CreatedAt: moment(t, "2024-03-10T08:00:00Z")},
+			from:  "2024-03-01", to: "2024-03-05", today: "2024-03-20",
+			want: Stats{From: day(t, "2024-03-10"), To: day(t, "2024-03-05"), StreakUnit: "week"},
+		},
+		{
+			name:   "over DST start",
+			habit:  Habit{Schedule: DailySchedule(), CreatedAt: moment(t, "2024-03-24T23:30:00Z")}, // 25 March in Berlin
+			checks: completedChecks(t, "2024-03-25", "2024-03-26", "2024-03-27", "2024-03-28", "2024-03-29", "2024-03-30", "2024-03-31", "2024-04-01", "2024-04-02"),
+			from:   "2024-03-20", to: "2024-04-02", today: "2024-04-02", loc: berlin,
+			want: Stats{From: day(t, "2024-03-25"), To: day(t, "2024-04-02"), StreakUnit: "day", CurrentStreak: 9, LongestStreak: 9, TotalCompleted: 9, TotalDays: 9, Expected: 9, CompletionRate: 1},
+		},
+		{
+			name:   "weeks over DST end",
+			habit:  Habit{Schedule: Schedule{Type: TimesPerWeek, Times: 2}, CreatedAt: moment(t, "2024-10-20T23:30:00Z")}, // 21 October in Berlin
+			checks: completedChecks(t, "2024-10-21", "2024-10-27", "2024-10-28", "2024-10-29"),
+			from:   "2024-10-01", to: "2024-11-03", today: "2024-11-10", loc: berlin,
+			want: Stats{From: day(t, "2024-10-21"), To: day(t, "2024-11-03"), StreakUnit: "week", CurrentStreak: 2, LongestStreak: 2, TotalCompleted: 4, TotalDays: 14, Expected: 4, CompletionRate: 1},
+		},
+		{
+			name:   "archived in owner's location",
+			habit:  Habit{Archived: true, ArchivedAt: &lateArchivedAt, Schedule: DailySchedule(), CreatedAt: moment(t, "2024-03-29T08:00:00Z")},
+			checks: completedChecks(t, "2024-03-29", "2024-03-30", "2024-03-31", "2024-04-01"),
+			from:   "2024-03-01", to: "2024-04-30", today: "2024-04-20", loc: berlin,
+			want: Stats{From: day(t, "2024-03-29"), To: day(t, "2024-04-01"), StreakUnit: "day", LongestStreak: 4, TotalCompleted: 4, TotalDays: 4, Expected: 4, CompletionRate: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := tt.loc
+			if loc == nil {
+				loc = time.UTC
+			}
+
+			got := CalcStats(&tt.habit, tt.checks, day(t, tt.from), day(t, tt.to), day(t, tt.today), loc)
+
+			if math.Abs(got.CompletionRate-tt.want.CompletionRate) > 1e-9 {
+				t.Errorf("got completion rate %v, want %v", got.CompletionRate, tt.want.CompletionRate)
+			}
+			got.CompletionRate = tt.want.CompletionRate
+			if got.From.Compare(tt.want.From) != 0 || got.To.Compare(tt.want.To) != 0 {
+				t.Errorf("got range [%s, %s], want [%s, %s]", got.From, got.To, tt.want.From, tt.want.To)
+			}
+			got.From, got.To = tt.want.From, tt.want.To
+			if *got != tt.want {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalcStatsArchivedWithoutArchivingTime(t *testing.T) {
+	// Habits archived before archiving time was tracked have their range up to today
+	h := &Habit{Archived: true, Schedule: DailySchedule(), CreatedAt: moment(t, "2024-03-01T08:00:00Z")}
+
+	got := CalcStats(h, nil, day(t, "2024-03-01"), day(t, "2024-03-31"), day(t, "2024-03-10"), time.UTC)
+	if got.To.Compare(day(t, "2024-03-10")) != 0 || got.CurrentStreak != 0 {
+		t.Errorf("unexpected stats %+v", got)
+	}
+}
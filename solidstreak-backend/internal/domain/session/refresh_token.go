@@ -0,0 +1,60 @@
+package session
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Prefix of refresh token secrets, which tells them apart from session and access tokens
+const Prefix = "ssr_"
+
+// RefreshToken is exchanged once for a new session and the next refresh token of the family.
+// Its secret is given to the client only, the hash is stored
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	FamilyID  string // Shared by tokens rotated since the login, so they're revoked together
+	Hash      string
+	LoginAt   time.Time // When the family's first session started, which caps its lifetime
+	ExpiresAt time.Time
+	RotatedAt *time.Time // When it's exchanged, reusing it afterwards revokes the family
+	CreatedAt time.Time
+}
+
+// New returns the first refresh token of a login along with its secret
+func New(userID int64, loginAt, expiresAt time.Time) (*RefreshToken, string, error) {
+	return newToken(userID, uuid.NewString(), loginAt, expiresAt)
+}
+
+// Next returns the refresh token replacing the rotated one along with its secret
+func (t *RefreshToken) Next(expiresAt time.Time) (*RefreshToken, string, error) {
+	return newToken(t.UserID, t.FamilyID, t.LoginAt, expiresAt)
+}
+
+func newToken(userID int64, familyID string, loginAt, expiresAt time.Time) (*RefreshToken, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	secret := Prefix + base64.RawURLEncoding.EncodeToString(b)
+
+	return &RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		Hash:      HashSecret(secret),
+		LoginAt:   loginAt,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}, secret, nil
+}
+
+// HashSecret returns the hash the refresh token with the secret is stored by
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
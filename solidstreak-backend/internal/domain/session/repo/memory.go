@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	sessPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// memRepo keeps refresh tokens in memory with the same semantics as pgRepo
+type memRepo struct {
+	db *memdb.DB
+}
+
+func initMemRepo(db *memdb.DB) *memRepo {
+	return &memRepo{db}
+}
+
+func (r memRepo) Create(ctx context.Context, t *sessPkg.RefreshToken) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	t.ID = r.db.NextID("refresh_tokens")
+	copied := *t
+	r.db.RefreshTokens[t.ID] = &copied
+
+	return nil
+}
+
+func (r memRepo) GetByHash(ctx context.Context, hash string) (*sessPkg.RefreshToken, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	for _, t := range r.db.RefreshTokens {
+		if t.Hash == hash {
+			copied := *t
+			return &copied, nil
+		}
+	}
+
+	return nil, apperrors.ErrNotFound("couldn't find refresh token")
+}
+
+func (r memRepo) Rotate(ctx context.Context, id int64, at time.Time) (bool, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	t, ok := r.db.RefreshTokens[id]
+	if !ok || t.RotatedAt != nil {
+		return false, nil
+	}
+	t.RotatedAt = &at
+
+	return true, nil
+}
+
+func (r memRepo) DeleteFamily(ctx context.Context, familyID string) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	for id, t := range r.db.RefreshTokens {
+		if t.FamilyID == familyID {
+			delete(r.db.RefreshTokens, id)
+		}
+	}
+
+	return nil
+}
+
+func (r memRepo) DeleteExpired(ctx context.Context, userID int64, now time.Time) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	for id, t := range r.db.RefreshTokens {
+		if t.UserID == userID && !t.ExpiresAt.After(now) {
+			delete(r.db.RefreshTokens, id)
+		}
+	}
+
+	return nil
+}
+
+func (r memRepo) MarkInitDataUsed(ctx context.Context, hash string, expiresAt *time.Time, now time.Time) (bool, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	for h, exp := range r.db.UsedInitData {
+		if exp != nil && !exp.After(now) {
+			delete(r.db.UsedInitData, h)
+		}
+	}
+
+	if _, ok := r.db.UsedInitData[hash]; ok {
+		return false, nil
+	}
+	r.db.UsedInitData[hash] = expiresAt
+
+	return true, nil
+}
@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	sessPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+type pgRepo struct {
+	pool *pgxpool.Pool
+}
+
+func initPGRepo(p *pgxpool.Pool) *pgRepo {
+	return &pgRepo{p}
+}
+
+func (r pgRepo) Create(ctx context.Context, t *sessPkg.RefreshToken) error {
+	sql := `
+		INSERT INTO refresh_tokens (user_id, family_id, hash, login_at, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
+		sql,
+		t.UserID,
+		t.FamilyID,
+		t.Hash,
+		t.LoginAt,
+		t.ExpiresAt,
+		t.CreatedAt,
+	).Scan(&t.ID)
+
+	return err
+}
+
+func (r pgRepo) GetByHash(ctx context.Context, hash string) (*sessPkg.RefreshToken, error) {
+	sql := `
+		SELECT id, user_id, family_id, hash, login_at, expires_at, rotated_at, created_at
+		FROM refresh_tokens
+		WHERE hash = $1
+	`
+	t := &sessPkg.RefreshToken{}
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
+		sql,
+		hash,
+	).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.FamilyID,
+		&t.Hash,
+		&t.LoginAt,
+		&t.ExpiresAt,
+		&t.RotatedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apperrors.ErrNotFound("couldn't find refresh token")
+		}
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Rotate marks the refresh token exchanged, unless it's already been. Returns if it's marked, so
+// concurrent exchanges of the token couldn't both succeed
+func (r pgRepo) Rotate(ctx context.Context, id int64, at time.Time) (bool, error) {
+	sql := `UPDATE refresh_tokens SET rotated_at = $1 WHERE id = $2 AND rotated_at IS NULL`
+	tag, err := uow.Conn(ctx, r.pool).Exec(
+		ctx,
+		sql,
+		at,
+		id,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// DeleteFamily revokes all refresh tokens rotated since the same login
+func (r pgRepo) DeleteFamily(ctx context.Context, familyID string) error {
+	sql := `DELETE FROM refresh_tokens WHERE family_id = $1`
+	_, err := uow.Conn(ctx, r.pool).Exec(ctx, sql, familyID)
+
+	return err
+}
+
+// DeleteExpired deletes the user's refresh tokens which couldn't be exchanged anymore
+func (r pgRepo) DeleteExpired(ctx context.Context, userID int64, now time.Time) error {
+	sql := `DELETE FROM refresh_tokens WHERE user_id = $1 AND expires_at <= $2`
+	_, err := uow.Conn(ctx, r.pool).Exec(ctx, sql, userID, now)
+
+	return err
+}
+
+// MarkInitDataUsed records initData by its hash until it expires, never if expiresAt is nil, and
+// reports whether it's recorded for the first time. Expired records are cleaned up here
+func (r pgRepo) MarkInitDataUsed(ctx context.Context, hash string, expiresAt *time.Time, now time.Time) (bool, error) {
+	conn := uow.Conn(ctx, r.pool)
+
+	sql := `DELETE FROM used_init_data WHERE expires_at <= $1`
+	if _, err := conn.Exec(ctx, sql, now); err != nil {
+		return false, err
+	}
+
+	sql = `INSERT INTO used_init_data (hash, expires_at) VALUES ($1, $2) ON CONFLICT (hash) DO NOTHING`
+	tag, err := conn.Exec(ctx, sql, hash, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
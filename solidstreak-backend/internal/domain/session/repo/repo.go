@@ -0,0 +1,28 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	sessPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type Repo interface {
+	Create(context.Context, *sessPkg.RefreshToken) error
+	GetByHash(context.Context, string) (*sessPkg.RefreshToken, error)
+	Rotate(ctx context.Context, id int64, at time.Time) (bool, error)
+	DeleteFamily(ctx context.Context, familyID string) error
+	DeleteExpired(ctx context.Context, userID int64, now time.Time) error
+	MarkInitDataUsed(ctx context.Context, hash string, expiresAt *time.Time, now time.Time) (bool, error)
+}
+
+func Init(p *pgxpool.Pool) Repo {
+	return initPGRepo(p)
+}
+
+// InitMemory returns the repository keeping data in the in-memory storage instead of PostgreSQL
+func InitMemory(db *memdb.DB) Repo {
+	return initMemRepo(db)
+}
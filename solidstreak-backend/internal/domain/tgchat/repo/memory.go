@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	tcPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// memRepo keeps telegram chats in memory with the same semantics as pgRepo
+type memRepo struct {
+	db *memdb.DB
+}
+
+func initMemRepo(db *memdb.DB) *memRepo {
+	return &memRepo{db}
+}
+
+func (r memRepo) Upsert(ctx context.Context, c *tcPkg.Chat) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	if stored, ok := r.db.TgChats[c.TgID]; ok {
+		c.CreatedAt = stored.CreatedAt
+	}
+	copied := *c
+	r.db.TgChats[c.TgID] = &copied
+
+	return nil
+}
+
+func (r memRepo) GetByUserID(ctx context.Context, userID int64) (*tcPkg.Chat, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	u, ok := r.db.Users[userID]
+	if !ok {
+		return nil, apperrors.ErrNotFound("couldn't find telegram chat of user")
+	}
+
+	var found *tcPkg.Chat
+	for _, c := range r.db.TgChats {
+		if c.UserID != userID {
+			continue
+		}
+		switch {
+		case found == nil:
+			found = c
+		case (c.TgID == u.TgID) != (found.TgID == u.TgID):
+			if c.TgID == u.TgID {
+				found = c
+			}
+		case c.CreatedAt.After(found.CreatedAt):
+			found = c
+		}
+	}
+	if found == nil {
+		return nil, apperrors.ErrNotFound("couldn't find telegram chat of user")
+	}
+
+	copied := *found
+	return &copied, nil
+}
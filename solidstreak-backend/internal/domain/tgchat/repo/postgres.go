@@ -3,62 +3,68 @@ package repo
 import (
 	"context"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
 	tcPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
 )
 
 type pgRepo struct {
-	ctx  context.Context
 	pool *pgxpool.Pool
 }
 
-func initPGRepo(c context.Context, p *pgxpool.Pool) *pgRepo {
-	return &pgRepo{c, p}
+func initPGRepo(p *pgxpool.Pool) *pgRepo {
+	return &pgRepo{p}
 }
 
-func (r pgRepo) IsExistsByTgID(tgID int64) (bool, error) {
-	exists := false
-
-	sql := `SELECT EXISTS(SELECT 1 FROM tg_chats WHERE tg_id = $1)`
-	err := r.pool.QueryRow(
-		r.ctx,
-		sql,
-		tgID,
-	).Scan(&exists)
-	if err != nil {
-		return false, err
-	}
-
-	return exists, nil
-}
-
-func (r pgRepo) Create(tc *tcPkg.Chat) error {
-	sql := `INSERT INTO tg_chats (tg_id, user_id, created_at) VALUES ($1, $2, $3)`
-	_, err := r.pool.Exec(
-		r.ctx,
+// Upsert creates the chat or binds the existing one to chat's user
+func (r pgRepo) Upsert(ctx context.Context, c *tcPkg.Chat) error {
+	sql := `
+		INSERT INTO tg_chats (tg_id, user_id, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (tg_id) DO UPDATE SET
+			user_id = EXCLUDED.user_id
+		RETURNING created_at
+	`
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
 		sql,
-		tc.TgID,
-		tc.UserID,
-		tc.CreatedAt,
-	)
+		c.TgID,
+		c.UserID,
+		c.CreatedAt,
+	).Scan(&c.CreatedAt)
 
 	return err
 }
 
-func (r pgRepo) Update(c *tcPkg.Chat) error {
+// GetByUserID returns user's private chat with the bot, or the latest other chat if there is none
+func (r pgRepo) GetByUserID(ctx context.Context, userID int64) (*tcPkg.Chat, error) {
+	c := &tcPkg.Chat{}
+
 	sql := `
-		UPDATE tg_chats SET
-			user_id = $1
-		WHERE tg_id = $2
-		RETURNING created_at
+		SELECT tc.tg_id, tc.user_id, tc.created_at
+		FROM tg_chats tc
+		JOIN users u ON u.id = tc.user_id
+		WHERE tc.user_id = $1
+		ORDER BY tc.tg_id = u.tg_id DESC, tc.created_at DESC
+		LIMIT 1
 	`
-	err := r.pool.QueryRow(
-		r.ctx,
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
 		sql,
-		c.UserID,
-		c.TgID,
-	).Scan(&c.CreatedAt)
+		userID,
+	).Scan(
+		&c.TgID,
+		&c.UserID,
+		&c.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, apperrors.ErrNotFound("couldn't find telegram chat of user")
+		}
+		return nil, err
+	}
 
-	return err
+	return c, nil
 }
@@ -3,16 +3,21 @@ package repo
 import (
 	"context"
 
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
 	tcPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/tgchat"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repo interface {
-	IsExistsByTgID(int64) (bool, error)
-	Create(*tcPkg.Chat) error
-	Update(*tcPkg.Chat) error
+	Upsert(context.Context, *tcPkg.Chat) error
+	GetByUserID(context.Context, int64) (*tcPkg.Chat, error)
 }
 
-func Init(c context.Context, p *pgxpool.Pool) Repo {
-	return initPGRepo(c, p)
+func Init(p *pgxpool.Pool) Repo {
+	return initPGRepo(p)
+}
+
+// InitMemory returns the repository keeping data in the in-memory storage instead of PostgreSQL
+func InitMemory(db *memdb.DB) Repo {
+	return initMemRepo(db)
 }
@@ -0,0 +1,209 @@
+package repo
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+// memRepo keeps users in memory with the same semantics as pgRepo
+type memRepo struct {
+	db *memdb.DB
+}
+
+func initMemRepo(db *memdb.DB) *memRepo {
+	return &memRepo{db}
+}
+
+func copyUser(u *usrPkg.User) *usrPkg.User {
+	copied := *u
+	if u.DeletionScheduledAt != nil {
+		t := *u.DeletionScheduledAt
+		copied.DeletionScheduledAt = &t
+	}
+	return &copied
+}
+
+func (r memRepo) getByTgID(tgID int64) *usrPkg.User {
+	for _, u := range r.db.Users {
+		if u.TgID == tgID {
+			return u
+		}
+	}
+	return nil
+}
+
+func (r memRepo) Upsert(ctx context.Context, u *usrPkg.User) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	stored := r.getByTgID(u.TgID)
+	if stored == nil {
+		u.ID = r.db.NextID("users")
+		r.db.Users[u.ID] = copyUser(u)
+		return nil
+	}
+
+	stored.TgUsername = u.TgUsername
+	stored.TgFirstName = u.TgFirstName
+	stored.TgLastName = u.TgLastName
+	stored.TgLangCode = u.TgLangCode
+	stored.TgIsBot = u.TgIsBot
+	if stored.Timezone == "" {
+		stored.Timezone = u.Timezone
+	}
+	*u = *copyUser(stored)
+
+	return nil
+}
+
+func (r memRepo) UpdateTimezone(ctx context.Context, u *usrPkg.User) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	if stored, ok := r.db.Users[u.ID]; ok {
+		stored.Timezone = u.Timezone
+	}
+
+	return nil
+}
+
+func (r memRepo) GetByID(ctx context.Context, ID int64) (*usrPkg.User, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	u, ok := r.db.Users[ID]
+	if !ok {
+		return nil, apperrors.ErrNotFound("couldn't find user")
+	}
+
+	return copyUser(u), nil
+}
+
+func (r memRepo) GetByTgID(ctx context.Context, tgID int64) (*usrPkg.User, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	u := r.getByTgID(tgID)
+	if u == nil {
+		return nil, apperrors.ErrNotFound("couldn't find user")
+	}
+
+	return copyUser(u), nil
+}
+
+func (r memRepo) UpdateDeletionScheduledAt(ctx context.Context, u *usrPkg.User) error {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	if stored, ok := r.db.Users[u.ID]; ok {
+		stored.DeletionScheduledAt = copyUser(u).DeletionScheduledAt
+	}
+
+	return nil
+}
+
+func (r memRepo) GetScheduledForDeletion(ctx context.Context, now time.Time) ([]*usrPkg.User, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	users := []*usrPkg.User{}
+	for _, u := range r.db.Users {
+		if u.DeletionScheduledAt != nil && !u.DeletionScheduledAt.After(now) {
+			users = append(users, copyUser(u))
+		}
+	}
+	slices.SortFunc(users, func(a, b *usrPkg.User) int {
+		return a.DeletionScheduledAt.Compare(*b.DeletionScheduledAt)
+	})
+
+	return users, nil
+}
+
+func (r memRepo) Delete(ctx context.Context, id int64, now time.Time) (bool, error) {
+	r.db.Lock()
+	defer r.db.Unlock()
+
+	if u, ok := r.db.Users[id]; !ok || u.DeletionScheduledAt == nil || u.DeletionScheduledAt.After(now) {
+		return false, nil
+	}
+
+	// Shared habits created by the deleted user are handed over to their longest participant
+	for habitID, h := range r.db.Habits {
+		if h.CreatorID != id || !h.Active {
+			continue
+		}
+		var successor *memdb.MemberKey
+		for k, m := range r.db.Members {
+			if k.HabitID != habitID || k.UserID == id || !m.Active {
+				continue
+			}
+			if successor == nil {
+				successor = &k
+				continue
+			}
+			sm := r.db.Members[*successor]
+			if c := cmp.Or(m.JoinedAt.Compare(sm.JoinedAt), cmp.Compare(k.UserID, successor.UserID)); c < 0 {
+				successor = &k
+			}
+		}
+		if successor != nil {
+			h.CreatorID = successor.UserID
+		}
+	}
+
+	// Everything left created by the user is solely owned by them
+	owned := func(habitID int64) bool {
+		h, ok := r.db.Habits[habitID]
+		return ok && h.CreatorID == id
+	}
+	for token, i := range r.db.Invites {
+		if i.CreatorID == id || owned(i.HabitID) {
+			delete(r.db.Invites, token)
+		}
+	}
+	for k := range r.db.Checks {
+		if k.UserID == id || owned(k.HabitID) {
+			delete(r.db.Checks, k)
+		}
+	}
+	for k := range r.db.Members {
+		if k.UserID == id || owned(k.HabitID) {
+			delete(r.db.Members, k)
+		}
+	}
+	for habitID := range r.db.Habits {
+		if owned(habitID) {
+			delete(r.db.Habits, habitID)
+		}
+	}
+	for k := range r.db.Friendships {
+		if k.LesserID == id || k.GreaterID == id {
+			delete(r.db.Friendships, k)
+		}
+	}
+	for tokenID, t := range r.db.AccessTokens {
+		if t.UserID == id {
+			delete(r.db.AccessTokens, tokenID)
+		}
+	}
+	for tokenID, t := range r.db.RefreshTokens {
+		if t.UserID == id {
+			delete(r.db.RefreshTokens, tokenID)
+		}
+	}
+	for tgID, c := range r.db.TgChats {
+		if c.UserID == id {
+			delete(r.db.TgChats, tgID)
+		}
+	}
+	delete(r.db.Users, id)
+
+	return true, nil
+}
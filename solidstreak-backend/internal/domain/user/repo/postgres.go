@@ -6,40 +6,37 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
 	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
 
 	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
 )
 
 type pgRepo struct {
-	ctx  context.Context
 	pool *pgxpool.Pool
 }
 
-func initPGRepo(c context.Context, p *pgxpool.Pool) *pgRepo {
-	return &pgRepo{c, p}
+func initPGRepo(p *pgxpool.Pool) *pgRepo {
+	return &pgRepo{p}
 }
 
-func (r pgRepo) IsExists(u *usrPkg.User) (bool, error) {
-	exists := false
-
-	sql := `SELECT EXISTS(SELECT 1 FROM users WHERE tg_id = $1)`
-	err := r.pool.QueryRow(r.ctx, sql, u.TgID).Scan(&exists)
-	if err != nil {
-		return false, err
-	}
-
-	return exists, nil
-}
-
-func (r pgRepo) Create(u *usrPkg.User) error {
+// Upsert creates the user or updates Telegram profile of the existing one by Telegram ID.
+// User's time zone is set only if it isn't known yet
+func (r pgRepo) Upsert(ctx context.Context, u *usrPkg.User) error {
 	sql := `
-		INSERT INTO users (tg_id, tg_username, tg_first_name, tg_last_name, tg_lang_code, tg_is_bot, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id
+		INSERT INTO users (tg_id, tg_username, tg_first_name, tg_last_name, tg_lang_code, tg_is_bot, timezone, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8)
+		ON CONFLICT (tg_id) DO UPDATE SET
+			tg_username = EXCLUDED.tg_username,
+			tg_first_name = EXCLUDED.tg_first_name,
+			tg_last_name = EXCLUDED.tg_last_name,
+			tg_lang_code = EXCLUDED.tg_lang_code,
+			tg_is_bot = EXCLUDED.tg_is_bot,
+			timezone = COALESCE(users.timezone, EXCLUDED.timezone)
+		RETURNING id, COALESCE(timezone, ''), created_at, deletion_scheduled_at
 	`
-	err := r.pool.QueryRow(
-		r.ctx,
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
 		sql,
 		u.TgID,
 		u.TgUsername,
@@ -47,49 +44,39 @@ func (r pgRepo) Create(u *usrPkg.User) error {
 		u.TgLastName,
 		u.TgLangCode,
 		u.TgIsBot,
+		u.Timezone,
 		u.CreatedAt,
-	).Scan(&u.ID)
+	).Scan(
+		&u.ID,
+		&u.Timezone,
+		&u.CreatedAt,
+		&u.DeletionScheduledAt,
+	)
 
 	return err
 }
 
-func (r pgRepo) Update(u *usrPkg.User) error {
-	sql := `
-		UPDATE users SET
-			tg_username = $1,
-			tg_first_name = $2,
-			tg_last_name = $3,
-			tg_lang_code = $4,
-			tg_is_bot = $5
-		WHERE tg_id = $6
-		RETURNING id, created_at
-	`
-	err := r.pool.QueryRow(
-		r.ctx,
+func (r pgRepo) UpdateTimezone(ctx context.Context, u *usrPkg.User) error {
+	sql := `UPDATE users SET timezone = NULLIF($1, '') WHERE id = $2`
+	_, err := uow.Conn(ctx, r.pool).Exec(
+		ctx,
 		sql,
-		u.TgUsername,
-		u.TgFirstName,
-		u.TgLastName,
-		u.TgLangCode,
-		u.TgIsBot,
-		u.TgID,
-	).Scan(
-		&u.ID,
-		&u.CreatedAt,
+		u.Timezone,
+		u.ID,
 	)
 
 	return err
 }
 
-func (r pgRepo) GetByID(ID int64) (*usrPkg.User, error) {
+func (r pgRepo) GetByID(ctx context.Context, ID int64) (*usrPkg.User, error) {
 	u := &usrPkg.User{}
 
 	sql := `
-		SELECT id, tg_id, tg_username, tg_first_name, tg_last_name, tg_lang_code, tg_is_bot, created_at
+		SELECT id, tg_id, tg_username, tg_first_name, tg_last_name, tg_lang_code, tg_is_bot, COALESCE(timezone, ''), created_at, deletion_scheduled_at
 		FROM users WHERE id = $1
 	`
-	err := r.pool.QueryRow(
-		r.ctx,
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
 		sql,
 		ID,
 	).Scan(
@@ -100,7 +87,9 @@ func (r pgRepo) GetByID(ID int64) (*usrPkg.User, error) {
 		&u.TgLastName,
 		&u.TgLangCode,
 		&u.TgIsBot,
+		&u.Timezone,
 		&u.CreatedAt,
+		&u.DeletionScheduledAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -112,15 +101,15 @@ func (r pgRepo) GetByID(ID int64) (*usrPkg.User, error) {
 	return u, nil
 }
 
-func (r pgRepo) GetByTgID(tgID int64) (*usrPkg.User, error) {
+func (r pgRepo) GetByTgID(ctx context.Context, tgID int64) (*usrPkg.User, error) {
 	u := &usrPkg.User{}
 
 	sql := `
-		SELECT id, tg_id, tg_username, tg_first_name, tg_last_name, tg_lang_code, tg_is_bot, created_at
+		SELECT id, tg_id, tg_username, tg_first_name, tg_last_name, tg_lang_code, tg_is_bot, COALESCE(timezone, ''), created_at, deletion_scheduled_at
 		FROM users WHERE tg_id = $1
 	`
-	err := r.pool.QueryRow(
-		r.ctx,
+	err := uow.Conn(ctx, r.pool).QueryRow(
+		ctx,
 		sql,
 		tgID,
 	).Scan(
@@ -131,7 +120,9 @@ func (r pgRepo) GetByTgID(tgID int64) (*usrPkg.User, error) {
 		&u.TgLastName,
 		&u.TgLangCode,
 		&u.TgIsBot,
+		&u.Timezone,
 		&u.CreatedAt,
+		&u.DeletionScheduledAt,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -0,0 +1,121 @@
+package repo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+func (r pgRepo) UpdateDeletionScheduledAt(ctx context.Context, u *usrPkg.User) error {
+	sql := `UPDATE users SET deletion_scheduled_at = $1 WHERE id = $2`
+	_, err := uow.Conn(ctx, r.pool).Exec(
+		ctx,
+		sql,
+		u.DeletionScheduledAt,
+		u.ID,
+	)
+
+	return err
+}
+
+// GetScheduledForDeletion returns users which account deletion time has come
+func (r pgRepo) GetScheduledForDeletion(ctx context.Context, now time.Time) ([]*usrPkg.User, error) {
+	sql := `
+		SELECT id, tg_id, tg_username, tg_first_name, tg_last_name, tg_lang_code, tg_is_bot, COALESCE(timezone, ''), created_at, deletion_scheduled_at
+		FROM users WHERE deletion_scheduled_at <= $1
+		ORDER BY deletion_scheduled_at
+	`
+	rows, err := uow.Conn(ctx, r.pool).Query(ctx, sql, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*usrPkg.User{}
+	for rows.Next() {
+		u := &usrPkg.User{}
+		if err = rows.Scan(
+			&u.ID,
+			&u.TgID,
+			&u.TgUsername,
+			&u.TgFirstName,
+			&u.TgLastName,
+			&u.TgLangCode,
+			&u.TgIsBot,
+			&u.Timezone,
+			&u.CreatedAt,
+			&u.DeletionScheduledAt,
+		); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// Shared habits created by the deleted user are handed over to their longest participant
+const reassignSharedHabitsSQL = `
+	UPDATE habits h SET
+		creator_id = m.user_id
+	FROM (
+		SELECT DISTINCT ON (uh.habit_id) uh.habit_id, uh.user_id
+		FROM users_habits uh
+		WHERE
+			uh.user_id <> $1
+			AND uh.active IS TRUE
+		ORDER BY uh.habit_id, uh.joined_at, uh.user_id
+	) m
+	WHERE
+		h.id = m.habit_id
+		AND h.creator_id = $1
+		AND h.active IS TRUE
+`
+
+// Everything left created by the user is solely owned by them. Checks are deleted through
+// the partitioned table, so from all its partitions
+var deleteUserDataSQLs = []string{
+	`DELETE FROM habit_invites WHERE creator_id = $1 OR habit_id IN (SELECT id FROM habits WHERE creator_id = $1)`,
+	`DELETE FROM user_habit_checks WHERE user_id = $1 OR habit_id IN (SELECT id FROM habits WHERE creator_id = $1)`,
+	`DELETE FROM users_habits WHERE user_id = $1 OR habit_id IN (SELECT id FROM habits WHERE creator_id = $1)`,
+	`DELETE FROM habits WHERE creator_id = $1`,
+	`DELETE FROM friendships WHERE requester_id = $1 OR addressee_id = $1`,
+	`DELETE FROM access_tokens WHERE user_id = $1`,
+	`DELETE FROM refresh_tokens WHERE user_id = $1`,
+	`DELETE FROM tg_chats WHERE user_id = $1`,
+	`DELETE FROM users WHERE id = $1`,
+}
+
+// Delete irreversibly deletes the user with their Telegram chats, memberships, checks, friendships,
+// access and refresh tokens and habits they solely own in a single transaction, unless the user's
+// account deletion isn't due by now anymore. The user is locked while it's checked, so deletion
+// cancelled concurrently either waits for it or prevents it. Returns if the user is deleted
+func (r pgRepo) Delete(ctx context.Context, id int64, now time.Time) (bool, error) {
+	deleted := false
+	err := pgx.BeginFunc(ctx, uow.Conn(ctx, r.pool), func(tx pgx.Tx) error {
+		sql := `SELECT id FROM users WHERE id = $1 AND deletion_scheduled_at <= $2 FOR UPDATE`
+		if err := tx.QueryRow(ctx, sql, id, now).Scan(&id); err != nil {
+			if err == pgx.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, reassignSharedHabitsSQL, id); err != nil {
+			return err
+		}
+		for _, sql := range deleteUserDataSQLs {
+			if _, err := tx.Exec(ctx, sql, id); err != nil {
+				return err
+			}
+		}
+		deleted = true
+		return nil
+	})
+
+	return deleted && err == nil, err
+}
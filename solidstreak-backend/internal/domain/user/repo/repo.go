@@ -2,19 +2,28 @@ package repo
 
 import (
 	"context"
+	"time"
 
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
 	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Repo interface {
-	IsExists(*usrPkg.User) (bool, error)
-	Create(*usrPkg.User) error
-	Update(*usrPkg.User) error
-	GetByID(int64) (*usrPkg.User, error)
-	GetByTgID(int64) (*usrPkg.User, error)
+	Upsert(context.Context, *usrPkg.User) error
+	UpdateTimezone(context.Context, *usrPkg.User) error
+	GetByID(context.Context, int64) (*usrPkg.User, error)
+	GetByTgID(context.Context, int64) (*usrPkg.User, error)
+	UpdateDeletionScheduledAt(context.Context, *usrPkg.User) error
+	GetScheduledForDeletion(context.Context, time.Time) ([]*usrPkg.User, error)
+	Delete(ctx context.Context, id int64, now time.Time) (bool, error)
 }
 
-func Init(c context.Context, p *pgxpool.Pool) Repo {
-	return initPGRepo(c, p)
+func Init(p *pgxpool.Pool) Repo {
+	return initPGRepo(p)
+}
+
+// InitMemory returns the repository keeping data in the in-memory storage instead of PostgreSQL
+func InitMemory(db *memdb.DB) Repo {
+	return initMemRepo(db)
 }
@@ -1,16 +1,26 @@
 package user
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+// DeletionConfirmation is the text the user types to confirm account deletion
+const DeletionConfirmation = "DELETE"
 
 type User struct {
-	ID          int64     `json:"id"`
-	TgID        int64     `json:"tgId"`
-	TgUsername  string    `json:"tgUsername"`
-	TgFirstName string    `json:"tgFirstName"`
-	TgLastName  string    `json:"tgLastName"`
-	TgLangCode  string    `json:"tgLangCode"`
-	TgIsBot     bool      `json:"tgIsBot"`
-	CreatedAt   time.Time `json:"createdAt"`
+	ID                  int64      `json:"id"`
+	TgID                int64      `json:"tgId"`
+	TgUsername          string     `json:"tgUsername"`
+	TgFirstName         string     `json:"tgFirstName"`
+	TgLastName          string     `json:"tgLastName"`
+	TgLangCode          string     `json:"tgLangCode"`
+	TgIsBot             bool       `json:"tgIsBot"`
+	Timezone            string     `json:"timezone"` // IANA time zone name, empty if unknown
+	CreatedAt           time.Time  `json:"createdAt"`
+	DeletionScheduledAt *time.Time `json:"deletionScheduledAt,omitempty"` // When the account is deleted, unless the deletion is cancelled
 }
 
 func NewUser(tgID int64, tgUsername, tgFirstName, tgLastName, tgLangCode string, tgIsBot bool) *User {
@@ -24,3 +34,44 @@ func NewUser(tgID int64, tgUsername, tgFirstName, tgLastName, tgLangCode string,
 		CreatedAt:   time.Now(),
 	}
 }
+
+// Location returns user's time zone, UTC if it's unknown
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Today returns the current date in user's time zone
+func (u *User) Today() date.Date {
+	return date.TodayIn(u.Location())
+}
+
+// ScheduleDeletion schedules account deletion after the grace period, keeps the time of already
+// scheduled one
+func (u *User) ScheduleDeletion(gracePeriod time.Duration) {
+	if u.DeletionScheduledAt != nil {
+		return
+	}
+	t := time.Now().Add(gracePeriod)
+	u.DeletionScheduledAt = &t
+}
+
+func (u *User) CancelDeletion() {
+	u.DeletionScheduledAt = nil
+}
+
+func ValidateTimezone(tz string) error {
+	if tz == "" || tz == "Local" {
+		return errors.New("invalid time zone")
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return errors.New("unknown time zone \"" + tz + "\"")
+	}
+	return nil
+}
@@ -0,0 +1,282 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed sql/*.sql
+var sqlFS embed.FS
+
+// Key of the advisory lock preventing concurrent migrations from several backend replicas
+const lockKey int64 = 0x4d494752 // "MIGR"
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+type Migrator struct {
+	pool       *pgxpool.Pool
+	migrations []*Migration
+}
+
+func New(p *pgxpool.Pool) (*Migrator, error) {
+	migrations, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{pool: p, migrations: migrations}, nil
+}
+
+// load reads embedded migrations named as "<version>_<name>.up.sql" and "<version>_<name>.down.sql"
+func load() ([]*Migration, error) {
+	files, err := fs.Glob(sqlFS, "sql/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, f := range files {
+		base := path.Base(f)
+
+		var (
+			stem      string
+			direction string
+		)
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			stem, direction = strings.TrimSuffix(base, ".up.sql"), "up"
+		case strings.HasSuffix(base, ".down.sql"):
+			stem, direction = strings.TrimSuffix(base, ".down.sql"), "down"
+		default:
+			return nil, errors.New("unexpected migration file name: " + base)
+		}
+
+		versionStr, name, ok := strings.Cut(stem, "_")
+		if !ok {
+			return nil, errors.New("unexpected migration file name: " + base)
+		}
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, errors.New("invalid migration version in file name: " + base)
+		}
+
+		data, err := sqlFS.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has different names: %q and %q", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", m.Version)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Up applies all pending migrations in version order, each in its own transaction
+func (m *Migrator) Up(ctx context.Context) ([]*Migration, error) {
+	applied := []*Migration{}
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		appliedAt, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range m.migrations {
+			if _, ok := appliedAt[mig.Version]; ok {
+				continue
+			}
+			err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, mig.Up); err != nil {
+					return err
+				}
+				_, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)`, mig.Version, mig.Name, time.Now())
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("applying migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied = append(applied, mig)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the latest applied migration. It returns nil if there is nothing to revert
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		appliedAt, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := appliedAt[m.migrations[i].Version]; ok {
+				reverted = m.migrations[i]
+				break
+			}
+		}
+		if reverted == nil {
+			return nil
+		}
+		if reverted.Down == "" {
+			return fmt.Errorf("migration %d_%s has no down script", reverted.Version, reverted.Name)
+		}
+
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, reverted.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, `DELETE FROM schema_migrations WHERE version = $1`, reverted.Version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("reverting migration %d_%s: %w", reverted.Version, reverted.Name, err)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]*Status, error) {
+	var statuses []*Status
+
+	err := m.withLock(ctx, func(conn *pgxpool.Conn) error {
+		appliedAt, err := getApplied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		statuses = make([]*Status, 0, len(m.migrations))
+		for _, mig := range m.migrations {
+			s := &Status{Version: mig.Version, Name: mig.Name}
+			if t, ok := appliedAt[mig.Version]; ok {
+				s.Applied = true
+				s.AppliedAt = &t
+			}
+			statuses = append(statuses, s)
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
+
+// Pending returns the number of migrations that are not applied yet
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, s := range statuses {
+		if !s.Applied {
+			pending++
+		}
+	}
+
+	return pending, nil
+}
+
+func (m *Migrator) withLock(ctx context.Context, f func(*pgxpool.Conn) error) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err = conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return err
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+
+	sql := `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY NOT NULL,
+			name VARCHAR(256) NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL
+		)
+	`
+	if _, err = conn.Exec(ctx, sql); err != nil {
+		return err
+	}
+
+	return f(conn)
+}
+
+func getApplied(ctx context.Context, conn *pgxpool.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.Query(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int64]time.Time)
+	for rows.Next() {
+		var (
+			version int64
+			t       time.Time
+		)
+		if err = rows.Scan(&version, &t); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = t
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return appliedAt, nil
+}
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS user_habit_checks;
+DROP TABLE IF EXISTS users_habits;
+DROP TABLE IF EXISTS habits;
+DROP TABLE IF EXISTS tg_chats;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,51 @@
+-- Tables are created only if missing, so databases set up by hand from the former db_schema file
+-- can adopt versioned migrations as is
+CREATE TABLE IF NOT EXISTS users (
+	id BIGSERIAL PRIMARY KEY UNIQUE NOT NULL,
+	tg_id BIGINT UNIQUE NOT NULL,
+	tg_username VARCHAR(32) UNIQUE NOT NULL,
+	tg_first_name VARCHAR(64),
+	tg_last_name VARCHAR(64),
+	tg_lang_code VARCHAR(3) NOT NULL,
+	tg_is_bot BOOLEAN NOT NULL,
+	created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tg_chats (
+	id BIGSERIAL PRIMARY KEY UNIQUE NOT NULL,
+	tg_id BIGINT UNIQUE NOT NULL,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS habits (
+	id BIGSERIAL PRIMARY KEY UNIQUE NOT NULL,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	archived BOOLEAN NOT NULL DEFAULT FALSE,
+	creator_id BIGINT NOT NULL REFERENCES users(id),
+	title VARCHAR(256) NOT NULL,
+	description TEXT,
+	color VARCHAR(32) NOT NULL DEFAULT 'green',
+	created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+	updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users_habits (
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	habit_id BIGINT NOT NULL REFERENCES habits(id),
+	is_public BOOLEAN NOT NULL DEFAULT FALSE,
+	PRIMARY KEY (user_id, habit_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_habit_checks (
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	habit_id BIGINT NOT NULL REFERENCES habits(id),
+	check_date DATE NOT NULL,
+	completed BOOLEAN NOT NULL DEFAULT FALSE,
+	checked_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+	PRIMARY KEY (user_id, habit_id, check_date)
+) PARTITION BY RANGE (check_date);
+
+-- Monthly partitions (user_habit_checks_yYYYYmMM) are created and maintained by the backend
+CREATE TABLE IF NOT EXISTS user_habit_checks_default PARTITION OF user_habit_checks DEFAULT;
@@ -0,0 +1,7 @@
+ALTER TABLE user_habit_checks ALTER COLUMN checked_at TYPE TIMESTAMP WITHOUT TIME ZONE USING checked_at AT TIME ZONE current_setting('TimeZone');
+ALTER TABLE habits ALTER COLUMN updated_at TYPE TIMESTAMP WITHOUT TIME ZONE USING updated_at AT TIME ZONE current_setting('TimeZone');
+ALTER TABLE habits ALTER COLUMN created_at TYPE TIMESTAMP WITHOUT TIME ZONE USING created_at AT TIME ZONE current_setting('TimeZone');
+ALTER TABLE tg_chats ALTER COLUMN created_at TYPE TIMESTAMP WITHOUT TIME ZONE USING created_at AT TIME ZONE current_setting('TimeZone');
+ALTER TABLE users ALTER COLUMN created_at TYPE TIMESTAMP WITHOUT TIME ZONE USING created_at AT TIME ZONE current_setting('TimeZone');
+
+ALTER TABLE users DROP COLUMN timezone;
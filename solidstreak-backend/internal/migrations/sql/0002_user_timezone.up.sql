@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64);
+
+-- Timestamps used to be written as the server's wall-clock time, which is assumed to match the database time zone
+ALTER TABLE users ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE USING created_at AT TIME ZONE current_setting('TimeZone');
+ALTER TABLE tg_chats ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE USING created_at AT TIME ZONE current_setting('TimeZone');
+ALTER TABLE habits ALTER COLUMN created_at TYPE TIMESTAMP WITH TIME ZONE USING created_at AT TIME ZONE current_setting('TimeZone');
+ALTER TABLE habits ALTER COLUMN updated_at TYPE TIMESTAMP WITH TIME ZONE USING updated_at AT TIME ZONE current_setting('TimeZone');
+ALTER TABLE user_habit_checks ALTER COLUMN checked_at TYPE TIMESTAMP WITH TIME ZONE USING checked_at AT TIME ZONE current_setting('TimeZone');
@@ -0,0 +1,6 @@
+-- Archived habits' stats end on the archiving date, which later edits mustn't move
+ALTER TABLE habits ADD COLUMN archived_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE habits SET
+	archived_at = updated_at
+WHERE archived IS TRUE;
@@ -0,0 +1 @@
+ALTER TABLE habits ADD COLUMN schedule JSONB NOT NULL DEFAULT '{"type": "daily"}';
@@ -0,0 +1,4 @@
+ALTER TABLE user_habit_checks DROP COLUMN amount;
+
+ALTER TABLE habits DROP COLUMN unit;
+ALTER TABLE habits DROP COLUMN target_value;
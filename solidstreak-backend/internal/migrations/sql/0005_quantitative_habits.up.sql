@@ -0,0 +1,4 @@
+ALTER TABLE habits ADD COLUMN target_value DOUBLE PRECISION;
+ALTER TABLE habits ADD COLUMN unit VARCHAR(32);
+
+ALTER TABLE user_habit_checks ADD COLUMN amount DOUBLE PRECISION;
@@ -0,0 +1,2 @@
+ALTER TABLE habits DROP COLUMN reminder_sent_on;
+ALTER TABLE habits DROP COLUMN reminder_time;
@@ -0,0 +1,3 @@
+ALTER TABLE habits ADD COLUMN reminder_time TIME WITHOUT TIME ZONE;
+-- Habit owner's local date of the latest sent reminder, keeps reminders from being sent twice a day
+ALTER TABLE habits ADD COLUMN reminder_sent_on DATE;
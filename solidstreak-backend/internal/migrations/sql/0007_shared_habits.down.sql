@@ -0,0 +1,16 @@
+ALTER TABLE habits ADD COLUMN reminder_sent_on DATE;
+
+UPDATE habits h SET
+	reminder_sent_on = uh.reminder_sent_on
+FROM users_habits uh
+WHERE
+	h.id = uh.habit_id
+	AND h.creator_id = uh.user_id;
+
+ALTER TABLE users_habits DROP COLUMN reminder_sent_on;
+
+ALTER TABLE users_habits DROP COLUMN joined_at;
+
+DROP INDEX users_habits_habit_id_idx;
+
+DROP TABLE habit_invites;
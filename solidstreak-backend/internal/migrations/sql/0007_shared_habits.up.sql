@@ -0,0 +1,32 @@
+CREATE TABLE habit_invites (
+	token VARCHAR(64) PRIMARY KEY NOT NULL,
+	habit_id BIGINT NOT NULL REFERENCES habits(id),
+	creator_id BIGINT NOT NULL REFERENCES users(id),
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX habit_invites_habit_id_idx ON habit_invites (habit_id);
+
+CREATE INDEX users_habits_habit_id_idx ON users_habits (habit_id);
+
+ALTER TABLE users_habits ADD COLUMN joined_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE users_habits uh SET
+	joined_at = h.created_at
+FROM habits h
+WHERE h.id = uh.habit_id;
+
+ALTER TABLE users_habits ALTER COLUMN joined_at SET NOT NULL;
+
+-- Reminders are sent to every participant, so their sending is tracked per participant
+ALTER TABLE users_habits ADD COLUMN reminder_sent_on DATE;
+
+UPDATE users_habits uh SET
+	reminder_sent_on = h.reminder_sent_on
+FROM habits h
+WHERE
+	h.id = uh.habit_id
+	AND h.creator_id = uh.user_id;
+
+ALTER TABLE habits DROP COLUMN reminder_sent_on;
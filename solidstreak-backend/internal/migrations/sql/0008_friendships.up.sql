@@ -0,0 +1,14 @@
+-- Single relationship per pair of users, the requester of blocked one is the user who blocked the other
+CREATE TABLE friendships (
+	requester_id BIGINT NOT NULL REFERENCES users(id),
+	addressee_id BIGINT NOT NULL REFERENCES users(id),
+	status VARCHAR(16) NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	PRIMARY KEY (requester_id, addressee_id),
+	CHECK (requester_id <> addressee_id)
+);
+
+CREATE UNIQUE INDEX friendships_pair_idx ON friendships (LEAST(requester_id, addressee_id), GREATEST(requester_id, addressee_id));
+
+CREATE INDEX friendships_addressee_id_idx ON friendships (addressee_id);
@@ -0,0 +1,8 @@
+-- Deleted habits are kept in the trash until they're restored or purged after the retention period
+ALTER TABLE habits ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE habits SET
+	deleted_at = updated_at
+WHERE active IS FALSE;
+
+CREATE INDEX habits_deleted_at_idx ON habits (deleted_at) WHERE active IS FALSE;
@@ -0,0 +1,8 @@
+-- Telegram initData exchanged for sessions is stored by SHA-256 hash until it expires, so it's
+-- exchanged once. Never expiring initData is kept forever
+CREATE TABLE used_init_data (
+	hash CHAR(64) PRIMARY KEY,
+	expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX used_init_data_expires_at_idx ON used_init_data (expires_at);
@@ -0,0 +1,15 @@
+-- Refresh tokens are stored by SHA-256 hash of their secrets. Rotated ones are kept until they
+-- expire, so their reuse is detected
+CREATE TABLE refresh_tokens (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	family_id UUID NOT NULL,
+	hash CHAR(64) UNIQUE NOT NULL,
+	login_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+	rotated_at TIMESTAMP WITH TIME ZONE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
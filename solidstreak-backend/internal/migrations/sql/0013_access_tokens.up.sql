@@ -0,0 +1,11 @@
+-- Personal access tokens are stored by SHA-256 hash of their secrets, which are shown once on creation
+CREATE TABLE access_tokens (
+	id BIGSERIAL PRIMARY KEY,
+	user_id BIGINT NOT NULL REFERENCES users(id),
+	name VARCHAR(64) NOT NULL,
+	scope VARCHAR(16) NOT NULL,
+	hash CHAR(64) UNIQUE NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX access_tokens_user_id_idx ON access_tokens (user_id);
@@ -0,0 +1,100 @@
+package accesstoken
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	atPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/accesstoken"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// Create creates the access token of the owner on behalf of the user, who could create own tokens
+// only, and returns it along with its secret
+func Create(ctx context.Context, r resources.Resources, u *usrPkg.User, ownerID int64, name, scopeStr string) (*atPkg.Token, string, error) {
+	name = strings.TrimSpace(name)
+	if err := atPkg.ValidateName(name); err != nil {
+		return nil, "", apperrors.ErrBadRequest(err.Error())
+	}
+	scope, ok := atPkg.ScopeMapping[scopeStr]
+	if !ok {
+		return nil, "", apperrors.ErrBadRequest("access token scope must be one of \"read\", \"checks\" or \"full\"")
+	}
+
+	if ownerID != u.ID {
+		return nil, "", apperrors.ErrForbidden("couldn't create access token for another user")
+	}
+
+	tokens, err := r.TokenRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(tokens) >= atPkg.MaxPerUser {
+		return nil, "", apperrors.ErrBadRequest("couldn't have more than " + strconv.Itoa(atPkg.MaxPerUser) + " access tokens, revoke unused ones")
+	}
+
+	t, secret, err := atPkg.New(u.ID, name, scope)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = r.TokenRepo.Create(ctx, t); err != nil {
+		return nil, "", err
+	}
+
+	return t, secret, nil
+}
+
+// List returns access tokens of the owner on behalf of the user, who could see own tokens only
+func List(ctx context.Context, r resources.Resources, u *usrPkg.User, ownerID int64) ([]*atPkg.Token, error) {
+	if ownerID != u.ID {
+		return nil, apperrors.ErrForbidden("couldn't get access tokens of another user")
+	}
+
+	return r.TokenRepo.GetByUserID(ctx, u.ID)
+}
+
+// Revoke deletes the access token of the owner on behalf of the user, who could revoke own tokens only
+func Revoke(ctx context.Context, r resources.Resources, u *usrPkg.User, ownerID, tokenID int64) (*atPkg.Token, error) {
+	if ownerID != u.ID {
+		return nil, apperrors.ErrForbidden("couldn't revoke access token of another user")
+	}
+
+	tokens, err := r.TokenRepo.GetByUserID(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(tokens, func(t *atPkg.Token) bool { return t.ID == tokenID })
+	if i < 0 {
+		return nil, apperrors.ErrNotFound("couldn't find access token")
+	}
+
+	if err = r.TokenRepo.Delete(ctx, u.ID, tokenID); err != nil {
+		return nil, err
+	}
+
+	return tokens[i], nil
+}
+
+// Authenticate returns the access token with the secret and the user it's issued for
+func Authenticate(ctx context.Context, r resources.Resources, secret string) (*usrPkg.User, *atPkg.Token, error) {
+	t, err := r.TokenRepo.GetByHash(ctx, atPkg.HashSecret(secret))
+	if err != nil {
+		var apperror apperrors.Error
+		if errors.As(err, &apperror) && apperror.HTTPCode == 404 {
+			return nil, nil, apperrors.ErrUnauthorized("invalid access token")
+		}
+		return nil, nil, err
+	}
+
+	u, err := r.UsrRepo.GetByID(ctx, t.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return u, t, nil
+}
@@ -0,0 +1,224 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+)
+
+// Export writes all data stored about the user. Habits include shared ones created by other users
+type Export struct {
+	User       *usrPkg.User
+	ExportedAt time.Time
+
+	r resources.Resources
+}
+
+// New loads the user only, the rest of data is read from the storage while the archive is written
+func New(ctx context.Context, r resources.Resources, userID int64) (*Export, error) {
+	u, err := r.UsrRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Export{User: u, ExportedAt: time.Now(), r: r}, nil
+}
+
+// FileName returns the archive name, e.g. "solidstreak-export-42-20250131.zip"
+func (e *Export) FileName() string {
+	return fmt.Sprintf("solidstreak-export-%d-%s.zip", e.User.ID, e.ExportedAt.Format("20060102"))
+}
+
+// WriteZip writes the archive with a JSON and a CSV file per table, named and structured after
+// the storage tables. Rows are streamed from the storage to w, every file reads them anew, so
+// all files are read in one snapshot to match each other
+func (e *Export) WriteZip(ctx context.Context, w io.Writer) error {
+	return e.r.UoW.DoSnapshot(ctx, func(ctx context.Context) error {
+		zw := zip.NewWriter(w)
+
+		for _, t := range e.tables() {
+			if err := t.writeJSON(ctx, zw, e.ExportedAt); err != nil {
+				return err
+			}
+			if err := t.writeCSV(ctx, zw, e.ExportedAt); err != nil {
+				return err
+			}
+		}
+
+		return zw.Close()
+	})
+}
+
+// table passes its rows one by one to fn as values of its columns, which are strings, numbers,
+// booleans, JSON or nils
+type table struct {
+	name    string
+	columns []string
+	rows    func(ctx context.Context, fn func([]any) error) error
+}
+
+func (e *Export) tables() []*table {
+	u := e.User
+	users := &table{
+		name:    "users",
+		columns: []string{"id", "tg_id", "tg_username", "tg_first_name", "tg_last_name", "tg_lang_code", "tg_is_bot", "timezone", "created_at", "deletion_scheduled_at"},
+		rows: func(ctx context.Context, fn func([]any) error) error {
+			var deletionScheduledAt any
+			if u.DeletionScheduledAt != nil {
+				deletionScheduledAt = timestamp(*u.DeletionScheduledAt)
+			}
+			return fn([]any{u.ID, u.TgID, u.TgUsername, u.TgFirstName, u.TgLastName, u.TgLangCode, u.TgIsBot, nullable(u.Timezone), timestamp(u.CreatedAt), deletionScheduledAt})
+		},
+	}
+
+	habits := &table{
+		name:    "habits",
+		columns: []string{"id", "active", "archived", "creator_id", "title", "description", "color", "schedule", "target_value", "unit", "reminder_time", "created_at", "updated_at", "archived_at"},
+		rows: func(ctx context.Context, fn func([]any) error) error {
+			return e.r.HabitRepo.ForEachByMemberID(ctx, u.ID, func(h *hPkg.Habit) error {
+				schedule, _ := json.Marshal(h.Schedule)
+				var target any
+				if h.Target != nil {
+					target = *h.Target
+				}
+				var archivedAt any
+				if h.ArchivedAt != nil {
+					archivedAt = timestamp(*h.ArchivedAt)
+				}
+				return fn([]any{h.ID, h.Active, h.Archived, h.CreatorID, h.Title, h.Description, string(h.Color), json.RawMessage(schedule), target, nullable(h.Unit), nullable(h.ReminderTime), timestamp(h.CreatedAt), timestamp(h.UpdatedAt), archivedAt})
+			})
+		},
+	}
+
+	memberships := &table{
+		name:    "users_habits",
+		columns: []string{"user_id", "habit_id", "active", "is_public", "joined_at"},
+		rows: func(ctx context.Context, fn func([]any) error) error {
+			return e.r.HabitRepo.ForEachMembershipByUserID(ctx, u.ID, func(m *hPkg.Membership) error {
+				return fn([]any{m.UserID, m.HabitID, m.Active, m.IsPublic, timestamp(m.JoinedAt)})
+			})
+		},
+	}
+
+	checks := &table{
+		name:    "user_habit_checks",
+		columns: []string{"user_id", "habit_id", "check_date", "completed", "amount", "checked_at"},
+		rows: func(ctx context.Context, fn func([]any) error) error {
+			return e.r.HabitRepo.ForEachUserCheck(ctx, u.ID, func(hc *hPkg.HabitCheck) error {
+				var amount any
+				if hc.Amount != nil {
+					amount = *hc.Amount
+				}
+				return fn([]any{hc.UserID, hc.HabitID, hc.CheckDate.String(), hc.Completed, amount, timestamp(hc.CheckedAt)})
+			})
+		},
+	}
+
+	return []*table{users, habits, memberships, checks}
+}
+
+func (t *table) writeJSON(ctx context.Context, zw *zip.Writer, modified time.Time) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: t.name + ".json", Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+
+	// Rows are written by hand to keep the columns order
+	if _, err = io.WriteString(f, "["); err != nil {
+		return err
+	}
+	sep := ""
+	err = t.rows(ctx, func(row []any) error {
+		if _, err := io.WriteString(f, sep+"\n  {"); err != nil {
+			return err
+		}
+		sep = ","
+		for j, v := range row {
+			key, _ := json.Marshal(t.columns[j])
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			valueSep := ", "
+			if j == 0 {
+				valueSep = ""
+			}
+			if _, err = fmt.Fprintf(f, "%s%s: %s", valueSep, key, value); err != nil {
+				return err
+			}
+		}
+		_, err := io.WriteString(f, "}")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(f, "\n]\n")
+
+	return err
+}
+
+func (t *table) writeCSV(ctx context.Context, zw *zip.Writer, modified time.Time) error {
+	f, err := zw.CreateHeader(&zip.FileHeader{Name: t.name + ".csv", Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(f)
+	if err = cw.Write(t.columns); err != nil {
+		return err
+	}
+	err = t.rows(ctx, func(row []any) error {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = csvValue(v)
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.RawMessage:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// nullable maps empty strings stored as NULLs back to nils
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,351 @@
+package habit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// Data is habit settings requested by the user, nil fields aren't specified
+type Data struct {
+	Archived     *bool
+	Title        *string
+	Description  *string
+	Color        *string
+	Schedule     *hPkg.Schedule
+	Target       *float64
+	Unit         *string
+	ReminderTime *string
+	IsPublic     *bool
+}
+
+// LoadOptions specify what's loaded along with habits
+type LoadOptions struct {
+	Status            hPkg.HabitStatus
+	WithChecks        bool
+	WithStats         bool
+	IncludeIncomplete bool       // Checks include incomplete ones holding partial progress of quantitative habits
+	From, To          *date.Date // Checks and statistics dates range, the last year up to today by default
+}
+
+// CreateHabit creates the habit of the owner on behalf of the user, who could create own habits only
+func CreateHabit(ctx context.Context, r resources.Resources, u *usrPkg.User, ownerID int64, d Data) (*hPkg.Habit, error) {
+	if d.Title == nil {
+		return nil, apperrors.ErrBadRequest("habit title is required")
+	}
+	color, err := parseColor(d.Color)
+	if err != nil {
+		return nil, err
+	}
+	schedule := hPkg.DailySchedule()
+	if d.Schedule != nil {
+		schedule = *d.Schedule
+	}
+	if err = schedule.Validate(); err != nil {
+		return nil, apperrors.ErrBadRequest(err.Error())
+	}
+	var unit string
+	if d.Unit != nil {
+		unit = *d.Unit
+	}
+	if err = hPkg.ValidateTarget(d.Target, unit); err != nil {
+		return nil, apperrors.ErrBadRequest(err.Error())
+	}
+	var reminderTime string
+	if d.ReminderTime != nil {
+		reminderTime = *d.ReminderTime
+	}
+	if err = hPkg.ValidateReminderTime(reminderTime); err != nil {
+		return nil, apperrors.ErrBadRequest(err.Error())
+	}
+	if d.IsPublic == nil {
+		return nil, apperrors.ErrBadRequest("habit public status is required")
+	}
+	var description string
+	if d.Description != nil {
+		description = *d.Description
+	}
+
+	if ownerID != u.ID {
+		return nil, apperrors.ErrForbidden("couldn't create habit for another user")
+	}
+
+	h := hPkg.NewHabit(*d.Title, description, color, schedule, d.Target, unit, reminderTime, u.ID, *d.IsPublic)
+
+	if err = r.HabitRepo.Create(ctx, h); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// UpdateHabit updates the habit of the owner on behalf of the user. Settings shared by all participants
+// are changed by the creator only, while participants could change their own habit's visibility
+func UpdateHabit(ctx context.Context, r resources.Resources, u *usrPkg.User, ownerID, habitID int64, d Data) (*hPkg.Habit, error) {
+	if d.Archived == nil {
+		return nil, apperrors.ErrBadRequest("habit archived status is required")
+	}
+	if d.Title == nil {
+		return nil, apperrors.ErrBadRequest("habit title is required")
+	}
+	color, err := parseColor(d.Color)
+	if err != nil {
+		return nil, err
+	}
+	if d.Schedule != nil {
+		if err = d.Schedule.Validate(); err != nil {
+			return nil, apperrors.ErrBadRequest(err.Error())
+		}
+	}
+	if d.ReminderTime != nil {
+		if err = hPkg.ValidateReminderTime(*d.ReminderTime); err != nil {
+			return nil, apperrors.ErrBadRequest(err.Error())
+		}
+	}
+	if d.IsPublic == nil {
+		return nil, apperrors.ErrBadRequest("habit public status is required")
+	}
+
+	if ownerID != u.ID {
+		return nil, apperrors.ErrForbidden("couldn't update habit for another user")
+	}
+
+	h, err := r.HabitRepo.GetByIDAndOwnerID(ctx, habitID, ownerID, true)
+	if err != nil {
+		return nil, err
+	}
+	current := *h
+
+	h.SetArchived(*d.Archived)
+	h.Title = *d.Title
+	if d.Description != nil {
+		h.Description = *d.Description
+	} else {
+		h.Description = ""
+	}
+	h.Color = color
+	if d.Schedule != nil { // Schedule is kept as is if not specified
+		h.Schedule = *d.Schedule
+	}
+
+	// Target and unit are kept as is if not specified. Habit kind couldn't be changed,
+	// since completion of recorded checks depends on it
+	targetChanged := false
+	if d.Target != nil {
+		if !h.IsQuantitative() {
+			return nil, apperrors.ErrBadRequest("couldn't set target for habit without target")
+		}
+		targetChanged = *d.Target != *h.Target
+		h.Target = d.Target
+	}
+	if d.Unit != nil {
+		h.Unit = *d.Unit
+	}
+	if err = hPkg.ValidateTarget(h.Target, h.Unit); err != nil {
+		return nil, apperrors.ErrBadRequest(err.Error())
+	}
+
+	if d.ReminderTime != nil { // Reminder is kept as is if not specified and turned off by empty time
+		h.ReminderTime = *d.ReminderTime
+	}
+
+	h.IsPublic = *d.IsPublic
+
+	settingsChanged := !h.HasSameSettings(&current)
+	if settingsChanged && !h.IsCreator(u.ID) {
+		return nil, apperrors.ErrForbidden("only habit creator could change its settings")
+	}
+
+	// Completion of checks is recalculated along with the target, so they never disagree
+	err = r.UoW.Do(ctx, func(ctx context.Context) error {
+		if settingsChanged {
+			h.UpdatedAt = time.Now()
+			if err := r.HabitRepo.Update(ctx, h); err != nil {
+				return err
+			}
+		}
+
+		if h.IsPublic != current.IsPublic {
+			if err := r.HabitRepo.SetMemberVisibility(ctx, h.ID, u.ID, h.IsPublic); err != nil {
+				return err
+			}
+		}
+
+		if targetChanged {
+			return r.HabitRepo.RecalcChecksCompletion(ctx, h)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// DeleteHabit moves the habit of the owner to the trash on behalf of the user. Only the creator
+// could delete the habit, participants could leave it
+func DeleteHabit(ctx context.Context, r resources.Resources, u *usrPkg.User, ownerID, habitID int64) (*hPkg.Habit, error) {
+	if ownerID != u.ID {
+		return nil, apperrors.ErrForbidden("couldn't delete habit for another user")
+	}
+
+	h, err := r.HabitRepo.GetByIDAndOwnerID(ctx, habitID, ownerID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if !h.IsCreator(u.ID) {
+		return nil, apperrors.ErrForbidden("only habit creator could delete it, participants could leave it")
+	}
+
+	h.Trash()
+
+	if err = r.HabitRepo.Update(ctx, h); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// GetHabit returns the habit of the owner as seen by the viewer, other users see public habits only.
+// Whether the viewer is allowed to see owner's habits at all is checked by the caller
+func GetHabit(ctx context.Context, r resources.Resources, viewer *usrPkg.User, ownerID, habitID int64, opts LoadOptions) (*hPkg.Habit, error) {
+	h, err := r.HabitRepo.GetByIDAndOwnerID(ctx, habitID, ownerID, ownerID == viewer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = load(ctx, r, viewer, ownerID, []*hPkg.Habit{h}, opts); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// ListHabits returns habits of the owner with specified status as seen by the viewer, other users
+// see public habits only. Whether the viewer is allowed to see owner's habits at all is checked by the caller
+func ListHabits(ctx context.Context, r resources.Resources, viewer *usrPkg.User, ownerID int64, opts LoadOptions) ([]*hPkg.Habit, error) {
+	habits, err := r.HabitRepo.GetByOwnerIDAndStatus(ctx, ownerID, opts.Status, ownerID == viewer.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = load(ctx, r, viewer, ownerID, habits, opts); err != nil {
+		return nil, err
+	}
+
+	return habits, nil
+}
+
+// CheckHabit records the check of the habit of the owner on behalf of the user, who could check
+// own habits only, for today or one of backfillDays days before
+func CheckHabit(ctx context.Context, r resources.Resources, u *usrPkg.User, ownerID, habitID int64, checkDate date.Date, completed *bool, amount *float64, backfillDays int) (*hPkg.Habit, *hPkg.HabitCheck, error) {
+	if ownerID != u.ID {
+		return nil, nil, apperrors.ErrForbidden("couldn't check habit for another user")
+	}
+
+	h, err := r.HabitRepo.GetByIDAndOwnerID(ctx, habitID, ownerID, true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var cdErr hPkg.CheckDateError
+	if err = h.ValidateCheckDate(checkDate, u.Today(), u.Location(), hPkg.CheckDatePolicy{BackfillDays: backfillDays}); errors.As(err, &cdErr) {
+		return nil, nil, apperrors.ErrBadRequest(cdErr.Message).WithCode(cdErr.Code)
+	}
+
+	hc, err := h.NewCheck(u.ID, checkDate, completed, amount)
+	if err != nil {
+		return nil, nil, apperrors.ErrBadRequest(err.Error())
+	}
+
+	if err = r.HabitRepo.SetUserHabitCheck(ctx, hc); err != nil {
+		return nil, nil, err
+	}
+
+	return h, hc, nil
+}
+
+// load loads checks and statistics of the owner's habits requested by options. Statistics are
+// calculated in the owner's time zone
+func load(ctx context.Context, r resources.Resources, viewer *usrPkg.User, ownerID int64, habits []*hPkg.Habit, opts LoadOptions) error {
+	if !opts.WithChecks && !opts.WithStats || len(habits) == 0 {
+		return nil
+	}
+
+	owner := viewer
+	if ownerID != viewer.ID {
+		var err error
+		if owner, err = r.UsrRepo.GetByID(ctx, ownerID); err != nil {
+			return err
+		}
+	}
+
+	today := owner.Today()
+	from, to := today.AddDate(-1, 0, 0), today
+	if opts.From != nil {
+		from = *opts.From
+	}
+	if opts.To != nil {
+		to = *opts.To
+	}
+
+	habitIDs := make([]int64, 0, len(habits))
+	for _, h := range habits {
+		habitIDs = append(habitIDs, h.ID)
+	}
+
+	var (
+		checks []*hPkg.HabitCheck
+		err    error
+	)
+	if opts.IncludeIncomplete {
+		checks, err = r.HabitRepo.GetUserHabitsChecks(ctx, ownerID, habitIDs, &from, &to)
+	} else {
+		checks, err = r.HabitRepo.GetUserHabitsCompletedChecks(ctx, ownerID, habitIDs, &from, &to)
+	}
+	if err != nil {
+		return err
+	}
+
+	checksByHabitID := make(map[int64][]*hPkg.HabitCheck, len(habits))
+	for _, hc := range checks {
+		checksByHabitID[hc.HabitID] = append(checksByHabitID[hc.HabitID], hc)
+	}
+	for _, h := range habits {
+		if opts.WithChecks {
+			if h.Checks = checksByHabitID[h.ID]; h.Checks == nil {
+				h.Checks = []*hPkg.HabitCheck{}
+			}
+		}
+		if opts.WithStats {
+			h.Stats = hPkg.CalcStats(h, checksByHabitID[h.ID], from, to, today, owner.Location())
+		}
+	}
+
+	return nil
+}
+
+// parseColor returns the requested color, green by default
+func parseColor(colorStr *string) (hPkg.Color, error) {
+	if colorStr == nil {
+		return hPkg.Green, nil
+	}
+
+	color, ok := hPkg.ColorMapping[*colorStr]
+	if !ok {
+		return "", apperrors.ErrBadRequest("invalid habit color")
+	}
+	if color == "" {
+		color = hPkg.Green
+	}
+
+	return color, nil
+}
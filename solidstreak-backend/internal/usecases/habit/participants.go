@@ -0,0 +1,58 @@
+package habit
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// Join makes the user a participant of the habit the invite with specified token is issued for.
+// Joining the habit the user already participates in just returns it
+func Join(ctx context.Context, r resources.Resources, u *usrPkg.User, token string, isPublic bool) (*hPkg.Habit, error) {
+	invite, err := r.HabitRepo.GetInviteByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if invite.IsExpired() {
+		return nil, apperrors.ErrBadRequest("habit invite has expired")
+	}
+
+	// Invites are issued by habits creators only
+	h, err := r.HabitRepo.GetByIDAndOwnerID(ctx, invite.HabitID, invite.CreatorID, true)
+	if err != nil {
+		return nil, err
+	}
+	if !h.Active || !h.IsCreator(invite.CreatorID) {
+		return nil, apperrors.ErrNotFound("couldn't find habit of the invite")
+	}
+	if h.Archived {
+		return nil, apperrors.ErrBadRequest("couldn't join archived habit")
+	}
+
+	joined, err := r.HabitRepo.GetByIDAndOwnerID(ctx, h.ID, u.ID, true)
+	if err == nil {
+		return joined, nil
+	}
+	var apperror apperrors.Error
+	if !errors.As(err, &apperror) || apperror.HTTPCode != 404 {
+		return nil, err
+	}
+
+	// Habit is locked while joined, so imports moving its creation back see the new participant
+	err = r.UoW.Do(ctx, func(ctx context.Context) error {
+		if err := r.HabitRepo.LockParticipants(ctx, h.ID); err != nil {
+			return err
+		}
+		return r.HabitRepo.AddMember(ctx, h.ID, u.ID, isPublic, time.Now())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return r.HabitRepo.GetByIDAndOwnerID(ctx, h.ID, u.ID, true)
+}
@@ -0,0 +1,113 @@
+package importer
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// parseGeneric reads CSV with a row per check: "habit" title, "date" in YYYY-MM-DD format and
+// optional "completed", "amount", "target" and "unit". New habits are daily ones, those having
+// a target are quantitative. Rows of yes/no habits without "completed" value are completed checks
+func parseGeneric(data []byte) ([]*record, []*RowError, error) {
+	rows, lines, err := readCSV(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cols := map[string]int{}
+	for i, col := range normalizeHeader(rows[0]) {
+		cols[col] = i
+	}
+	idx := func(name string) int {
+		if i, ok := cols[name]; ok {
+			return i
+		}
+		return -1
+	}
+	habitCol, dateCol := idx("habit"), idx("date")
+	if habitCol < 0 || dateCol < 0 {
+		return nil, nil, apperrors.ErrBadRequest("CSV must have \"habit\" and \"date\" columns")
+	}
+	completedCol, amountCol, targetCol, unitCol := idx("completed"), idx("amount"), idx("target"), idx("unit")
+
+	records := []*record{}
+	errs := []*RowError{}
+	for i, row := range rows[1:] {
+		line := lines[i+1]
+		rowErr := func(msg string) {
+			errs = append(errs, &RowError{Row: line, Message: msg})
+		}
+
+		title := column(row, habitCol)
+		if title == "" {
+			rowErr("habit title is required")
+			continue
+		}
+		d, err := date.Parse(column(row, dateCol))
+		if err != nil {
+			rowErr("invalid date, YYYY-MM-DD expected")
+			continue
+		}
+
+		var target *float64
+		if v := column(row, targetCol); v != "" {
+			t, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				rowErr("invalid target")
+				continue
+			}
+			target = &t
+		}
+		unit := column(row, unitCol)
+
+		rec := findRecord(records, title)
+		if rec == nil {
+			rec = &record{title: title, color: hPkg.Green, schedule: hPkg.DailySchedule(), target: target, unit: unit, row: line}
+			records = append(records, rec)
+		} else if (target != nil && (rec.target == nil || *rec.target != *target)) || (unit != "" && unit != rec.unit) {
+			rowErr("target and unit differ from the first row of the habit")
+			continue
+		}
+
+		e := &entry{date: d, row: line}
+		if v := column(row, completedCol); v != "" {
+			completed, ok := parseBool(v)
+			if !ok {
+				rowErr("invalid completed value \"" + v + "\"")
+				continue
+			}
+			e.completed = &completed
+		}
+		if v := column(row, amountCol); v != "" {
+			amount, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				rowErr("invalid amount")
+				continue
+			}
+			e.amount = &amount
+		}
+		if e.completed == nil && e.amount == nil && rec.target == nil {
+			completed := true
+			e.completed = &completed
+		}
+
+		rec.entries = append(rec.entries, e)
+	}
+
+	return records, errs, nil
+}
+
+func parseBool(v string) (bool, bool) {
+	switch strings.ToLower(v) {
+	case "1", "true", "yes", "y", "x", "done":
+		return true, true
+	case "0", "false", "no", "n", "":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,118 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+)
+
+func TestParseGeneric(t *testing.T) {
+	daily := func(title string, target float64, unit string, entries ...string) recordSummary {
+		return recordSummary{Title: title, Color: hPkg.Green, Schedule: hPkg.DailySchedule(), Target: target, Unit: unit, Entries: entries}
+	}
+
+	tests := []struct {
+		name     string
+		data     string
+		want     []recordSummary
+		wantErrs []string
+		wantErr  bool
+	}{
+		{
+			name: "yes/no habits",
+			data: "habit,date\nRead,2024-03-01\nRun,2024-03-01\nRead,2024-03-02\n",
+			want: []recordSummary{daily("Read", 0, "", "2024-03-01=true", "2024-03-02=true"), daily("Run", 0, "", "2024-03-01=true")},
+		},
+		{
+			name: "optional columns",
+			data: "Date,Habit,Completed,Amount,Target,Unit\n" +
+				"2024-03-01,Read,no,,,\n" +
+				"2024-03-02,Read,Yes,,,\n" +
+				"2024-03-01,Run,,5.5,5,km\n" +
+				"2024-03-02,Run,,3,,\n" +
+				"2024-03-03,Run,,,5,km\n",
+			want: []recordSummary{
+				daily("Read", 0, "", "2024-03-01=false", "2024-03-02=true"),
+				daily("Run", 5, "km", "2024-03-01=5.5", "2024-03-02=3", "2024-03-03="),
+			},
+		},
+		{
+			name: "byte order mark and spaces",
+			data: "\ufeff Habit , Date \nRead, 2024-03-01\n",
+			want: []recordSummary{daily("Read", 0, "", "2024-03-01=true")},
+		},
+		{
+			name: "invalid rows",
+			data: "habit,date,completed,amount,target,unit\n" +
+				",2024-03-01,,,,\n" +
+				"Read,01.03.2024,,,,\n" +
+				"Read,2024-03-01,maybe,,,\n" +
+				"Run,2024-03-01,,many,5,km\n" +
+				"Run,2024-03-01,,5,five,km\n" +
+				"Run,2024-03-01,,5,5,km\n" +
+				"Run,2024-03-02,,5,10,km\n" +
+				"Run,2024-03-03,,5,5,mi\n" +
+				"Read,2024-03-02,,,,\n",
+			want:     []recordSummary{daily("Read", 0, "", "2024-03-02=true"), daily("Run", 5, "km", "2024-03-01=5")},
+			wantErrs: []string{":2", ":3", ":4", ":5", ":6", ":8", ":9"},
+		},
+		{
+			name:    "missing columns",
+			data:    "habit,day\nRead,2024-03-01\n",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			data:    "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, errs, err := parseGeneric([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := summarize(records); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got records\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if got := rowErrors(errs); !reflect.DeepEqual(got, append([]string{}, tt.wantErrs...)) {
+				t.Errorf("got row errors %v, want %v", got, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestParseBool(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   bool
+		wantOK bool
+	}{
+		{"1", true, true},
+		{"TRUE", true, true},
+		{"yes", true, true},
+		{"x", true, true},
+		{"Done", true, true},
+		{"0", false, true},
+		{"false", false, true},
+		{"No", false, true},
+		{"", false, true},
+		{"maybe", false, false},
+		{"2", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got, ok := parseBool(tt.value); got != tt.want || ok != tt.wantOK {
+				t.Errorf("got %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+type Format string
+
+const (
+	Auto    Format = "auto"
+	Loop    Format = "loop"    // Loop Habit Tracker CSV export, zip archive or its Checkmarks.csv
+	Generic Format = "generic" // CSV with "habit", "date" and optional "completed", "amount", "target", "unit" columns
+)
+
+var FormatMapping = map[string]Format{
+	string(Auto):    Auto,
+	string(Loop):    Loop,
+	string(Generic): Generic,
+}
+
+// Max size of a file unpacked from zip archive
+const maxUnpackedSize = 64 << 20
+
+type Options struct {
+	Format      Format
+	DryRun      bool // Only reports what would be imported
+	SkipInvalid bool // Imports valid rows, otherwise nothing is imported if there are invalid ones
+}
+
+type RowError struct {
+	File    string `json:"file,omitempty"`
+	Row     int    `json:"row"` // Line number in the file, header is the first one
+	Message string `json:"message"`
+}
+
+type HabitResult struct {
+	Title   string     `json:"title"`
+	HabitID int64      `json:"habitId,omitempty"` // Not set for new habits until they're imported
+	New     bool       `json:"new"`
+	Checks  int        `json:"checks"`
+	From    *date.Date `json:"from,omitempty"`
+	To      *date.Date `json:"to,omitempty"`
+
+	// Creation date the existing habit is moved back to, so its imported history counts in statistics
+	BackdatedTo *date.Date `json:"backdatedTo,omitempty"`
+}
+
+type Result struct {
+	Format   Format         `json:"format"`
+	DryRun   bool           `json:"dryRun"`
+	Imported bool           `json:"imported"`
+	Habits   []*HabitResult `json:"habits"`
+	Errors   []*RowError    `json:"errors"`
+}
+
+// record is a habit with its history read from imported file
+type record struct {
+	title       string
+	description string
+	color       hPkg.Color
+	schedule    hPkg.Schedule
+	target      *float64
+	unit        string
+	archived    bool
+	entries     []*entry
+	file        string
+	row         int
+}
+
+type entry struct {
+	date      date.Date
+	completed *bool
+	amount    *float64
+	file      string
+	row       int
+}
+
+// Import imports habits and their checks to user's account. Habits are matched to the user's own
+// habits of the same title and kind, others are created. Checks overwrite existing ones of the
+// same dates. Everything is saved in a single transaction. Existing habits are moved back to their
+// earliest imported check, which is reported, unless they're shared, since it would change their
+// statistics for other participants. Checks of shared habits before their creation are rejected
+func Import(ctx context.Context, r resources.Resources, u *usrPkg.User, data []byte, opts Options) (*Result, error) {
+	format := opts.Format
+	if format == "" || format == Auto {
+		var err error
+		if format, err = detectFormat(data); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		records []*record
+		errs    []*RowError
+		err     error
+	)
+	switch format {
+	case Loop:
+		records, errs, err = parseLoop(data)
+	case Generic:
+		records, errs, err = parseGeneric(data)
+	default:
+		return nil, apperrors.ErrBadRequest("unknown import format \"" + string(format) + "\"")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{Format: format, DryRun: opts.DryRun, Habits: []*HabitResult{}}
+
+	// Matched habits are locked while their participants are checked, and saved in the same
+	// transaction, so nobody joins them before the habits are moved back
+	err = r.UoW.Do(ctx, func(ctx context.Context) error {
+		existing, err := r.HabitRepo.GetByOwnerIDAndStatus(ctx, u.ID, hPkg.Any, true)
+		if err != nil {
+			return err
+		}
+
+		records, matched := matchHabits(u, records, existing)
+
+		habits := make([]*hPkg.Habit, 0, len(records))
+		for i, rec := range records {
+			h, shared := matched[i], false
+			var createdAt time.Time
+			if h != nil {
+				if err = r.HabitRepo.LockParticipants(ctx, h.ID); err != nil {
+					return err
+				}
+				participants, err := r.HabitRepo.GetParticipants(ctx, h.ID)
+				if err != nil {
+					return err
+				}
+				shared, createdAt = len(participants) > 1, h.CreatedAt
+			}
+
+			h, recErrs := buildHabit(u, rec, h, shared)
+			errs = append(errs, recErrs...)
+			if h == nil {
+				continue
+			}
+			habits = append(habits, h)
+
+			hr := &HabitResult{Title: h.Title, HabitID: h.ID, New: h.ID == 0, Checks: len(h.Checks)}
+			if len(h.Checks) > 0 {
+				hr.From, hr.To = &h.Checks[0].CheckDate, &h.Checks[len(h.Checks)-1].CheckDate
+			}
+			if !hr.New && h.CreatedAt.Before(createdAt) {
+				backdated := date.NewIn(h.CreatedAt, u.Location())
+				hr.BackdatedTo = &backdated
+			}
+			res.Habits = append(res.Habits, hr)
+		}
+
+		if opts.DryRun || (len(errs) > 0 && !opts.SkipInvalid) {
+			return nil
+		}
+
+		if err = r.HabitRepo.ImportHabits(ctx, u.ID, habits); err != nil {
+			return err
+		}
+
+		for i, h := range habits {
+			res.Habits[i].HabitID = h.ID
+		}
+		res.Imported = true
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res.Errors = errs
+	if res.Errors == nil {
+		res.Errors = []*RowError{}
+	}
+
+	return res, nil
+}
+
+func detectFormat(data []byte) (Format, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return Loop, nil
+	case bytes.HasPrefix(data, []byte("SQLite format 3\x00")):
+		return "", apperrors.ErrBadRequest("Loop Habit Tracker database backups aren't supported, use its CSV export instead")
+	}
+
+	header, err := csv.NewReader(bytes.NewReader(data)).Read()
+	if err != nil {
+		return "", apperrors.ErrBadRequest("couldn't read CSV header: " + err.Error())
+	}
+	header = normalizeHeader(header)
+
+	switch {
+	case slices.Contains(header, "habit") && slices.Contains(header, "date"):
+		return Generic, nil
+	case len(header) > 0 && header[0] == "date":
+		return Loop, nil
+	default:
+		return "", apperrors.ErrBadRequest("couldn't detect import format, specify it explicitly")
+	}
+}
+
+// matchHabits returns the records along with the user's own habits they're imported to, nil for new
+// ones. Records matching the same habit are merged into the first of them, so the habit is built once
+func matchHabits(u *usrPkg.User, records []*record, existing []*hPkg.Habit) ([]*record, []*hPkg.Habit) {
+	var (
+		merged  = make([]*record, 0, len(records))
+		matched = make([]*hPkg.Habit, 0, len(records))
+		byHabit = map[int64]*record{}
+	)
+	for _, rec := range records {
+		h := matchHabit(u, rec, existing)
+		if h != nil {
+			if first, ok := byHabit[h.ID]; ok {
+				first.entries = append(first.entries, rec.entries...)
+				continue
+			}
+			byHabit[h.ID] = rec
+		}
+		merged = append(merged, rec)
+		matched = append(matched, h)
+	}
+	return merged, matched
+}
+
+// matchHabit returns the user's own habit the record is imported to, nil if a new one is created
+func matchHabit(u *usrPkg.User, rec *record, existing []*hPkg.Habit) *hPkg.Habit {
+	for _, e := range existing {
+		if e.IsCreator(u.ID) && e.Active && e.Title == rec.title && e.IsQuantitative() == (rec.target != nil) {
+			return e
+		}
+	}
+	return nil
+}
+
+// buildHabit maps the record to a new habit or the user's existing one with checks sorted by date.
+// Shared habits aren't moved back to the earliest imported check
+func buildHabit(u *usrPkg.User, rec *record, h *hPkg.Habit, shared bool) (*hPkg.Habit, []*RowError) {
+	var errs []*RowError
+
+	if h == nil {
+		if err := validateRecord(rec); err != nil {
+			return nil, []*RowError{{File: rec.file, Row: rec.row, Message: err.Error()}}
+		}
+		h = hPkg.NewHabit(rec.title, rec.description, rec.color, rec.schedule, rec.target, rec.unit, "", u.ID, false)
+		h.SetArchived(rec.archived)
+	}
+
+	today, created := u.Today(), date.NewIn(h.CreatedAt, u.Location())
+
+	byDate := make(map[string]*hPkg.HabitCheck, len(rec.entries))
+	for _, e := range rec.entries {
+		if err := h.ValidateCheckDate(e.date, today, u.Location(), hPkg.CheckDatePolicy{Import: true}); err != nil {
+			errs = append(errs, &RowError{File: e.file, Row: e.row, Message: err.Error()})
+			continue
+		}
+		if shared && e.date.Before(created) {
+			errs = append(errs, &RowError{File: e.file, Row: e.row, Message: "habit is shared, its check date couldn't be before habit creation"})
+			continue
+		}
+		hc, err := h.NewCheck(u.ID, e.date, e.completed, e.amount)
+		if err != nil {
+			errs = append(errs, &RowError{File: e.file, Row: e.row, Message: err.Error()})
+			continue
+		}
+		byDate[e.date.String()] = hc // The latest entry of the date wins
+	}
+
+	h.Checks = make([]*hPkg.HabitCheck, 0, len(byDate))
+	for _, hc := range byDate {
+		h.Checks = append(h.Checks, hc)
+	}
+	slices.SortFunc(h.Checks, func(a, b *hPkg.HabitCheck) int { return a.CheckDate.Compare(b.CheckDate) })
+
+	// Imported history must count in statistics, which start at habit's creation and joining
+	if len(h.Checks) > 0 && !shared {
+		first := time.Time(h.Checks[0].CheckDate)
+		first = time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, u.Location())
+		if first.Before(h.CreatedAt) {
+			h.CreatedAt = first
+		}
+		if first.Before(h.JoinedAt) {
+			h.JoinedAt = first
+		}
+	}
+
+	return h, errs
+}
+
+func validateRecord(rec *record) error {
+	if rec.title == "" {
+		return errors.New("habit title is required")
+	}
+	if utf8.RuneCountInString(rec.title) > 256 {
+		return errors.New("habit title must be at most 256 characters long")
+	}
+	if err := rec.schedule.Validate(); err != nil {
+		return err
+	}
+	return hPkg.ValidateTarget(rec.target, rec.unit)
+}
+
+// readCSV returns all CSV records along with their line numbers
+func readCSV(r io.Reader) ([][]string, []int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	var (
+		rows  [][]string
+		lines []int
+	)
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, apperrors.ErrBadRequest("couldn't read CSV: " + err.Error())
+		}
+		line, _ := cr.FieldPos(0)
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+	if len(rows) == 0 {
+		return nil, nil, apperrors.ErrBadRequest("CSV file is empty")
+	}
+
+	return rows, lines, nil
+}
+
+// normalizeHeader lowercases and trims column names, dropping byte order mark
+func normalizeHeader(header []string) []string {
+	normalized := make([]string, len(header))
+	for i, col := range header {
+		if i == 0 {
+			col = strings.TrimPrefix(col, "\ufeff")
+		}
+		normalized[i] = strings.ToLower(strings.TrimSpace(col))
+	}
+	return normalized
+}
+
+func column(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
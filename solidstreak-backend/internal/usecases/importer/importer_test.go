@@ -0,0 +1,329 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/memdb"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/uow"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	hRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit/repo"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	usrRepo "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user/repo"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+// testEnv imports to the in-memory repositories, as HTTP tests do
+type testEnv struct {
+	db  *memdb.DB
+	res resources.Resources
+}
+
+func newTestEnv() *testEnv {
+	db := memdb.New()
+	return &testEnv{
+		db: db,
+		res: resources.Resources{
+			Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+			UoW:       uow.InitMemory(),
+			UsrRepo:   usrRepo.InitMemory(db),
+			HabitRepo: hRepo.InitMemory(db),
+		},
+	}
+}
+
+func (env *testEnv) addUser(t *testing.T, tgID int64) *usrPkg.User {
+	t.Helper()
+	u := &usrPkg.User{TgID: tgID, TgFirstName: "Alex", CreatedAt: time.Now()}
+	if err := env.res.UsrRepo.Upsert(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+// addHabit adds the user's daily habit created the number of days ago
+func (env *testEnv) addHabit(t *testing.T, u *usrPkg.User, title string, createdDaysAgo int) *hPkg.Habit {
+	t.Helper()
+	h := hPkg.NewHabit(title, "", hPkg.Green, hPkg.DailySchedule(), nil, "", "", u.ID, false)
+	h.CreatedAt = time.Now().AddDate(0, 0, -createdDaysAgo)
+	h.JoinedAt = h.CreatedAt
+	if err := env.res.HabitRepo.Create(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+func (env *testEnv) habit(t *testing.T, id int64) *hPkg.Habit {
+	t.Helper()
+	h, ok := env.db.Habits[id]
+	if !ok {
+		t.Fatalf("habit %d not found", id)
+	}
+	return h
+}
+
+// daysAgo returns the user's date the number of days before today
+func daysAgo(u *usrPkg.User, days int) date.Date {
+	return u.Today().AddDate(0, 0, -days)
+}
+
+func genericCSV(lines ...string) []byte {
+	return []byte("habit,date\n" + strings.Join(lines, "\n") + "\n")
+}
+
+// zipOf returns zip archive of the files by their names
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range slices.Sorted(maps.Keys(files)) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = io.WriteString(w, files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// recordSummary is the parsed record with its entries as "date=value" strings, comparable as a whole
+type recordSummary struct {
+	Title       string
+	Description string
+	Color       hPkg.Color
+	Schedule    hPkg.Schedule
+	Target      float64
+	Unit        string
+	Archived    bool
+	Entries     []string
+}
+
+func summarize(records []*record) []recordSummary {
+	summaries := make([]recordSummary, 0, len(records))
+	for _, rec := range records {
+		s := recordSummary{
+			Title:       rec.title,
+			Description: rec.description,
+			Color:       rec.color,
+			Schedule:    rec.schedule,
+			Unit:        rec.unit,
+			Archived:    rec.archived,
+		}
+		if rec.target != nil {
+			s.Target = *rec.target
+		}
+		for _, e := range rec.entries {
+			value := ""
+			if e.completed != nil {
+				value = strconv.FormatBool(*e.completed)
+			}
+			if e.amount != nil {
+				value = strconv.FormatFloat(*e.amount, 'f', -1, 64)
+			}
+			s.Entries = append(s.Entries, e.date.String()+"="+value)
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// rowErrors returns row errors as "file:row" strings
+func rowErrors(errs []*RowError) []string {
+	rows := make([]string, 0, len(errs))
+	for _, e := range errs {
+		rows = append(rows, e.File+":"+strconv.Itoa(e.Row))
+	}
+	return rows
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Format
+		wantErr bool
+	}{
+		{"zip archive", zipOf(t, map[string]string{"Habits.csv": "Name\n"}), Loop, false},
+		{"generic", []byte("habit,date\nRead,2024-03-01\n"), Generic, false},
+		{"generic with more columns", []byte("Date,Habit,Amount\n2024-03-01,Run,5\n"), Generic, false},
+		{"loop checkmarks", []byte("Date,Meditate,Run,\n2024-03-01,2,0,\n"), Loop, false},
+		{"loop checkmarks with BOM", []byte("\ufeffDate,Meditate,\n2024-03-01,2,\n"), Loop, false},
+		{"loop database", []byte("SQLite format 3\x00..."), "", true},
+		{"unknown CSV", []byte("name,value\nRead,1\n"), "", true},
+		{"empty", []byte{}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectFormat(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got format %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         Options
+		invalid      bool // Future and malformed dates are added
+		wantImported bool
+		wantChecks   int
+		wantErrors   int
+	}{
+		{"valid", Options{}, false, true, 2, 0},
+		{"valid dry run", Options{DryRun: true}, false, false, 0, 0},
+		{"invalid", Options{}, true, false, 0, 2},
+		{"invalid skipped", Options{SkipInvalid: true}, true, true, 2, 2},
+		{"invalid skipped dry run", Options{DryRun: true, SkipInvalid: true}, true, false, 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv()
+			u := env.addUser(t, 100)
+
+			lines := []string{"Read," + daysAgo(u, 2).String(), "Read," + daysAgo(u, 1).String()}
+			if tt.invalid {
+				lines = append(lines, "Read,"+daysAgo(u, -1).String(), "Read,yesterday")
+			}
+
+			res, err := Import(context.Background(), env.res, u, genericCSV(lines...), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Imported != tt.wantImported || res.DryRun != tt.opts.DryRun || res.Format != Generic {
+				t.Errorf("unexpected result %+v", res)
+			}
+			if len(res.Habits) != 1 || res.Habits[0].Checks != 2 || !res.Habits[0].New {
+				t.Errorf("habits to import aren't reported: %+v", res.Habits)
+			}
+			if len(res.Errors) != tt.wantErrors {
+				t.Errorf("got errors %+v, want %d", res.Errors, tt.wantErrors)
+			}
+
+			if tt.wantImported != (len(env.db.Habits) == 1) {
+				t.Errorf("got %d habits saved, want imported %v", len(env.db.Habits), tt.wantImported)
+			}
+			if len(env.db.Checks) != tt.wantChecks {
+				t.Errorf("got %d checks saved, want %d", len(env.db.Checks), tt.wantChecks)
+			}
+			if tt.wantImported && res.Habits[0].HabitID == 0 {
+				t.Error("imported habit's ID isn't reported")
+			}
+		})
+	}
+}
+
+func TestImportMovesBackExistingHabit(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	h := env.addHabit(t, u, "Read", 5)
+
+	data := genericCSV("Read,"+daysAgo(u, 20).String(), "Read,"+daysAgo(u, 1).String())
+
+	dryRun, err := Import(context.Background(), env.res, u, data, Options{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hr := dryRun.Habits[0]; hr.New || hr.BackdatedTo == nil || hr.BackdatedTo.Compare(daysAgo(u, 20)) != 0 {
+		t.Errorf("moving back isn't reported in dry run: %+v", hr)
+	}
+	if created := date.NewIn(env.habit(t, h.ID).CreatedAt, u.Location()); created.Compare(daysAgo(u, 5)) != 0 {
+		t.Errorf("habit is moved back to %s in dry run", created)
+	}
+
+	res, err := Import(context.Background(), env.res, u, data, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Imported || res.Habits[0].BackdatedTo == nil {
+		t.Errorf("unexpected result %+v", res.Habits[0])
+	}
+	if created := date.NewIn(env.habit(t, h.ID).CreatedAt, u.Location()); created.Compare(daysAgo(u, 20)) != 0 {
+		t.Errorf("habit is created on %s, want %s", created, daysAgo(u, 20))
+	}
+}
+
+func TestImportKeepsSharedHabitCreation(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	member := env.addUser(t, 200)
+	h := env.addHabit(t, u, "Run", 5)
+	if err := env.res.HabitRepo.AddMember(context.Background(), h.ID, member.ID, false, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	data := genericCSV("Run,"+daysAgo(u, 20).String(), "Run,"+daysAgo(u, 1).String())
+
+	res, err := Import(context.Background(), env.res, u, data, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Imported || len(res.Errors) != 1 || res.Errors[0].Row != 2 {
+		t.Errorf("check before shared habit's creation isn't rejected: %+v", res)
+	}
+
+	res, err = Import(context.Background(), env.res, u, data, Options{SkipInvalid: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hr := res.Habits[0]; !res.Imported || hr.Checks != 1 || hr.BackdatedTo != nil {
+		t.Errorf("unexpected result %+v", hr)
+	}
+	if stored := env.habit(t, h.ID); !stored.CreatedAt.Equal(h.CreatedAt) {
+		t.Errorf("shared habit is moved back from %v to %v", h.CreatedAt, stored.CreatedAt)
+	}
+	if m := env.db.Members[memdb.MemberKey{HabitID: h.ID, UserID: u.ID}]; !m.JoinedAt.Equal(h.JoinedAt) {
+		t.Errorf("creator's joining is moved back from %v to %v", h.JoinedAt, m.JoinedAt)
+	}
+}
+
+func TestImportMergesRecordsOfSameHabit(t *testing.T) {
+	env := newTestEnv()
+	u := env.addUser(t, 100)
+	h := env.addHabit(t, u, "Read", 5)
+
+	// Loop allows habits of the same name, both of which match the existing habit
+	data := zipOf(t, map[string]string{
+		"Habits.csv":              "Position,Name,Type,FrequencyNumerator,FrequencyDenominator\n001,Read,0,1,1\n002,Read,0,1,1\n",
+		"001 Read/Checkmarks.csv": daysAgo(u, 10).String() + ",2\n" + daysAgo(u, 2).String() + ",2\n",
+		"002 Read/Checkmarks.csv": daysAgo(u, 20).String() + ",2\n" + daysAgo(u, 1).String() + ",2\n",
+	})
+
+	res, err := Import(context.Background(), env.res, u, data, Options{Format: Loop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Habits) != 1 {
+		t.Fatalf("habit's history is split between results %+v", res.Habits)
+	}
+	if hr := res.Habits[0]; !res.Imported || hr.HabitID != h.ID || hr.Checks != 4 || hr.BackdatedTo == nil || hr.BackdatedTo.Compare(daysAgo(u, 20)) != 0 {
+		t.Errorf("unexpected result %+v", hr)
+	}
+	if len(env.db.Habits) != 1 || len(env.db.Checks) != 4 {
+		t.Errorf("got %d habits and %d checks saved, want 1 and 4", len(env.db.Habits), len(env.db.Checks))
+	}
+	if created := date.NewIn(env.habit(t, h.ID).CreatedAt, u.Location()); created.Compare(daysAgo(u, 20)) != 0 {
+		t.Errorf("habit is created on %s, want %s", created, daysAgo(u, 20))
+	}
+}
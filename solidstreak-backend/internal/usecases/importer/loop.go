@@ -0,0 +1,420 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+const (
+	loopHabitsFile     = "Habits.csv"
+	loopCheckmarksFile = "Checkmarks.csv"
+
+	loopYesManual = 2 // Boolean checkmark entered by the user, others are skips, misses and automatic ones
+)
+
+// Loop Habit Tracker palette, its color index maps to the closest habit color
+var loopPalette = []hPkg.Color{
+	hPkg.Red, hPkg.Orange, hPkg.Orange, hPkg.Yellow, hPkg.Yellow, hPkg.Lime, hPkg.Lime, hPkg.Green,
+	hPkg.Green, hPkg.Blue, hPkg.Blue, hPkg.Blue, hPkg.Purple, hPkg.Purple, hPkg.Purple, hPkg.Red,
+	hPkg.Orange, hPkg.Blue, hPkg.Blue, hPkg.Blue,
+}
+
+// parseLoop reads Loop Habit Tracker CSV export: zip archive with Habits.csv and Checkmarks.csv
+// having a column per habit, or per habit folders with their own Checkmarks.csv. Bare Checkmarks.csv
+// is accepted too, its habits are imported as daily yes/no ones
+func parseLoop(data []byte) ([]*record, []*RowError, error) {
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		records := []*record{}
+		errs, err := parseLoopCheckmarks(bytes.NewReader(data), loopCheckmarksFile, &records)
+		return records, errs, err
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, apperrors.ErrBadRequest("couldn't read zip archive: " + err.Error())
+	}
+
+	var habitsFile, checkmarksFile *zip.File
+	habitFolders := map[string]*zip.File{} // Per habit Checkmarks.csv by folder name
+	for _, f := range zr.File {
+		dir, name := path.Split(strings.TrimPrefix(f.Name, "/"))
+		dir = strings.TrimSuffix(dir, "/")
+		switch {
+		case name == loopHabitsFile && (habitsFile == nil || len(f.Name) < len(habitsFile.Name)):
+			habitsFile = f
+		case name == loopCheckmarksFile && dir == "":
+			checkmarksFile = f
+		case name == loopCheckmarksFile:
+			habitFolders[path.Base(dir)] = f
+		}
+	}
+	if habitsFile == nil {
+		return nil, nil, apperrors.ErrBadRequest("zip archive has no " + loopHabitsFile + ", it isn't a Loop Habit Tracker export")
+	}
+
+	f, err := openZipFile(habitsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	records, errs, err := parseLoopHabits(f, habitsFile.Name)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if checkmarksFile != nil {
+		f, err := openZipFile(checkmarksFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		fileErrs, err := parseLoopCheckmarks(f, checkmarksFile.Name, &records)
+		if err != nil {
+			return nil, nil, err
+		}
+		return records, append(errs, fileErrs...), nil
+	}
+
+	// Older exports have habit folders named "001 Title" instead of the common file
+	for dir, hf := range habitFolders {
+		rec := findRecord(records, strings.TrimSpace(strings.TrimLeft(dir, "0123456789")))
+		if rec == nil {
+			continue
+		}
+		f, err := openZipFile(hf)
+		if err != nil {
+			return nil, nil, err
+		}
+		fileErrs, err := parseLoopHabitCheckmarks(f, hf.Name, rec)
+		if err != nil {
+			return nil, nil, err
+		}
+		errs = append(errs, fileErrs...)
+	}
+
+	return records, errs, nil
+}
+
+func openZipFile(f *zip.File) (io.Reader, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, apperrors.ErrBadRequest("couldn't read " + f.Name + ": " + err.Error())
+	}
+	defer rc.Close()
+
+	b, err := io.ReadAll(io.LimitReader(rc, maxUnpackedSize+1))
+	if err != nil {
+		return nil, apperrors.ErrBadRequest("couldn't read " + f.Name + ": " + err.Error())
+	}
+	if len(b) > maxUnpackedSize {
+		return nil, apperrors.ErrBadRequest(f.Name + " is too large")
+	}
+
+	return bytes.NewReader(b), nil
+}
+
+func parseLoopHabits(r io.Reader, file string) ([]*record, []*RowError, error) {
+	rows, lines, err := readCSV(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cols := map[string]int{}
+	for i, col := range normalizeHeader(rows[0]) {
+		cols[col] = i
+	}
+	idx := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := cols[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	nameCol := idx("name")
+	if nameCol < 0 {
+		return nil, nil, apperrors.ErrBadRequest(file + " has no \"Name\" column")
+	}
+	var (
+		questionCol    = idx("question")
+		descriptionCol = idx("description")
+		numeratorCol   = idx("frequencynumerator", "numrepetitions")
+		denominatorCol = idx("frequencydenominator", "interval")
+		colorCol       = idx("color")
+		typeCol        = idx("type")
+		targetCol      = idx("target value", "targetvalue")
+		unitCol        = idx("unit")
+		archivedCol    = idx("archived?", "archived")
+	)
+
+	records := []*record{}
+	errs := []*RowError{}
+	for i, row := range rows[1:] {
+		line := lines[i+1]
+		rec := &record{
+			title:    column(row, nameCol),
+			color:    loopColor(column(row, colorCol)),
+			schedule: loopSchedule(column(row, numeratorCol), column(row, denominatorCol)),
+			archived: column(row, archivedCol) == "true",
+			file:     file,
+			row:      line,
+		}
+
+		// Loop asks a question to check a habit off, it's kept in the description
+		rec.description = column(row, questionCol)
+		if d := column(row, descriptionCol); d != "" {
+			if rec.description != "" {
+				rec.description += "\n"
+			}
+			rec.description += d
+		}
+
+		if column(row, typeCol) == "1" { // Numerical habit
+			target, err := strconv.ParseFloat(column(row, targetCol), 64)
+			if err != nil {
+				errs = append(errs, &RowError{File: file, Row: line, Message: "invalid target value"})
+				continue
+			}
+			rec.target, rec.unit = &target, column(row, unitCol)
+		}
+
+		records = append(records, rec)
+	}
+
+	return records, errs, nil
+}
+
+// parseLoopCheckmarks reads Checkmarks.csv with "Date" column and a column per habit, habits missing
+// in records are added as daily yes/no ones
+func parseLoopCheckmarks(r io.Reader, file string, records *[]*record) ([]*RowError, error) {
+	rows, lines, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	header := rows[0]
+	if len(header) == 0 || normalizeHeader(header)[0] != "date" {
+		return nil, apperrors.ErrBadRequest(file + " has no \"Date\" column")
+	}
+
+	colRecords := make([]*record, len(header))
+	for i := 1; i < len(header); i++ {
+		title := strings.TrimSpace(header[i])
+		if title == "" { // Loop ends lines with a separator
+			continue
+		}
+		rec := findRecord(*records, title)
+		if rec == nil {
+			rec = &record{title: title, color: hPkg.Green, schedule: hPkg.DailySchedule(), file: file, row: lines[0]}
+			*records = append(*records, rec)
+		}
+		colRecords[i] = rec
+	}
+
+	scale := loopScale(rows[1:])
+
+	errs := []*RowError{}
+	for i, row := range rows[1:] {
+		line := lines[i+1]
+		d, err := date.Parse(column(row, 0))
+		if err != nil {
+			errs = append(errs, &RowError{File: file, Row: line, Message: "invalid date, YYYY-MM-DD expected"})
+			continue
+		}
+		for j := 1; j < len(row) && j < len(colRecords); j++ {
+			if colRecords[j] == nil {
+				continue
+			}
+			e, err := loopEntry(colRecords[j], d, column(row, j), scale)
+			if err != nil {
+				errs = append(errs, &RowError{File: file, Row: line, Message: colRecords[j].title + ": " + err.Error()})
+				continue
+			}
+			if e != nil {
+				e.file, e.row = file, line
+				colRecords[j].entries = append(colRecords[j].entries, e)
+			}
+		}
+	}
+
+	return errs, nil
+}
+
+// parseLoopHabitCheckmarks reads per habit Checkmarks.csv with date and value columns
+func parseLoopHabitCheckmarks(r io.Reader, file string, rec *record) ([]*RowError, error) {
+	rows, lines, err := readCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	scale := loopScale(rows)
+
+	errs := []*RowError{}
+	for i, row := range rows {
+		d, err := date.Parse(column(row, 0))
+		if err != nil {
+			if i > 0 { // First line may be a header
+				errs = append(errs, &RowError{File: file, Row: lines[i], Message: "invalid date, YYYY-MM-DD expected"})
+			}
+			continue
+		}
+		e, err := loopEntry(rec, d, column(row, 1), scale)
+		if err != nil {
+			errs = append(errs, &RowError{File: file, Row: lines[i], Message: err.Error()})
+			continue
+		}
+		if e != nil {
+			e.file, e.row = file, lines[i]
+			rec.entries = append(rec.entries, e)
+		}
+	}
+
+	return errs, nil
+}
+
+// loopEntry converts checkmark value to an entry, returns nil for values without a check. Numerical
+// values are divided by the scale of their file
+func loopEntry(rec *record, d date.Date, value string, scale float64) (*entry, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if rec.target == nil {
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.New("invalid checkmark value \"" + value + "\"")
+		}
+		if v != loopYesManual {
+			return nil, nil
+		}
+		completed := true
+		return &entry{date: d, completed: &completed}, nil
+	}
+
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, errors.New("invalid checkmark value \"" + value + "\"")
+	}
+	amount /= scale
+	if amount <= 0 { // Loop has zeros for days without entries and negatives for skips
+		return nil, nil
+	}
+
+	return &entry{date: d, amount: &amount}, nil
+}
+
+// loopScale returns the scale of numerical checkmark values in the file's rows. Loop keeps them
+// multiplied by 1000 in integers, while exports of newer versions have decimals instead. The file
+// comes from a single version, so whole amounts next to fractional ones aren't read as thousandths
+func loopScale(rows [][]string) float64 {
+	for _, row := range rows {
+		for i := 1; i < len(row); i++ {
+			if strings.Contains(row[i], ".") {
+				return 1
+			}
+		}
+	}
+	return 1000
+}
+
+// loopSchedule maps Loop frequency of numerator times per denominator days to the closest schedule
+func loopSchedule(numerator, denominator string) hPkg.Schedule {
+	num, err := strconv.Atoi(numerator)
+	if err != nil || num <= 0 {
+		return hPkg.DailySchedule()
+	}
+	den, err := strconv.Atoi(denominator)
+	if err != nil || den <= 0 || num >= den {
+		return hPkg.DailySchedule()
+	}
+
+	switch {
+	case num == 1:
+		return hPkg.Schedule{Type: hPkg.EveryNDays, Interval: den}
+	case den == 7:
+		return hPkg.Schedule{Type: hPkg.TimesPerWeek, Times: num}
+	case den == 30 || den == 31:
+		return hPkg.Schedule{Type: hPkg.TimesPerMonth, Times: num}
+	}
+
+	times := int(math.Round(float64(num) * 7 / float64(den)))
+	switch {
+	case times >= 7:
+		return hPkg.DailySchedule()
+	case times < 1:
+		times = 1
+	}
+	return hPkg.Schedule{Type: hPkg.TimesPerWeek, Times: times}
+}
+
+// loopColor maps Loop palette index or "#RRGGBB" color of newer versions to a habit color by hue
+func loopColor(value string) hPkg.Color {
+	if i, err := strconv.Atoi(value); err == nil {
+		if i >= 0 && i < len(loopPalette) {
+			return loopPalette[i]
+		}
+		return hPkg.Green
+	}
+
+	value = strings.TrimPrefix(value, "#")
+	if len(value) != 6 {
+		return hPkg.Green
+	}
+	rgb, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return hPkg.Green
+	}
+
+	r, g, b := float64(rgb>>16&0xff)/255, float64(rgb>>8&0xff)/255, float64(rgb&0xff)/255
+	maxC, minC := max(r, g, b), min(r, g, b)
+	if maxC-minC < 0.1 { // Grey
+		return hPkg.Blue
+	}
+
+	var hue float64
+	switch maxC {
+	case r:
+		hue = math.Mod((g-b)/(maxC-minC), 6) * 60
+	case g:
+		hue = ((b-r)/(maxC-minC) + 2) * 60
+	default:
+		hue = ((r-g)/(maxC-minC) + 4) * 60
+	}
+	if hue < 0 {
+		hue += 360
+	}
+
+	switch {
+	case hue < 15 || hue >= 330:
+		return hPkg.Red
+	case hue < 40:
+		return hPkg.Orange
+	case hue < 65:
+		return hPkg.Yellow
+	case hue < 95:
+		return hPkg.Lime
+	case hue < 170:
+		return hPkg.Green
+	case hue < 250:
+		return hPkg.Blue
+	default:
+		return hPkg.Purple
+	}
+}
+
+func findRecord(records []*record, title string) *record {
+	for _, rec := range records {
+		if rec.title == title {
+			return rec
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+)
+
+const loopHabitsCSV = "Position,Name,Type,Question,Description,FrequencyNumerator,FrequencyDenominator,Color,Unit,Target Type,Target Value,Archived?\n" +
+	"001,Meditate,0,Did you meditate?,Calm mind,1,1,#4CAF50,,,,false\n" +
+	"002,Run,1,How far did you run?,,3,7,#F44336,km,0,5,true\n"
+
+func TestParseLoop(t *testing.T) {
+	meditate := recordSummary{Title: "Meditate", Description: "Did you meditate?\nCalm mind", Color: hPkg.Green, Schedule: hPkg.DailySchedule()}
+	run := recordSummary{Title: "Run", Description: "How far did you run?", Color: hPkg.Red, Schedule: hPkg.Schedule{Type: hPkg.TimesPerWeek, Times: 3}, Target: 5, Unit: "km", Archived: true}
+
+	with := func(s recordSummary, entries ...string) recordSummary {
+		s.Entries = entries
+		return s
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		want     []recordSummary
+		wantErrs []string
+		wantErr  bool
+	}{
+		{
+			name: "common checkmarks",
+			data: zipOf(t, map[string]string{
+				"Habits.csv":     loopHabitsCSV,
+				"Checkmarks.csv": "Date,Meditate,Run,\n2024-03-02,2,5500,\n2024-03-01,0,-1000,\n2024-02-29,1,0,\n",
+			}),
+			want: []recordSummary{with(meditate, "2024-03-02=true"), with(run, "2024-03-02=5.5")},
+		},
+		{
+			name: "decimal values",
+			data: zipOf(t, map[string]string{
+				"Habits.csv":     loopHabitsCSV,
+				"Checkmarks.csv": "Date,Meditate,Run,\n2024-03-02,2,5.5,\n2024-03-01,2,3,\n",
+			}),
+			want: []recordSummary{with(meditate, "2024-03-02=true", "2024-03-01=true"), with(run, "2024-03-02=5.5", "2024-03-01=3")},
+		},
+		{
+			name: "habit folders",
+			data: zipOf(t, map[string]string{
+				"Habits.csv":                  loopHabitsCSV,
+				"001 Meditate/Checkmarks.csv": "Date,Value\n2024-03-02,2\n2024-03-01,0\n",
+				"002 Run/Checkmarks.csv":      "2024-03-02,2000\n2024-03-01,7500\n",
+				"003 Removed/Checkmarks.csv":  "2024-03-02,2\n",
+			}),
+			want: []recordSummary{with(meditate, "2024-03-02=true"), with(run, "2024-03-02=2", "2024-03-01=7.5")},
+		},
+		{
+			name: "checks of unknown habits",
+			data: zipOf(t, map[string]string{
+				"Habits.csv":     loopHabitsCSV,
+				"Checkmarks.csv": "Date,Meditate,Stretch,\n2024-03-02,2,2,\n",
+			}),
+			want: []recordSummary{
+				with(meditate, "2024-03-02=true"),
+				run,
+				{Title: "Stretch", Color: hPkg.Green, Schedule: hPkg.DailySchedule(), Entries: []string{"2024-03-02=true"}},
+			},
+		},
+		{
+			name: "bare checkmarks",
+			data: []byte("Date,Meditate,\n2024-03-02,2,\n2024-03-01,3,\n"),
+			want: []recordSummary{{Title: "Meditate", Color: hPkg.Green, Schedule: hPkg.DailySchedule(), Entries: []string{"2024-03-02=true"}}},
+		},
+		{
+			name: "invalid rows",
+			data: zipOf(t, map[string]string{
+				"Habits.csv":     loopHabitsCSV + "003,Swim,1,,,1,1,5,m,0,many,false\n",
+				"Checkmarks.csv": "Date,Meditate,\n2024-03-02,yes,\n03/01/2024,2,\n2024-02-29,2,\n",
+			}),
+			want:     []recordSummary{with(meditate, "2024-02-29=true"), run},
+			wantErrs: []string{"Habits.csv:4", "Checkmarks.csv:2", "Checkmarks.csv:3"},
+		},
+		{
+			name:    "archive without habits",
+			data:    zipOf(t, map[string]string{"Checkmarks.csv": "Date,Meditate,\n2024-03-02,2,\n"}),
+			wantErr: true,
+		},
+		{
+			name:    "habits without names",
+			data:    zipOf(t, map[string]string{"Habits.csv": "Position,Title\n001,Meditate\n"}),
+			wantErr: true,
+		},
+		{
+			name:    "checkmarks without dates",
+			data:    []byte("Day,Meditate,\n2024-03-02,2,\n"),
+			wantErr: true,
+		},
+		{
+			name:    "broken archive",
+			data:    []byte("PK\x03\x04broken"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, errs, err := parseLoop(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := summarize(records); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got records\n%+v\nwant\n%+v", got, tt.want)
+			}
+			if got := rowErrors(errs); !reflect.DeepEqual(got, append([]string{}, tt.wantErrs...)) {
+				t.Errorf("got row errors %v, want %v", got, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestLoopScale(t *testing.T) {
+	tests := []struct {
+		name string
+		rows [][]string
+		want float64
+	}{
+		{"integers", [][]string{{"2024-03-02", "2", "5500"}, {"2024-03-01", "0", "-1000"}}, 1000},
+		{"decimals", [][]string{{"2024-03-02", "2", "5.5"}, {"2024-03-01", "2", "3"}}, 1},
+		{"decimals in another column", [][]string{{"2024-03-02", "3", "0.5"}}, 1},
+		{"empty", nil, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loopScale(tt.rows); got != tt.want {
+				t.Errorf("got scale %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoopSchedule(t *testing.T) {
+	tests := []struct {
+		numerator, denominator string
+		want                   hPkg.Schedule
+	}{
+		{"1", "1", hPkg.DailySchedule()},
+		{"", "", hPkg.DailySchedule()},
+		{"x", "7", hPkg.DailySchedule()},
+		{"-1", "7", hPkg.DailySchedule()},
+		{"1", "0", hPkg.DailySchedule()},
+		{"7", "7", hPkg.DailySchedule()},
+		{"1", "3", hPkg.Schedule{Type: hPkg.EveryNDays, Interval: 3}},
+		{"1", "7", hPkg.Schedule{Type: hPkg.EveryNDays, Interval: 7}},
+		{"3", "7", hPkg.Schedule{Type: hPkg.TimesPerWeek, Times: 3}},
+		{"10", "30", hPkg.Schedule{Type: hPkg.TimesPerMonth, Times: 10}},
+		{"4", "31", hPkg.Schedule{Type: hPkg.TimesPerMonth, Times: 4}},
+		{"2", "10", hPkg.Schedule{Type: hPkg.TimesPerWeek, Times: 1}},
+		{"2", "30", hPkg.Schedule{Type: hPkg.TimesPerMonth, Times: 2}},
+		{"2", "60", hPkg.Schedule{Type: hPkg.TimesPerWeek, Times: 1}},
+		{"5", "6", hPkg.Schedule{Type: hPkg.TimesPerWeek, Times: 6}},
+		{"13", "14", hPkg.DailySchedule()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.numerator+"/"+tt.denominator, func(t *testing.T) {
+			if got := loopSchedule(tt.numerator, tt.denominator); !got.Equal(tt.want) {
+				t.Errorf("got schedule %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoopColor(t *testing.T) {
+	tests := []struct {
+		value string
+		want  hPkg.Color
+	}{
+		{"0", hPkg.Red},
+		{"7", hPkg.Green},
+		{"19", hPkg.Blue},
+		{"20", hPkg.Green},
+		{"-1", hPkg.Green},
+		{"#F44336", hPkg.Red},
+		{"#E91E63", hPkg.Red},
+		{"#FF9800", hPkg.Orange},
+		{"#FFEB3B", hPkg.Yellow},
+		{"#8BC34A", hPkg.Lime},
+		{"#4CAF50", hPkg.Green},
+		{"4caf50", hPkg.Green},
+		{"#2196F3", hPkg.Blue},
+		{"#9C27B0", hPkg.Purple},
+		{"#9E9E9E", hPkg.Blue},
+		{"#FFF", hPkg.Green},
+		{"#GGGGGG", hPkg.Green},
+		{"", hPkg.Green},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := loopColor(tt.value); got != tt.want {
+				t.Errorf("got color %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
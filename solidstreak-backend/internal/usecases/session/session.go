@@ -0,0 +1,135 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	sessPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/session"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	apperrors "github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/errors"
+)
+
+// ErrExpired is returned for refresh tokens which are valid but expired, so the client should log in again
+var ErrExpired = errors.New("refresh token has expired")
+
+var errReused = errors.New("refresh token is already exchanged")
+
+// ErrInitDataUsed is returned for Telegram initData which is already exchanged for a session, so
+// the client should get fresh one
+var ErrInitDataUsed = errors.New("telegram initData is already used")
+
+// Start issues the first refresh token of the user's login along with its secret
+func Start(ctx context.Context, r resources.Resources, u *usrPkg.User, now time.Time, ttl, maxLifetime time.Duration) (*sessPkg.RefreshToken, string, error) {
+	return start(ctx, r, u, now, ttl, maxLifetime, nil)
+}
+
+// StartWithInitData starts the login like Start, exchanging Telegram initData once: it's recorded
+// by hash until usedUntil, or forever if it's nil, and reusing it returns ErrInitDataUsed
+func StartWithInitData(ctx context.Context, r resources.Resources, u *usrPkg.User, initData string, usedUntil *time.Time, now time.Time, ttl, maxLifetime time.Duration) (*sessPkg.RefreshToken, string, error) {
+	return start(ctx, r, u, now, ttl, maxLifetime, func(ctx context.Context) error {
+		marked, err := r.SessionRepo.MarkInitDataUsed(ctx, sessPkg.HashSecret(initData), usedUntil, now)
+		if err != nil {
+			return err
+		}
+		if !marked {
+			return ErrInitDataUsed
+		}
+		return nil
+	})
+}
+
+// start issues the first refresh token of the login after the check, if any, passes in the same
+// transaction
+func start(ctx context.Context, r resources.Resources, u *usrPkg.User, now time.Time, ttl, maxLifetime time.Duration, check func(context.Context) error) (*sessPkg.RefreshToken, string, error) {
+	t, secret, err := sessPkg.New(u.ID, now, expiresAt(now, now, ttl, maxLifetime))
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Tokens of the user's past logins are cleaned up here, as they're the only ones piling up
+	err = r.UoW.Do(ctx, func(ctx context.Context) error {
+		if check != nil {
+			if err := check(ctx); err != nil {
+				return err
+			}
+		}
+		if err := r.SessionRepo.DeleteExpired(ctx, u.ID, now); err != nil {
+			return err
+		}
+		return r.SessionRepo.Create(ctx, t)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return t, secret, nil
+}
+
+// Refresh exchanges the refresh token with the secret for the next one of its family, returning
+// it along with its secret and the user. Each token is exchanged once: reusing a rotated token,
+// e.g. the leaked one, revokes the whole family, so neither the thief nor the user could refresh
+// the session anymore. Rotated tokens never outlive the login by more than maxLifetime
+func Refresh(ctx context.Context, r resources.Resources, secret string, now time.Time, ttl, maxLifetime time.Duration) (*usrPkg.User, *sessPkg.RefreshToken, string, error) {
+	t, err := r.SessionRepo.GetByHash(ctx, sessPkg.HashSecret(secret))
+	if err != nil {
+		var apperror apperrors.Error
+		if errors.As(err, &apperror) && apperror.HTTPCode == 404 {
+			return nil, nil, "", apperrors.ErrUnauthorized("invalid refresh token")
+		}
+		return nil, nil, "", err
+	}
+
+	if !now.Before(t.ExpiresAt) {
+		return nil, nil, "", ErrExpired
+	}
+
+	var (
+		next       *sessPkg.RefreshToken
+		nextSecret string
+	)
+	err = r.UoW.Do(ctx, func(ctx context.Context) error {
+		if t.RotatedAt != nil {
+			return errReused
+		}
+		rotated, err := r.SessionRepo.Rotate(ctx, t.ID, now)
+		if err != nil {
+			return err
+		}
+		if !rotated {
+			return errReused
+		}
+
+		if next, nextSecret, err = t.Next(expiresAt(now, t.LoginAt, ttl, maxLifetime)); err != nil {
+			return err
+		}
+		return r.SessionRepo.Create(ctx, next)
+	})
+	if errors.Is(err, errReused) {
+		if err = r.SessionRepo.DeleteFamily(ctx, t.FamilyID); err != nil {
+			return nil, nil, "", err
+		}
+		return nil, nil, "", apperrors.ErrUnauthorized("refresh token is reused, the session is revoked")
+	}
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	u, err := r.UsrRepo.GetByID(ctx, t.UserID)
+	if err != nil {
+		return nil, nil, "", err
+	}
+
+	return u, next, nextSecret, nil
+}
+
+// expiresAt returns when the refresh token issued now expires, which is capped by maxLifetime
+// since the login, unless it's zero
+func expiresAt(now, loginAt time.Time, ttl, maxLifetime time.Duration) time.Time {
+	exp := now.Add(ttl)
+	if maxLifetime > 0 && exp.After(loginAt.Add(maxLifetime)) {
+		exp = loginAt.Add(maxLifetime)
+	}
+	return exp.Truncate(time.Second)
+}
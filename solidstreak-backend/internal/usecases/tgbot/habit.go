@@ -0,0 +1,77 @@
+package tgbot
+
+import (
+	"context"
+
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
+	hPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/habit"
+	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
+	hUsecases "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/usecases/habit"
+	"github.com/anatoliy9697/solidstreak/solidstreak-backend/pkg/date"
+)
+
+// GetTodayHabits returns user's active habits scheduled for today along with today's checks
+func GetTodayHabits(ctx context.Context, r resources.Resources, u *usrPkg.User) ([]*hPkg.Habit, error) {
+	today := u.Today()
+
+	habits, err := hUsecases.ListHabits(ctx, r, u, u.ID, hUsecases.LoadOptions{
+		Status:            hPkg.Active,
+		WithChecks:        true,
+		IncludeIncomplete: true,
+		From:              &today,
+		To:                &today,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	todayHabits := make([]*hPkg.Habit, 0, len(habits))
+	for _, h := range habits {
+		if h.Schedule.IsDueOn(today, date.NewIn(h.CreatedAt, u.Location())) {
+			todayHabits = append(todayHabits, h)
+		}
+	}
+
+	return todayHabits, nil
+}
+
+// IsCompletedToday reports whether habit loaded by GetTodayHabits is completed today
+func IsCompletedToday(h *hPkg.Habit) bool {
+	for _, hc := range h.Checks {
+		if hc.Completed {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleTodayCheck flips today's completion of user's habit. Quantitative habits are
+// completed with their target amount and reset with zero amount
+func ToggleTodayCheck(ctx context.Context, r resources.Resources, u *usrPkg.User, habitID int64) (*hPkg.Habit, *hPkg.HabitCheck, error) {
+	today := u.Today()
+
+	h, err := hUsecases.GetHabit(ctx, r, u, u.ID, habitID, hUsecases.LoadOptions{WithChecks: true, From: &today, To: &today})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		completed = len(h.Checks) == 0
+		amount    *float64
+	)
+	if h.IsQuantitative() {
+		a := 0.0
+		if completed {
+			a = *h.Target
+		}
+		amount = &a
+	}
+
+	// Bot checks habits off for today only, so there is nothing to backfill
+	return hUsecases.CheckHabit(ctx, r, u, u.ID, h.ID, today, &completed, amount, 0)
+}
+
+// GetHabitsStats returns user's active habits with their statistics over the last year
+func GetHabitsStats(ctx context.Context, r resources.Resources, u *usrPkg.User) ([]*hPkg.Habit, error) {
+	return hUsecases.ListHabits(ctx, r, u, u.ID, hUsecases.LoadOptions{Status: hPkg.Active, WithStats: true})
+}
@@ -1,6 +1,8 @@
 package tgbot
 
 import (
+	"context"
+
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
@@ -8,21 +10,9 @@ import (
 	usrPkg "github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/domain/user"
 )
 
-func MapTgChatToInnerAndSave(r resources.Resources, outerTC *tgbotapi.Chat, u *usrPkg.User) (*tcPkg.Chat, error) {
-	var err error
-
+func MapTgChatToInnerAndSave(ctx context.Context, r resources.Resources, outerTC *tgbotapi.Chat, u *usrPkg.User) (*tcPkg.Chat, error) {
 	tc := tcPkg.NewChat(outerTC.ID, u.ID)
-
-	chatExists := false
-	if chatExists, err = r.TCRepo.IsExistsByTgID(tc.TgID); err != nil {
-		return nil, err
-	}
-
-	if chatExists {
-		err = r.TCRepo.Update(tc)
-	} else {
-		err = r.TCRepo.Create(tc)
-	}
+	err := r.TCRepo.Upsert(ctx, tc)
 
 	return tc, err
 }
@@ -14,3 +14,28 @@ func SendReplyMsg(r resources.Resources, tc *tcPkg.Chat, msgText string) error {
 
 	return err
 }
+
+func SendReplyMsgWithKeyboard(r resources.Resources, tc *tcPkg.Chat, msgText string, kb tgbotapi.InlineKeyboardMarkup) error {
+	msg := tgbotapi.NewMessage(tc.TgID, msgText)
+	msg.ReplyMarkup = kb
+
+	_, err := r.TgBotAPI.Send(msg)
+
+	return err
+}
+
+func EditMsgKeyboard(r resources.Resources, tc *tcPkg.Chat, msgID int, kb tgbotapi.InlineKeyboardMarkup) error {
+	edit := tgbotapi.NewEditMessageReplyMarkup(tc.TgID, msgID, kb)
+
+	_, err := r.TgBotAPI.Request(edit)
+
+	return err
+}
+
+func AnswerCallbackQuery(r resources.Resources, callbackQueryID, text string) error {
+	callback := tgbotapi.NewCallback(callbackQueryID, text)
+
+	_, err := r.TgBotAPI.Request(callback)
+
+	return err
+}
@@ -1,6 +1,8 @@
 package tgbot
 
 import (
+	"context"
+
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
 	"github.com/anatoliy9697/solidstreak/solidstreak-backend/internal/common/resources"
@@ -18,17 +20,9 @@ func mapUserToInner(u *tgbotapi.User) *usrPkg.User {
 	)
 }
 
-func MapUserToInnerAndSave(r resources.Resources, outerUsr *tgbotapi.User) (u *usrPkg.User, err error) {
+func MapUserToInnerAndSave(ctx context.Context, r resources.Resources, outerUsr *tgbotapi.User) (u *usrPkg.User, err error) {
 	u = mapUserToInner(outerUsr)
-
-	userExists := false
-	if userExists, err = r.UsrRepo.IsExists(u); err == nil {
-		if userExists {
-			err = r.UsrRepo.Update(u)
-		} else {
-			err = r.UsrRepo.Create(u)
-		}
-	}
+	err = r.UsrRepo.Upsert(ctx, u)
 
 	return u, err
 }
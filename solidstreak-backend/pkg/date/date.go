@@ -72,10 +72,58 @@ func (d *Date) Scan(value interface{}) error {
 	}
 }
 
+// NewIn returns the date of the specified moment in the specified location
+func NewIn(t time.Time, loc *time.Location) Date {
+	t = t.In(loc)
+	return Date(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC))
+}
+
 func Today() Date {
 	return New(time.Now())
 }
 
+func TodayIn(loc *time.Location) Date {
+	return NewIn(time.Now(), loc)
+}
+
+// Compare compares calendar dates regardless of their locations
+func (d Date) Compare(o Date) int {
+	dy, dm, dd := time.Time(d).Date()
+	oy, om, od := time.Time(o).Date()
+	switch {
+	case dy != oy:
+		return dy - oy
+	case dm != om:
+		return int(dm - om)
+	default:
+		return dd - od
+	}
+}
+
+func (d Date) Before(o Date) bool {
+	return d.Compare(o) < 0
+}
+
+func (d Date) After(o Date) bool {
+	return d.Compare(o) > 0
+}
+
 func (d Date) AddDate(years int, months int, days int) Date {
 	return New(time.Time(d).AddDate(years, months, days))
 }
+
+func (d Date) MonthStart() Date {
+	t := time.Time(d)
+	return Date(time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()))
+}
+
+// DaysSince returns the number of calendar days from o to d
+func (d Date) DaysSince(o Date) int {
+	dy, dm, dd := time.Time(d).Date()
+	oy, om, od := time.Time(o).Date()
+	return int(time.Date(dy, dm, dd, 0, 0, 0, 0, time.UTC).Sub(time.Date(oy, om, od, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+}
+
+func (d Date) Weekday() time.Weekday {
+	return time.Time(d).Weekday()
+}
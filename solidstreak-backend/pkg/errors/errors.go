@@ -4,6 +4,7 @@ type Error struct {
 	HTTPCode int    `json:"status,string"`
 	Title    string `json:"title"`
 	Detail   string `json:"detail,omitempty"`
+	Code     string `json:"code,omitempty"` // Machine-readable reason, set where clients handle it specifically
 }
 
 func (e Error) Error() string {
@@ -14,6 +15,12 @@ func (e Error) Error() string {
 	return msg
 }
 
+// WithCode returns the error with specified machine-readable reason
+func (e Error) WithCode(code string) Error {
+	e.Code = code
+	return e
+}
+
 func New(httpCode int, title string, detail string) Error {
 	return Error{
 		HTTPCode: httpCode,
@@ -41,3 +48,7 @@ func ErrUnauthorized(detail string) Error {
 func ErrForbidden(detail string) Error {
 	return New(403, "forbidden", detail)
 }
+
+func ErrServiceUnavailable(detail string) Error {
+	return New(503, "service unavailable", detail)
+}
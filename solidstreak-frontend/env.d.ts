@@ -1 +1,6 @@
 /// <reference types="vite/client" />
+
+interface ImportMetaEnv {
+  // Username of the bot for Telegram Login Widget, without "@"
+  readonly VITE_TG_BOT_USERNAME?: string
+}
@@ -8,9 +8,40 @@ interface Metadata {
 }
 
 export interface Error {
-  HTTPCode: number
-  Title: string
-  Detail?: string
+  status: string
+  title: string
+  detail?: string
+  code?: string
+}
+
+// Code of authentication errors telling the app to get fresh initData by reopening
+export const INIT_DATA_EXPIRED = 'init_data_expired'
+
+let initDataExpiredNotified = false
+
+// Asks the user to reopen the app, since Telegram issues new initData on launch only
+function handleInitDataExpired(): void {
+  if (initDataExpiredNotified) {
+    return
+  }
+  initDataExpiredNotified = true
+
+  const webApp = window.Telegram?.WebApp
+  if (!webApp) {
+    return
+  }
+  webApp.showAlert('Your session has expired. Please reopen the app.', () => webApp.close())
+}
+
+// Code of authentication errors telling the app to refresh its session
+export const SESSION_EXPIRED = 'session_expired'
+
+export interface Session {
+  token: string
+  expiresAt: string
+  refreshToken: string
+  refreshExpiresAt: string
+  user: User
 }
 
 export interface UserInfoData {
@@ -30,16 +61,34 @@ export interface PostPutHabitRequest {
 export interface DeleteHabitRequest {
   meta?: Metadata
 }
+export interface PostHabitJoinRequest {
+  data: { token: string; isPublic?: boolean }
+  meta?: Metadata
+}
 export interface PostHabitCheckRequest {
   data: HabitCheck
   meta?: Metadata
 }
+export interface PostAccountDeletionRequest {
+  data: { confirmation: string }
+  meta?: Metadata
+}
+export interface PostSessionRefreshRequest {
+  data: { refreshToken: string }
+}
+export interface PostTelegramLoginRequest {
+  data: { login: TelegramLoginUser; timezone?: string }
+}
 
 type ApiRequest =
   | PostUserInfoRequest
   | PostPutHabitRequest
   | DeleteHabitRequest
+  | PostHabitJoinRequest
   | PostHabitCheckRequest
+  | PostAccountDeletionRequest
+  | PostSessionRefreshRequest
+  | PostTelegramLoginRequest
 
 export interface PostUserInfoResponse {
   data: User
@@ -61,6 +110,10 @@ export interface PostHabitCheckResponse {
   data: HabitCheck
   errors?: Error[]
 }
+export interface PostSessionResponse {
+  data: Session
+  errors?: Error[]
+}
 
 type ApiResponse =
   | PostUserInfoResponse
@@ -68,6 +121,7 @@ type ApiResponse =
   | DeleteHabitResponse
   | GetHabitsResponse
   | PostHabitCheckResponse
+  | PostSessionResponse
 
 export interface RequestResult {
   success: boolean
@@ -80,7 +134,7 @@ export interface RequestResult {
 async function performRequest(
   method: 'post' | 'put' | 'delete' | 'get',
   url: string,
-  initData: string,
+  auth: Record<string, string>,
   data?: ApiRequest,
 ): Promise<RequestResult> {
   const result: RequestResult = {
@@ -97,7 +151,7 @@ async function performRequest(
       url,
       data,
       headers: {
-        'X-Telegram-InitData': initData,
+        ...auth,
         'X-Request-ID': crypto.randomUUID(),
       },
     })
@@ -113,6 +167,9 @@ async function performRequest(
       result.httpCode = err.response?.status || 500
       result.httpError = err.message || 'Unknown error'
       result.apiErrors = err.response?.data?.errors || []
+      if (result.httpCode === 401 && result.apiErrors.some((e) => e.code === INIT_DATA_EXPIRED)) {
+        handleInitDataExpired()
+      }
     } else {
       result.httpCode = 500
       result.httpError = String(error)
@@ -122,29 +179,117 @@ async function performRequest(
   return result
 }
 
+function isSessionExpired(result: RequestResult): boolean {
+  return result.httpCode === 401 && result.apiErrors.some((e) => e.code === SESSION_EXPIRED)
+}
+
 export class ApiFetcher {
   initData: string
   username: string | undefined
+  session: Session | null = null
+  private refreshing: Promise<void> | null = null
 
   constructor(initData: string, username?: string) {
     this.initData = initData
     this.username = username
   }
 
+  private initDataAuth(): Record<string, string> {
+    return { 'X-Telegram-InitData': this.initData }
+  }
+
+  // Requests are authenticated by the session once it's started and by initData otherwise
+  private auth(): Record<string, string> {
+    if (this.session) {
+      return { Authorization: `Bearer ${this.session.token}` }
+    }
+    return this.initDataAuth()
+  }
+
+  // Sends the request, refreshing the expired session and retrying once. If the session couldn't
+  // be refreshed, the request is retried with initData
+  private async request(
+    method: 'post' | 'put' | 'delete' | 'get',
+    url: string,
+    data?: ApiRequest,
+  ): Promise<RequestResult> {
+    const session = this.session
+    const result = await performRequest(method, url, this.auth(), data)
+    if (!session || !isSessionExpired(result)) {
+      return result
+    }
+
+    await this.refreshSession(session)
+    return await performRequest(method, url, this.auth(), data)
+  }
+
+  // Exchanges initData for the session, requests keep using initData if it fails
+  async startSession(): Promise<RequestResult> {
+    const result = await performRequest('post', `/api/v1/auth/session`, this.initDataAuth())
+    if (result.success && result.response) {
+      this.session = (result.response as PostSessionResponse).data
+    }
+    return result
+  }
+
+  // Starts the session of the user logged in with Telegram Login Widget outside of Telegram
+  async loginWithTelegram(login: TelegramLoginUser, timezone?: string): Promise<RequestResult> {
+    const payload: PostTelegramLoginRequest = { data: { login, timezone } }
+    const result = await performRequest('post', `/api/v1/auth/telegram-login`, {}, payload)
+    if (result.success && result.response) {
+      this.session = (result.response as PostSessionResponse).data
+    }
+    return result
+  }
+
+  // Refreshes the expired session once for all requests it has failed, since the refresh token
+  // is exchanged once and reusing it revokes the session
+  private async refreshSession(expired: Session): Promise<void> {
+    if (!this.refreshing && this.session === expired) {
+      this.refreshing = this.exchangeRefreshToken(expired.refreshToken).finally(() => {
+        this.refreshing = null
+      })
+    }
+    if (this.refreshing) {
+      await this.refreshing
+    }
+  }
+
+  private async exchangeRefreshToken(refreshToken: string): Promise<void> {
+    this.session = null
+
+    const payload: PostSessionRefreshRequest = { data: { refreshToken } }
+    const result = await performRequest('post', `/api/v1/auth/refresh`, {}, payload)
+    if (result.success && result.response) {
+      this.session = (result.response as PostSessionResponse).data
+      return
+    }
+
+    // Outside of Telegram there is no initData to fall back to, so the user logs in again
+    if (!this.initData) {
+      window.location.reload()
+    }
+  }
+
   async upsertUserInfo(user: User, tgChat: { tgId: number }): Promise<RequestResult> {
     const payload: PostUserInfoRequest = { data: { user, tgChat: tgChat } }
     if (this.username) {
       payload.meta = { username: this.username } as Metadata
     }
-    return await performRequest('post', `/api/v1/user-info/upsert`, this.initData, payload)
+    const result = await performRequest(
+      'post',
+      `/api/v1/user-info/upsert`,
+      this.initDataAuth(),
+      payload,
+    )
+    if (result.success) {
+      await this.startSession()
+    }
+    return result
   }
 
   async fetchHabits(userId: number): Promise<RequestResult> {
-    return await performRequest(
-      'get',
-      `/api/v1/users/${userId}/habits?with_checks=true`,
-      this.initData,
-    )
+    return await this.request('get', `/api/v1/users/${userId}/habits?with_checks=true`)
   }
 
   async postHabit(userId: number, habit: Habit): Promise<RequestResult> {
@@ -152,7 +297,7 @@ export class ApiFetcher {
     if (this.username) {
       payload.meta = { username: this.username } as Metadata
     }
-    return await performRequest('post', `/api/v1/users/${userId}/habits`, this.initData, payload)
+    return await this.request('post', `/api/v1/users/${userId}/habits`, payload)
   }
 
   async putHabit(userId: number, habit: Habit): Promise<RequestResult> {
@@ -160,12 +305,7 @@ export class ApiFetcher {
     if (this.username) {
       payload.meta = { username: this.username } as Metadata
     }
-    return await performRequest(
-      'put',
-      `/api/v1/users/${userId}/habits/${habit.id}`,
-      this.initData,
-      payload,
-    )
+    return await this.request('put', `/api/v1/users/${userId}/habits/${habit.id}`, payload)
   }
 
   async deleteHabit(userId: number, habitId: number): Promise<RequestResult> {
@@ -173,12 +313,15 @@ export class ApiFetcher {
     if (this.username) {
       payload.meta = { username: this.username } as Metadata
     }
-    return await performRequest(
-      'delete',
-      `/api/v1/users/${userId}/habits/${habitId}`,
-      this.initData,
-      payload,
-    )
+    return await this.request('delete', `/api/v1/users/${userId}/habits/${habitId}`, payload)
+  }
+
+  async joinHabit(userId: number, token: string): Promise<RequestResult> {
+    const payload: PostHabitJoinRequest = { data: { token } }
+    if (this.username) {
+      payload.meta = { username: this.username } as Metadata
+    }
+    return await this.request('post', `/api/v1/users/${userId}/habits/join`, payload)
   }
 
   async postHabitCheck(
@@ -190,11 +333,26 @@ export class ApiFetcher {
     if (this.username) {
       payload.meta = { username: this.username } as Metadata
     }
-    return await performRequest(
-      'post',
-      `/api/v1/users/${userId}/habits/${habitId}/checks`,
-      this.initData,
-      payload,
-    )
+    return await this.request('post', `/api/v1/users/${userId}/habits/${habitId}/checks`, payload)
+  }
+
+  async requestAccountDeletion(userId: number, confirmation: string): Promise<RequestResult> {
+    const payload: PostAccountDeletionRequest = { data: { confirmation } }
+    if (this.username) {
+      payload.meta = { username: this.username } as Metadata
+    }
+    return await this.request('post', `/api/v1/users/${userId}/deletion`, payload)
+  }
+
+  async cancelAccountDeletion(userId: number): Promise<RequestResult> {
+    return await this.request('delete', `/api/v1/users/${userId}/deletion`)
+  }
+
+  async fetchHabitsTrash(userId: number): Promise<RequestResult> {
+    return await this.request('get', `/api/v1/users/${userId}/habits/trash`)
+  }
+
+  async restoreHabit(userId: number, habitId: number): Promise<RequestResult> {
+    return await this.request('post', `/api/v1/users/${userId}/habits/${habitId}/restore`)
   }
 }
@@ -1,6 +1,7 @@
 export interface HabitCheck {
   checkDate: string
   completed: boolean
+  amount?: number
   checkedAt: Date
 }
 
@@ -10,8 +11,16 @@ export interface Habit {
   title: string
   description?: string
   color?: string
+  target?: number
+  unit?: string
+  reminderTime?: string
   isPublic: boolean
+  creatorId?: number
+  joinedAt?: Date
   createdAt?: Date
   updatedAt?: Date
+  archivedAt?: Date
+  deletedAt?: Date
+  purgeAt?: Date
   checks?: HabitCheck[]
 }
@@ -6,4 +6,6 @@ export interface User {
   tgLastName?: string
   tgLangCode?: string
   tgIsBot?: boolean
+  timezone?: string
+  deletionScheduledAt?: string
 }
@@ -8,6 +8,7 @@ export const useHabitStore = defineStore('habit', {
     apiFetcher: null as ApiFetcher | null,
     habits: [] as Habit[],
     habitsMap: new Map<number, Habit>(),
+    trashedHabits: [] as Habit[],
   }),
 
   actions: {
@@ -41,6 +42,20 @@ export const useHabitStore = defineStore('habit', {
       return result
     },
 
+    async joinHabit(userId: number, token: string): Promise<RequestResult> {
+      const result = await this.apiFetcher!.joinHabit(userId, token)
+
+      if (result.success) {
+        const joinedHabit = result.response?.data as Habit
+        if (!this.habitsMap.has(joinedHabit.id)) {
+          this.habitsMap.set(joinedHabit.id, joinedHabit)
+          this.habits.push(joinedHabit)
+        }
+      }
+
+      return result
+    },
+
     async updateHabit(userId: number, habit: Habit): Promise<RequestResult> {
       const result = await this.apiFetcher!.putHabit(userId, habit)
 
@@ -81,9 +96,9 @@ export const useHabitStore = defineStore('habit', {
           httpError: 'Habit not found',
           apiErrors: [
             {
-              HTTPCode: 404,
-              Title: 'not found',
-              Detail: `couldn't find habit with specified id`,
+              status: '404',
+              title: 'not found',
+              detail: `couldn't find habit with specified id`,
             },
           ],
           response: null,
@@ -117,6 +132,28 @@ export const useHabitStore = defineStore('habit', {
       return result
     },
 
+    async fetchHabitsTrash(userId: number): Promise<RequestResult> {
+      const result = await this.apiFetcher!.fetchHabitsTrash(userId)
+
+      if (result.success) {
+        this.trashedHabits = (result.response?.data as Habit[]) || []
+      }
+
+      return result
+    },
+
+    async restoreHabit(userId: number, habitId: number): Promise<RequestResult> {
+      const result = await this.apiFetcher!.restoreHabit(userId, habitId)
+
+      if (result.success) {
+        this.trashedHabits = this.trashedHabits.filter((h) => h.id !== habitId)
+        // Restored habit is fetched along with its checks
+        await this.fetchHabits(userId)
+      }
+
+      return result
+    },
+
     async setHabitCheck(
       userId: number,
       habitId: number,
@@ -1,6 +1,6 @@
 import { defineStore } from 'pinia'
 
-import { ApiFetcher, type RequestResult } from '@/api/request'
+import { ApiFetcher, type RequestResult, type Session } from '@/api/request'
 import type { User } from '@/models/user'
 
 export const useUserStore = defineStore('user', {
@@ -12,6 +12,8 @@ export const useUserStore = defineStore('user', {
     tgFirstName: '' as string,
     tgLastName: '' as string,
     tgLangCode: '' as string,
+    timezone: '' as string,
+    deletionScheduledAt: null as string | null,
     avatarUrl: '' as string,
   }),
 
@@ -32,6 +34,7 @@ export const useUserStore = defineStore('user', {
         tgLastName: webAppUser.last_name,
         tgLangCode: webAppUser.language_code,
         tgIsBot: webAppUser.is_bot,
+        timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
       } as User
 
       const result = await this.apiFetcher!.upsertUserInfo(inputUser, { tgId: webAppChat.id })
@@ -39,15 +42,53 @@ export const useUserStore = defineStore('user', {
       const user = result.response?.data ? (result.response?.data as User) : null
 
       if (user) {
-        this.id = user.id || 0
-        this.tgId = user.tgId
-        this.tgUsername = user.tgUsername || ''
-        this.tgFirstName = user.tgFirstName
-        this.tgLastName = user.tgLastName || ''
-        this.tgLangCode = user.tgLangCode || ''
+        this.setUser(user)
       }
 
       return result
     },
+
+    async loginWithTelegram(login: TelegramLoginUser): Promise<RequestResult> {
+      const result = await this.apiFetcher!.loginWithTelegram(
+        login,
+        Intl.DateTimeFormat().resolvedOptions().timeZone,
+      )
+
+      const session = result.response?.data ? (result.response?.data as Session) : null
+
+      if (session) {
+        this.setUser(session.user)
+      }
+
+      return result
+    },
+
+    setUser(user: User): void {
+      this.id = user.id || 0
+      this.tgId = user.tgId
+      this.tgUsername = user.tgUsername || ''
+      this.tgFirstName = user.tgFirstName
+      this.tgLastName = user.tgLastName || ''
+      this.tgLangCode = user.tgLangCode || ''
+      this.timezone = user.timezone || ''
+      this.deletionScheduledAt = user.deletionScheduledAt || null
+    },
+
+    async requestAccountDeletion(confirmation: string): Promise<RequestResult> {
+      const result = await this.apiFetcher!.requestAccountDeletion(this.id, confirmation)
+      const user = result.response?.data ? (result.response?.data as User) : null
+      if (user) {
+        this.deletionScheduledAt = user.deletionScheduledAt || null
+      }
+      return result
+    },
+
+    async cancelAccountDeletion(): Promise<RequestResult> {
+      const result = await this.apiFetcher!.cancelAccountDeletion(this.id)
+      if (result.response?.data) {
+        this.deletionScheduledAt = null
+      }
+      return result
+    },
   },
 })
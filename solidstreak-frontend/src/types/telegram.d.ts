@@ -15,6 +15,7 @@ interface WebAppChat {
 interface WebAppInitData {
   user?: WebAppUser
   chat?: WebAppChat
+  start_param?: string
 }
 
 interface TelegramWebApp {
@@ -22,10 +23,24 @@ interface TelegramWebApp {
   initDataUnsafe: WebAppInitData
   onEvent(event: 'webAppReady', callback: () => void): void
   ready(): void
+  showAlert(message: string, callback?: () => void): void
+  close(): void
+}
+
+// User's data passed by Telegram Login Widget, signed by Telegram
+interface TelegramLoginUser {
+  id: number
+  first_name: string
+  last_name?: string
+  username?: string
+  photo_url?: string
+  auth_date: number
+  hash: string
 }
 
 interface Window {
   Telegram?: {
     WebApp?: TelegramWebApp
   }
+  onTelegramAuth?: (user: TelegramLoginUser) => void
 }